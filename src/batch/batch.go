@@ -0,0 +1,75 @@
+package batch
+
+import (
+	"bufio"
+	"calcWithTests/src/calculator"
+	"io"
+	"strings"
+)
+
+// maxLineLength - самая длинная строка, которую читает Run
+const maxLineLength = 1 << 20
+
+// Result - результат вычисления одной строки ввода
+type Result struct {
+	// номер строки, начиная с 1
+	Line       int
+	Expression string
+	Value      calculator.Value
+	Err        error
+}
+
+type job struct {
+	result Result
+	done   chan struct{}
+}
+
+// Run вычисляет выражения из r, по одному на строку; пустые строки
+// пропускаются. Строки независимы и считаются параллельно в workers
+// горутинах, а emit получает результаты в порядке строк. Возвращает
+// число строк с ошибками.
+func Run(r io.Reader, config calculator.CalculatorConfig, workers int, emit func(Result)) (int, error) {
+	workers = max(workers, 1)
+	// order хранит задачи в порядке строк, work раздаёт их горутинам;
+	// буфер order ограничивает число строк, вычисленных впрок
+	work := make(chan *job)
+	order := make(chan *job, 2*workers)
+	for range workers {
+		go func() {
+			for j := range work {
+				j.result.Value, j.result.Err = calculator.Evaluate(j.result.Expression, config)
+				close(j.done)
+			}
+		}()
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(order)
+		defer close(work)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(nil, maxLineLength)
+		line := 0
+		for scanner.Scan() {
+			line++
+			expr := strings.TrimSpace(scanner.Text())
+			if expr == "" {
+				continue
+			}
+			j := &job{result: Result{Line: line, Expression: expr}, done: make(chan struct{})}
+			order <- j
+			work <- j
+		}
+		readErr <- scanner.Err()
+	}()
+
+	failed := 0
+	for j := range order {
+		<-j.done
+		if j.result.Err != nil {
+			failed++
+		}
+		emit(j.result)
+	}
+	return failed, <-readErr
+}
@@ -0,0 +1,78 @@
+package batch
+
+import (
+	"bufio"
+	"calcWithTests/src/calculator"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	type CaseRun struct {
+		line       int
+		expression string
+		result     string
+		err        string
+	}
+	input := "1 + 2\n\n  sin(pi / 2)  \n1/0\n2^10\nfoo(\n"
+	cases := []CaseRun{
+		{line: 1, expression: "1 + 2", result: "3"},
+		{line: 3, expression: "sin(pi / 2)", result: "1"},
+		{line: 4, expression: "1/0", err: "error while calculating: 1:1: calculating division: division by zero"},
+		{line: 5, expression: "2^10", result: "1024"},
+		{line: 6, expression: "foo(", err: "error while parsing: 1:5: unexpected end of expression"},
+	}
+
+	for _, workers := range []int{0, 1, 4} {
+		var results []Result
+		failed, err := Run(strings.NewReader(input), calculator.CalculatorConfig{}, workers, func(r Result) {
+			results = append(results, r)
+		})
+		require.NoError(t, err)
+		require.Equal(t, 2, failed)
+		require.Len(t, results, len(cases))
+
+		for i, c := range cases {
+			r := results[i]
+			require.Equal(t, c.line, r.Line, c.expression)
+			require.Equal(t, c.expression, r.Expression)
+			if c.err != "" {
+				require.EqualError(t, r.Err, c.err, c.expression)
+				continue
+			}
+			require.NoError(t, r.Err, c.expression)
+			require.Equal(t, c.result, r.Value.String(), c.expression)
+		}
+	}
+}
+
+func TestRunOrder(t *testing.T) {
+	var input strings.Builder
+	for i := 1; i <= 1000; i++ {
+		fmt.Fprintf(&input, "%d / 7 * 7\n", i)
+	}
+
+	line := 0
+	failed, err := Run(strings.NewReader(input.String()), calculator.CalculatorConfig{Mode: calculator.Rational}, 8, func(r Result) {
+		line++
+		require.Equal(t, line, r.Line)
+		require.NoError(t, r.Err)
+		require.Equal(t, fmt.Sprint(line), r.Value.String())
+	})
+	require.NoError(t, err)
+	require.Zero(t, failed)
+	require.Equal(t, 1000, line)
+}
+
+func TestRunLongLine(t *testing.T) {
+	input := "1\n" + strings.Repeat("1+", maxLineLength) + "1\n"
+	var lines []int
+	_, err := Run(strings.NewReader(input), calculator.CalculatorConfig{}, 2, func(r Result) {
+		lines = append(lines, r.Line)
+	})
+	require.ErrorIs(t, err, bufio.ErrTooLong)
+	require.Equal(t, []int{1}, lines)
+}
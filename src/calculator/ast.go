@@ -0,0 +1,293 @@
+package calculator
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Node - узел абстрактного синтаксического дерева выражения
+type Node interface {
+	String() string
+	Position() Span
+	node()
+}
+
+// NumberLit - числовой литерал, Text хранит исходную запись. Value - её
+// значение в float64, ±Inf для чисел вне диапазона float64; режимы на
+// math/big разбирают Text. Imaginary означает мнимое число вида 2i (режим Complex).
+type NumberLit struct {
+	Span
+	Value     float64
+	Text      string
+	Imaginary bool
+}
+
+// AngleLit - угол с единицей вплотную к числу: 30°, 0.5rad, 50grad.
+// Единица литерала заменяет AngleUnits, значение переводится в AngleUnits.
+type AngleLit struct {
+	Span
+	Value *NumberLit
+	Unit  AngleUnit
+}
+
+// Constant - именованная константа (pi, e, в режиме Complex - i)
+type Constant struct {
+	Span
+	Name string
+}
+
+// Variable - ссылка на переменную, значение задаётся при вычислении
+type Variable struct {
+	Span
+	Name string
+}
+
+// UnaryOp - унарная операция: минус или побитовое отрицание ~
+type UnaryOp struct {
+	Span
+	Op string
+	X  Node
+}
+
+// BinaryOp - бинарная операция X Op Y
+type BinaryOp struct {
+	Span
+	Op string
+	X  Node
+	Y  Node
+}
+
+// FuncCall - вызов функции
+type FuncCall struct {
+	Span
+	Name string
+	Args []Node
+}
+
+// List - список [a, b, ...]; в режиме Interval [lo, hi] задаёт интервал
+type List struct {
+	Span
+	Elems []Node
+}
+
+// Unit - единица измерения в степени Power (режим Units): km, s^-2
+type Unit struct {
+	Span
+	Name  string
+	Power int
+}
+
+// QuantityLit - число с единицами измерения: 5 km, 9.81 m s^-2
+type QuantityLit struct {
+	Span
+	Value *NumberLit
+	Units []*Unit
+}
+
+// Convert - перевод значения X в единицы Target: 60 mph to m/s
+type Convert struct {
+	Span
+	X      Node
+	Target Node
+}
+
+// Assign - присваивание значения переменной
+type Assign struct {
+	Span
+	Name  string
+	Value Node
+}
+
+// FuncDef - определение пользовательской функции name(params) = body
+type FuncDef struct {
+	Span
+	Name   string
+	Params []string
+	Body   Node
+}
+
+// Block - последовательность выражений через ';', значение блока - значение последнего
+type Block struct {
+	Span
+	Stmts []Node
+}
+
+func (*NumberLit) node()   {}
+func (*AngleLit) node()    {}
+func (*Constant) node()    {}
+func (*Variable) node()    {}
+func (*UnaryOp) node()     {}
+func (*BinaryOp) node()    {}
+func (*FuncCall) node()    {}
+func (*List) node()        {}
+func (*Unit) node()        {}
+func (*QuantityLit) node() {}
+func (*Convert) node()     {}
+func (*Assign) node()      {}
+func (*FuncDef) node()     {}
+func (*Block) node()       {}
+
+func (n *NumberLit) String() string {
+	if n.Text != "" {
+		return n.Text
+	}
+	return strconv.FormatFloat(n.Value, 'g', -1, 64)
+}
+
+func (n *AngleLit) String() string {
+	return n.Value.String() + angleSuffix(n.Unit)
+}
+
+func (n *Constant) String() string {
+	return n.Name
+}
+
+func (n *Variable) String() string {
+	return n.Name
+}
+
+func (n *UnaryOp) String() string {
+	return n.Op + wrapOperand(n.X, "", false)
+}
+
+func (n *BinaryOp) String() string {
+	return wrapOperand(n.X, n.Op, false) + " " + n.Op + " " + wrapOperand(n.Y, n.Op, true)
+}
+
+func (n *FuncCall) String() string {
+	return n.Name + "(" + joinNodes(n.Args) + ")"
+}
+
+func (n *List) String() string {
+	return "[" + joinNodes(n.Elems) + "]"
+}
+
+func (n *Unit) String() string {
+	if n.Power != 1 {
+		return n.Name + "^" + strconv.Itoa(n.Power)
+	}
+	return n.Name
+}
+
+func (n *QuantityLit) String() string {
+	var b strings.Builder
+	b.WriteString(n.Value.String())
+	for _, unit := range n.Units {
+		b.WriteString(" " + unit.String())
+	}
+	return b.String()
+}
+
+func (n *Convert) String() string {
+	target := n.Target.String()
+	if _, ok := n.Target.(*Convert); ok {
+		target = "(" + target + ")"
+	}
+	return n.X.String() + " to " + target
+}
+
+func joinNodes(nodes []Node) string {
+	strs := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		strs = append(strs, node.String())
+	}
+	return strings.Join(strs, ", ")
+}
+
+func (n *Assign) String() string {
+	return n.Name + " = " + n.Value.String()
+}
+
+func (n *FuncDef) String() string {
+	return n.Name + "(" + strings.Join(n.Params, ", ") + ") = " + n.Body.String()
+}
+
+func (n *Block) String() string {
+	stmts := make([]string, 0, len(n.Stmts))
+	for _, stmt := range n.Stmts {
+		stmts = append(stmts, stmt.String())
+	}
+	return strings.Join(stmts, "; ")
+}
+
+// wrapOperand расставляет скобки только там, где без них изменится порядок
+// вычисления. parentOp - пустая строка для унарного минуса. Приоритет
+// операторов, зарегистрированных в Calculator, неизвестен, поэтому их
+// операнды всегда берутся в скобки.
+func wrapOperand(operand Node, parentOp string, isRight bool) string {
+	parentPrec, parentAssoc, parentKnown := unaryPrecedence, LeftAssoc, true
+	if parentOp != "" {
+		parentPrec, parentAssoc, parentKnown = builtinPrecedence(parentOp)
+	}
+
+	var prec int
+	known := true
+	switch n := operand.(type) {
+	case *BinaryOp:
+		prec, _, known = builtinPrecedence(n.Op)
+	case *UnaryOp:
+		prec = unaryPrecedence
+	case *Convert:
+		prec = conversionPrecedence
+	default:
+		return operand.String()
+	}
+
+	sameSideAssoc := (parentAssoc == LeftAssoc) != isRight
+	if !known || !parentKnown || prec < parentPrec || (prec == parentPrec && !sameSideAssoc) {
+		return "(" + operand.String() + ")"
+	}
+	return operand.String()
+}
+
+func builtinPrecedence(op string) (int, Associativity, bool) {
+	if operator, ok := builtinOperators[op]; ok {
+		return operator.Precedence, operator.Associativity, true
+	}
+	if operator, ok := bitwiseOperators[op]; ok {
+		return operator.Precedence, operator.Associativity, true
+	}
+	return 0, LeftAssoc, false
+}
+
+// Inspect обходит дерево в глубину, вызывая f для каждого узла.
+// Если f возвращает false, потомки узла не посещаются.
+func Inspect(node Node, f func(Node) bool) {
+	if node == nil || !f(node) {
+		return
+	}
+
+	switch n := node.(type) {
+	case *UnaryOp:
+		Inspect(n.X, f)
+	case *BinaryOp:
+		Inspect(n.X, f)
+		Inspect(n.Y, f)
+	case *FuncCall:
+		for _, arg := range n.Args {
+			Inspect(arg, f)
+		}
+	case *List:
+		for _, elem := range n.Elems {
+			Inspect(elem, f)
+		}
+	case *AngleLit:
+		Inspect(n.Value, f)
+	case *QuantityLit:
+		Inspect(n.Value, f)
+		for _, unit := range n.Units {
+			Inspect(unit, f)
+		}
+	case *Convert:
+		Inspect(n.X, f)
+		Inspect(n.Target, f)
+	case *Assign:
+		Inspect(n.Value, f)
+	case *FuncDef:
+		Inspect(n.Body, f)
+	case *Block:
+		for _, stmt := range n.Stmts {
+			Inspect(stmt, f)
+		}
+	}
+}
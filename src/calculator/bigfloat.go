@@ -0,0 +1,365 @@
+package calculator
+
+import (
+	"errors"
+	"math"
+	"math/big"
+)
+
+var errNegativePower = errors.New("negative number to a non-integer power")
+
+// BigFloat - результат вычисления с произвольной точностью (CalculatorConfig.Precision)
+type BigFloat struct {
+	f *big.Float
+}
+
+// Big возвращает копию значения
+func (b BigFloat) Big() *big.Float {
+	return new(big.Float).Copy(b.f)
+}
+
+// String печатает все значащие десятичные цифры, которые даёт точность
+func (b BigFloat) String() string {
+	return b.f.Text('g', significantDigits(b.f.Prec()))
+}
+
+func (b BigFloat) Float64() (float64, bool) {
+	f, _ := b.f.Float64()
+	return f, !math.IsInf(f, 0)
+}
+
+// significantDigits - число десятичных цифр, которые гарантированно
+// представимы при точности prec бит
+func significantDigits(prec uint) int {
+	return max(1, int(float64(prec)*math.Log10(2)))
+}
+
+// bigDomain - вычисления над big.Float с точностью prec бит
+type bigDomain struct {
+	prec  uint
+	angle AngleUnit
+}
+
+func newBigDomain(config CalculatorConfig) *bigDomain {
+	return &bigDomain{prec: config.Precision, angle: config.AngleUnits}
+}
+
+func (d *bigDomain) number(n *NumberLit) (*big.Float, error) {
+	if n.Text == "" {
+		return d.fromFloatValue(n.Value), nil
+	}
+	f, ok := newBig(d.prec).SetString(n.Text)
+	if !ok {
+		return nil, errorAt(n.Span, "invalid number: %s", n.Text)
+	}
+	return f, nil
+}
+
+func (d *bigDomain) constant(name string) (*big.Float, error) {
+	if name == "e" {
+		return bigE(d.prec), nil
+	}
+	return bigPi(d.prec), nil
+}
+
+func (d *bigDomain) fromFloatValue(x float64) *big.Float {
+	return newBig(d.prec).SetFloat64(x)
+}
+
+func (d *bigDomain) fromFloat(x float64) (*big.Float, bool) {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return nil, false
+	}
+	return d.fromFloatValue(x), true
+}
+
+func (d *bigDomain) approx(x float64) (*big.Float, error) {
+	value, ok := d.fromFloat(x)
+	if !ok {
+		return nil, newError("result %v is not representable", x)
+	}
+	return value, nil
+}
+
+func (d *bigDomain) toFloat(x *big.Float) (float64, bool) {
+	return BigFloat{x}.Float64()
+}
+
+func (d *bigDomain) neg(x *big.Float) (*big.Float, error) {
+	return newBig(d.prec).Neg(x), nil
+}
+
+func (d *bigDomain) binary(op string, x, y *big.Float) (*big.Float, error) {
+	z := newBig(d.prec)
+	switch op {
+	case "+":
+		z.Add(x, y)
+	case "-":
+		z.Sub(x, y)
+	case "*":
+		z.Mul(x, y)
+	case "/":
+		if y.Sign() == 0 {
+			return nil, errDivisionByZero
+		}
+		z.Quo(x, y)
+	case "^":
+		var err error
+		if z, err = bigPow(x, y, d.prec); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errUnsupported
+	}
+
+	if z.IsInf() {
+		return nil, errOverflow
+	}
+	return z, nil
+}
+
+func (d *bigDomain) call(name string, args []*big.Float) (*big.Float, error) {
+	x := args[0]
+	switch name {
+	case "sqrt":
+		if x.Sign() < 0 {
+			return nil, errSqrtDomain
+		}
+		return newBig(d.prec).Sqrt(x), nil
+	case "ln":
+		if x.Sign() <= 0 {
+			return nil, errLnDomain
+		}
+		return bigLn(x, d.prec), nil
+	case "exp":
+		return bigExp(x, d.prec)
+	case "sin", "cos", "tg", "ctg":
+		return d.trig(name, d.toRadians(x))
+	case "log", "log10", "log2":
+		base, x := logArgs(name, args, d.fromFloatValue)
+		if base.Sign() <= 0 || base.Cmp(bigInt(1, d.prec)) == 0 {
+			return nil, errLogBase
+		}
+		if x.Sign() <= 0 {
+			return nil, errLogDomain
+		}
+		p := d.prec + guardBits
+		result := bigLn(x, p)
+		return newBig(d.prec).Quo(result, bigLn(base, p)), nil
+	case "atan2":
+		return d.fromRadians(bigAtan2(args[0], args[1], d.prec+guardBits)), nil
+	case "arcsin", "arccos", "arctg", "arcctg":
+		angle, err := d.inverseTrig(name, x)
+		if err != nil {
+			return nil, err
+		}
+		return d.fromRadians(angle), nil
+	case "sinh", "cosh", "tanh", "coth", "arsinh", "arcosh", "artanh", "arcoth":
+		return d.hyperbolic(name, x)
+	case "max", "min":
+		result := x
+		for _, arg := range args[1:] {
+			if c := arg.Cmp(result); c > 0 && name == "max" || c < 0 && name == "min" {
+				result = arg
+			}
+		}
+		return newBig(d.prec).Set(result), nil
+	case "stdev", "stdevp":
+		variance := newBig(d.prec + guardBits).SetRat(ratVariance(name == "stdevp", d.rats(args)))
+		return newBig(d.prec).Sqrt(variance), nil
+	case "geomean":
+		// exp от среднего логарифмов, как в float64
+		p := d.prec + guardBits
+		sum := newBig(p)
+		for _, arg := range args {
+			if arg.Sign() <= 0 {
+				return nil, newError("geometric mean of non-positive number")
+			}
+			sum.Add(sum, bigLn(arg, p))
+		}
+		result, err := bigExp(sum.Quo(sum, newBig(p).SetInt64(int64(len(args)))), p)
+		if err != nil {
+			return nil, err
+		}
+		return newBig(d.prec).Set(result), nil
+	}
+	return d.exact(name, args)
+}
+
+// exact вычисляет через дроби функции с рациональным результатом: floor, gcd, nCr, mean
+func (d *bigDomain) exact(name string, args []*big.Float) (*big.Float, error) {
+	result, err := ratFunction(name, d.rats(args))
+	if err != nil {
+		return nil, err
+	}
+	return newBig(d.prec).SetRat(result), nil
+}
+
+// rats переводит значения в дроби без потери точности
+func (d *bigDomain) rats(args []*big.Float) []*big.Rat {
+	rats := make([]*big.Rat, 0, len(args))
+	for _, arg := range args {
+		r, _ := arg.Rat(nil)
+		rats = append(rats, r)
+	}
+	return rats
+}
+
+func (d *bigDomain) trig(name string, x *big.Float) (*big.Float, error) {
+	sin, cos := bigSinCos(x, d.prec+guardBits)
+	// sin(pi) и cos(pi/2) дают остаток порядка погрешности, это ноль
+	if d.isZero(sin, x) {
+		sin.SetInt64(0)
+	}
+	if d.isZero(cos, x) {
+		cos.SetInt64(0)
+	}
+
+	switch name {
+	case "sin":
+		return newBig(d.prec).Set(sin), nil
+	case "cos":
+		return newBig(d.prec).Set(cos), nil
+	case "tg":
+		if cos.Sign() == 0 {
+			return nil, newError("tangent of pi/2 * k")
+		}
+		return newBig(d.prec).Quo(sin, cos), nil
+	default:
+		if sin.Sign() == 0 {
+			return nil, newError("cotangent of pi * k")
+		}
+		return newBig(d.prec).Quo(cos, sin), nil
+	}
+}
+
+// inverseTrig вычисляет обратные тригонометрические функции в радианах
+// с запасом точности guardBits
+func (d *bigDomain) inverseTrig(name string, x *big.Float) (*big.Float, error) {
+	p := d.prec + guardBits
+	switch name {
+	case "arctg":
+		return bigAtan(x, p), nil
+	case "arcctg":
+		// arcctg x = pi/2 - arctg x, значения в (0, pi)
+		result := bigPi(p)
+		result.SetMantExp(result, -1)
+		return result.Sub(result, bigAtan(x, p)), nil
+	}
+
+	one := bigInt(1, p)
+	if newBig(p).Abs(x).Cmp(one) > 0 {
+		return nil, outsideUnitError(name)
+	}
+	// sqrt(1 - x^2) = sqrt((1 - x)(1 + x)) без потери точности около ±1
+	c := newBig(p).Sub(one, x)
+	c.Mul(c, newBig(p).Add(one, x))
+	c.Sqrt(c)
+	if name == "arcsin" {
+		return bigAtan2(x, c, p), nil
+	}
+	return bigAtan2(c, x, p), nil
+}
+
+// hyperbolic вычисляет гиперболические функции через exp и обратные к ним
+// через ln. Около нуля в формулах вычитаются близкие числа, поэтому точность
+// увеличивается на число ведущих нулей аргумента.
+func (d *bigDomain) hyperbolic(name string, x *big.Float) (*big.Float, error) {
+	p := d.prec + guardBits
+	if x.Sign() != 0 {
+		p += uint(max(0, -x.MantExp(nil)))
+	}
+	if name == "arcoth" {
+		// (x + 1)/(x - 1) близко к 1 при больших x
+		p += uint(max(0, x.MantExp(nil)))
+	}
+	one := bigInt(1, p)
+	abs := newBig(p).Abs(x)
+	half := func(v *big.Float) *big.Float {
+		return newBig(d.prec).Set(v.SetMantExp(v, -1))
+	}
+
+	switch name {
+	case "sinh", "cosh", "tanh", "coth":
+		if name == "coth" && x.Sign() == 0 {
+			return nil, newError("hyperbolic cotangent of zero")
+		}
+		e, err := bigExp(x, p)
+		if err != nil {
+			return nil, err
+		}
+		inv := newBig(p).Quo(one, e)
+		sinh := newBig(p).Sub(e, inv)
+		cosh := newBig(p).Add(e, inv)
+		switch name {
+		case "sinh":
+			return half(sinh), nil
+		case "cosh":
+			return half(cosh), nil
+		case "tanh":
+			return newBig(d.prec).Quo(sinh, cosh), nil
+		}
+		return newBig(d.prec).Quo(cosh, sinh), nil
+	case "arsinh":
+		// arsinh x = sign(x) ln(|x| + sqrt(x^2 + 1))
+		r := newBig(p).Mul(abs, abs)
+		r.Add(r, one).Sqrt(r).Add(r, abs)
+		result := bigLn(r, p)
+		if x.Sign() < 0 {
+			result.Neg(result)
+		}
+		return newBig(d.prec).Set(result), nil
+	case "arcosh":
+		if x.Cmp(one) < 0 {
+			return nil, errAcoshDomain
+		}
+		r := newBig(p).Mul(x, x)
+		r.Sub(r, one).Sqrt(r).Add(r, x)
+		return newBig(d.prec).Set(bigLn(r, p)), nil
+	}
+
+	// artanh x = ln((1 + x)/(1 - x)) / 2, arcoth x = artanh(1/x)
+	if name == "artanh" && abs.Cmp(one) >= 0 {
+		return nil, errAtanhDomain
+	}
+	if name == "arcoth" && abs.Cmp(one) <= 0 {
+		return nil, errAcothDomain
+	}
+	num, den := newBig(p).Add(one, x), newBig(p).Sub(one, x)
+	if name == "arcoth" {
+		num, den = newBig(p).Add(x, one), newBig(p).Sub(x, one)
+	}
+	return half(bigLn(num.Quo(num, den), p)), nil
+}
+
+// isZero проверяет, что значение синуса или косинуса от x неотличимо
+// от нуля при заданной точности
+func (d *bigDomain) isZero(v, x *big.Float) bool {
+	return v.Sign() == 0 || x.Sign() != 0 && v.MantExp(nil) <= x.MantExp(nil)-int(d.prec)
+}
+
+func (d *bigDomain) toRadians(x *big.Float) *big.Float {
+	if d.angle.isRadian() {
+		return x
+	}
+	p := d.prec + guardBits
+	result := newBig(p).Mul(x, bigPi(p))
+	return result.Quo(result, newBig(p).SetFloat64(d.angle.halfTurn()))
+}
+
+func (d *bigDomain) fromRadians(x *big.Float) *big.Float {
+	if d.angle.isRadian() {
+		return newBig(d.prec).Set(x)
+	}
+	p := d.prec + guardBits
+	result := newBig(p).Mul(x, newBig(p).SetFloat64(d.angle.halfTurn()))
+	return newBig(d.prec).Quo(result, bigPi(p))
+}
+
+func (d *bigDomain) list(elems []*big.Float) (*big.Float, error) {
+	return nil, errUnsupported
+}
+
+func (d *bigDomain) value(x *big.Float) Value {
+	return BigFloat{x}
+}
@@ -0,0 +1,105 @@
+package calculator
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrecision(t *testing.T) {
+	type CasePrecision struct {
+		expression string
+		angleUnits AngleUnit
+		result     string
+		err        string
+	}
+	cases := []CasePrecision{
+		{expression: "0.1 + 0.2", result: "0.3"},
+		{expression: "1/3", result: "0.33333333333333333333333333333333333333333333333333333333333333333333333333333"},
+		{expression: "2^100", result: "1267650600228229401496703205376"},
+		{expression: "2^-3", result: "0.125"},
+		{expression: "pi", result: "3.1415926535897932384626433832795028841971693993751058209749445923078164062862"},
+		{expression: "e", result: "2.7182818284590452353602874713526624977572470936999595749669676277240766303536"},
+		{expression: "sqrt(2)", result: "1.4142135623730950488016887242096980785696718753769480731766797379907324784621"},
+		{expression: "ln(2)", result: "0.6931471805599453094172321214581765680755001343602552541206800094933936219697"},
+		{expression: "exp(1) - e", result: "0"},
+		{expression: "sin(1)", result: "0.84147098480789650665250232163029899962256306079837106567275170999191040439124"},
+		{expression: "cos(1)", result: "0.54030230586813971740093660744297660373231042061792222767009725538110039477448"},
+		{expression: "tg(1)", result: "1.5574077246549022305069748074583601730872507723815200383839466056988613971517"},
+		{expression: "sin(pi)", result: "0"},
+		{expression: "4 * atan2(1, 1) - pi", result: "0"},
+		{expression: "log(2, 1024)", result: "10"},
+		{expression: "max(1, 1/3, 0.5)", result: "1"},
+		{expression: "sin(30)", angleUnits: "degree", result: "0.5"},
+		{expression: "tg(45)", angleUnits: "degree", result: "1"},
+		{expression: "atan2(1, 1)", angleUnits: "degree", result: "45"},
+		{expression: "f(x) = x^2 + 1; a = 1/4; f(a)", result: "1.0625"},
+		{expression: "exp(1000)", result: "1.9700711140170469938888793522433231253169379853238457899528029913850638507825e+434"},
+		{expression: "1e400 / 4", result: "2.5e+399"},
+		{expression: "0xff + 1", result: "256"},
+
+		{expression: "1 / 0", err: "1:1: calculating division: division by zero"},
+		{expression: "sqrt(-1)", err: "1:1: calculating sqrt: square root of negative number"},
+		{expression: "ln(0)", err: "1:1: calculating ln: natural logarithm of non-positive number"},
+		{expression: "tg(pi/2)", err: "calculating tg: tangent of pi/2 * k"},
+		{expression: "ctg(180)", angleUnits: "degree", err: "calculating ctg: cotangent of pi * k"},
+		{expression: "(-8)^(1/3)", err: "calculating power: negative number to a non-integer power"},
+		{expression: "x + 1", err: "1:1: undefined variable x"},
+		{expression: "sin(1, 2)", err: "function sin expects 1 argument, got 2"},
+	}
+
+	for _, c := range cases {
+		result, err := Evaluate(c.expression, CalculatorConfig{AngleUnits: c.angleUnits, Precision: 256})
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.result, result.String(), c.expression)
+	}
+}
+
+func TestPrecisionMatchesFloat(t *testing.T) {
+	for _, c := range benchCases[:4] {
+		expected, err := Calculate(c.expression, CalculatorConfig{AngleUnits: "radian"})
+		require.NoError(t, err)
+
+		result, err := Calculate(c.expression, CalculatorConfig{AngleUnits: "radian", Precision: 128})
+		require.NoError(t, err, c.expression)
+		require.InDelta(t, expected, result, 1e-9*math.Max(1, math.Abs(expected)), c.expression)
+	}
+}
+
+func TestPrecisionDigits(t *testing.T) {
+	for _, prec := range []uint{64, 256, 1024} {
+		value, err := Evaluate("pi", CalculatorConfig{Precision: prec})
+		require.NoError(t, err)
+
+		pi := value.(BigFloat).Big()
+		require.Equal(t, prec, pi.Prec())
+		// "3." и цифры, последние нули отбрасываются
+		require.LessOrEqual(t, len(value.String()), significantDigits(prec)+1)
+
+		// точность не хуже половины последнего бита
+		exact, _ := Evaluate("pi", CalculatorConfig{Precision: prec + 256})
+		diff := new(big.Float).Sub(pi, exact.(BigFloat).Big())
+		require.LessOrEqual(t, diff.Abs(diff).MantExp(nil), pi.MantExp(nil)-int(prec))
+	}
+}
+
+func TestPrecisionCustomFunctions(t *testing.T) {
+	calc := NewCalculator(CalculatorConfig{Precision: 256})
+	require.NoError(t, calc.RegisterFunction("half", 1, func(args ...float64) (float64, error) {
+		return args[0] / 2, nil
+	}))
+	require.NoError(t, calc.RegisterOperator("%", 6, LeftAssoc, func(a, b float64) (float64, error) {
+		return math.Mod(a, b), nil
+	}))
+
+	result, err := calc.Evaluate("half(7 % 4) + 1")
+	require.NoError(t, err)
+	require.Equal(t, "2.5", result.String())
+}
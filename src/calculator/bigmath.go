@@ -0,0 +1,358 @@
+package calculator
+
+import (
+	"math"
+	"math/big"
+	"sync"
+)
+
+// Элементарные функции над big.Float. Каждая функция считает с запасом
+// guardBits бит и округляет результат до prec.
+const guardBits = 64
+
+// сколько раз аргумент делится пополам перед рядом Тейлора
+const halvings = 8
+
+var (
+	constMu    sync.Mutex
+	piCache    = map[uint]*big.Float{}
+	ln2Cache   = map[uint]*big.Float{}
+	eulerCache = map[uint]*big.Float{}
+)
+
+func newBig(prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec)
+}
+
+func bigInt(n int64, prec uint) *big.Float {
+	return newBig(prec).SetInt64(n)
+}
+
+// negligible проверяет, что term уже не влияет на sum при точности prec
+func negligible(term, sum *big.Float, prec uint) bool {
+	if term.Sign() == 0 {
+		return true
+	}
+	if sum.Sign() == 0 {
+		return false
+	}
+	return term.MantExp(nil) < sum.MantExp(nil)-int(prec)-1
+}
+
+// cached возвращает константу из кэша или вычисляет её. Вычисление идёт
+// без блокировки: e вычисляется через ln 2, который тоже кэшируется.
+func cached(cache map[uint]*big.Float, prec uint, compute func(prec uint) *big.Float) *big.Float {
+	constMu.Lock()
+	c, ok := cache[prec]
+	constMu.Unlock()
+	if ok {
+		return newBig(prec).Set(c)
+	}
+
+	c = newBig(prec).Set(compute(prec + guardBits))
+	constMu.Lock()
+	cache[prec] = c
+	constMu.Unlock()
+	return newBig(prec).Set(c)
+}
+
+// bigPi вычисляет pi по формуле Мэчина: pi = 16 atan(1/5) - 4 atan(1/239)
+func bigPi(prec uint) *big.Float {
+	return cached(piCache, prec, func(p uint) *big.Float {
+		a := atanInv(5, p)
+		a.Mul(a, bigInt(16, p))
+		b := atanInv(239, p)
+		b.Mul(b, bigInt(4, p))
+		return a.Sub(a, b)
+	})
+}
+
+// bigLn2 вычисляет ln 2 = 2 atanh(1/3)
+func bigLn2(prec uint) *big.Float {
+	return cached(ln2Cache, prec, func(p uint) *big.Float {
+		x := newBig(p).Quo(bigInt(1, p), bigInt(3, p))
+		ln2 := atanhSeries(x, p)
+		return ln2.Mul(ln2, bigInt(2, p))
+	})
+}
+
+// bigE вычисляет e = exp(1)
+func bigE(prec uint) *big.Float {
+	return cached(eulerCache, prec, func(p uint) *big.Float {
+		e, _ := bigExp(bigInt(1, p), p)
+		return e
+	})
+}
+
+// atanInv вычисляет atan(1/n) рядом Тейлора
+func atanInv(n int64, prec uint) *big.Float {
+	x := newBig(prec).Quo(bigInt(1, prec), bigInt(n, prec))
+	return atanSeries(x, prec)
+}
+
+// atanSeries - ряд x - x^3/3 + x^5/5 - ..., сходится при |x| < 1
+func atanSeries(x *big.Float, prec uint) *big.Float {
+	x2 := newBig(prec).Mul(x, x)
+	sum := newBig(prec).Set(x)
+	power := newBig(prec).Set(x)
+	term := newBig(prec)
+	for k := int64(1); ; k++ {
+		power.Mul(power, x2)
+		term.Quo(power, bigInt(2*k+1, prec))
+		if k%2 == 1 {
+			sum.Sub(sum, term)
+		} else {
+			sum.Add(sum, term)
+		}
+		if negligible(term, sum, prec) {
+			return sum
+		}
+	}
+}
+
+// atanhSeries - ряд x + x^3/3 + x^5/5 + ..., сходится при |x| < 1
+func atanhSeries(x *big.Float, prec uint) *big.Float {
+	x2 := newBig(prec).Mul(x, x)
+	sum := newBig(prec).Set(x)
+	power := newBig(prec).Set(x)
+	term := newBig(prec)
+	for k := int64(1); ; k++ {
+		power.Mul(power, x2)
+		term.Quo(power, bigInt(2*k+1, prec))
+		sum.Add(sum, term)
+		if negligible(term, sum, prec) {
+			return sum
+		}
+	}
+}
+
+// bigExp вычисляет e^x: x = k ln2 + r, e^x = 2^k e^r, а e^r считается
+// рядом Тейлора от r / 2^halvings с последующим возведением в квадрат
+func bigExp(x *big.Float, prec uint) (*big.Float, error) {
+	if x.Sign() == 0 {
+		return bigInt(1, prec), nil
+	}
+	if x.IsInf() {
+		return nil, errOverflow
+	}
+
+	p := prec + guardBits + uint(max(x.MantExp(nil), 0))
+	ln2 := bigLn2(p)
+
+	kf, _ := newBig(p).Quo(x, ln2).Float64()
+	if math.Abs(kf) > math.MaxInt32/2 {
+		if kf < 0 {
+			return newBig(prec), nil
+		}
+		return nil, errOverflow
+	}
+	k := int64(math.Round(kf))
+
+	r := newBig(p).Mul(ln2, bigInt(k, p))
+	r.Sub(x, r)
+	r.SetMantExp(r, -halvings)
+
+	sum := bigInt(1, p)
+	term := bigInt(1, p)
+	for n := int64(1); ; n++ {
+		term.Mul(term, r)
+		term.Quo(term, bigInt(n, p))
+		sum.Add(sum, term)
+		if negligible(term, sum, p) {
+			break
+		}
+	}
+	for range halvings {
+		sum.Mul(sum, sum)
+	}
+
+	// SetMantExp копирует точность sum, поэтому округляем отдельно
+	sum.SetMantExp(sum, int(k))
+	if sum.IsInf() {
+		return nil, errOverflow
+	}
+	return newBig(prec).Set(sum), nil
+}
+
+// bigLn вычисляет ln x для x > 0: x = m 2^k, m в [0.5, 1),
+// ln m = 2 atanh((m-1)/(m+1))
+func bigLn(x *big.Float, prec uint) *big.Float {
+	p := prec + guardBits
+	m := newBig(p)
+	k := x.MantExp(m)
+
+	num := newBig(p).Sub(m, bigInt(1, p))
+	den := newBig(p).Add(m, bigInt(1, p))
+	result := atanhSeries(num.Quo(num, den), p)
+	result.Mul(result, bigInt(2, p))
+
+	ln2 := bigLn2(p)
+	result.Add(result, ln2.Mul(ln2, bigInt(int64(k), p)))
+	return newBig(prec).Set(result)
+}
+
+// bigSinCos вычисляет sin x и cos x: аргумент приводится к [-pi, pi],
+// делится на 2^halvings, после ряда Тейлора применяются формулы двойного угла
+func bigSinCos(x *big.Float, prec uint) (sin, cos *big.Float) {
+	p := prec + guardBits + uint(max(x.MantExp(nil), 0))
+
+	twoPi := bigPi(p)
+	twoPi.Mul(twoPi, bigInt(2, p))
+	q := newBig(p).Quo(x, twoPi)
+	half := newBig(p).SetFloat64(0.5)
+	if q.Sign() < 0 {
+		half.Neg(half)
+	}
+	n, _ := q.Add(q, half).Int(nil)
+
+	r := newBig(p).Mul(twoPi, newBig(p).SetInt(n))
+	r.Sub(x, r)
+	r.SetMantExp(r, -halvings)
+
+	r2 := newBig(p).Mul(r, r)
+	sin = newBig(p).Set(r)
+	cos = bigInt(1, p)
+	sinTerm := newBig(p).Set(r)
+	cosTerm := bigInt(1, p)
+	for k := int64(1); ; k++ {
+		sinTerm.Mul(sinTerm, r2)
+		sinTerm.Quo(sinTerm, bigInt(-(2*k)*(2*k+1), p))
+		sin.Add(sin, sinTerm)
+		cosTerm.Mul(cosTerm, r2)
+		cosTerm.Quo(cosTerm, bigInt(-(2*k-1)*(2*k), p))
+		cos.Add(cos, cosTerm)
+		if negligible(sinTerm, sin, p) && negligible(cosTerm, cos, p) {
+			break
+		}
+	}
+
+	two := bigInt(2, p)
+	for range halvings {
+		// sin 2a = 2 sin a cos a, cos 2a = cos^2 a - sin^2 a
+		s := newBig(p).Mul(sin, cos)
+		s.Mul(s, two)
+		c := newBig(p).Mul(cos, cos)
+		c.Sub(c, newBig(p).Mul(sin, sin))
+		sin, cos = s, c
+	}
+
+	return newBig(prec).Set(sin), newBig(prec).Set(cos)
+}
+
+// bigAtan вычисляет atan x: при |x| > 1 используется atan x = pi/2 - atan(1/x),
+// аргумент уменьшается формулой atan x = 2 atan(x / (1 + sqrt(1 + x^2)))
+func bigAtan(x *big.Float, prec uint) *big.Float {
+	if x.Sign() == 0 {
+		return newBig(prec)
+	}
+
+	p := prec + guardBits
+	one := bigInt(1, p)
+	a := newBig(p).Abs(x)
+	invert := a.Cmp(one) > 0
+	if invert {
+		a.Quo(one, a)
+	}
+
+	for range halvings {
+		d := newBig(p).Mul(a, a)
+		d.Add(d, one)
+		d.Sqrt(d)
+		d.Add(d, one)
+		a.Quo(a, d)
+	}
+	result := atanSeries(a, p)
+	result.SetMantExp(result, halvings)
+
+	if invert {
+		halfPi := bigPi(p)
+		halfPi.SetMantExp(halfPi, -1)
+		result.Sub(halfPi, result)
+	}
+	if x.Sign() < 0 {
+		result.Neg(result)
+	}
+	return newBig(prec).Set(result)
+}
+
+// bigAtan2 - угол точки (x, y) в диапазоне (-pi, pi]
+func bigAtan2(y, x *big.Float, prec uint) *big.Float {
+	p := prec + guardBits
+	if x.Sign() == 0 {
+		if y.Sign() == 0 {
+			return newBig(prec)
+		}
+		halfPi := bigPi(p)
+		halfPi.SetMantExp(halfPi, -1)
+		if y.Sign() < 0 {
+			halfPi.Neg(halfPi)
+		}
+		return newBig(prec).Set(halfPi)
+	}
+
+	result := bigAtan(newBig(p).Quo(y, x), p)
+	if x.Sign() < 0 {
+		pi := bigPi(p)
+		if y.Sign() < 0 {
+			result.Sub(result, pi)
+		} else {
+			result.Add(result, pi)
+		}
+	}
+	return newBig(prec).Set(result)
+}
+
+// bigPow вычисляет x^y. Целые степени считаются точно, для остальных
+// x^y = exp(y ln x), что требует x > 0.
+func bigPow(x, y *big.Float, prec uint) (*big.Float, error) {
+	if y.IsInt() {
+		n, _ := y.Int(nil)
+		if n.IsInt64() && math.Abs(float64(n.Int64())) <= math.MaxInt32 {
+			return bigPowInt(x, n.Int64(), prec)
+		}
+	}
+
+	switch x.Sign() {
+	case 0:
+		if y.Sign() < 0 {
+			return nil, errDivisionByZero
+		}
+		return newBig(prec), nil
+	case -1:
+		return nil, errNegativePower
+	}
+
+	p := prec + guardBits
+	exponent := bigLn(x, p)
+	exponent.Mul(exponent, y)
+	return bigExp(exponent, prec)
+}
+
+// bigPowInt - возведение в целую степень двоичным методом
+func bigPowInt(x *big.Float, n int64, prec uint) (*big.Float, error) {
+	p := prec + guardBits
+	negative := n < 0
+	if negative {
+		if x.Sign() == 0 {
+			return nil, errDivisionByZero
+		}
+		n = -n
+	}
+
+	result := bigInt(1, p)
+	base := newBig(p).Set(x)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			result.Mul(result, base)
+		}
+		base.Mul(base, base)
+		if result.IsInf() || base.IsInf() && n > 1 {
+			return nil, errOverflow
+		}
+	}
+
+	if negative {
+		result.Quo(bigInt(1, p), result)
+	}
+	return newBig(prec).Set(result), nil
+}
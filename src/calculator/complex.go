@@ -0,0 +1,389 @@
+package calculator
+
+import (
+	"errors"
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+)
+
+// относительная погрешность, ниже которой часть комплексного числа
+// при печати считается нулём: exp(i*pi) печатается как -1
+const complexPrintEpsilon = 4 * 0x1p-52
+
+// ComplexNumber - результат вычисления в режиме Complex
+type ComplexNumber struct {
+	z     complex128
+	polar bool
+	angle AngleUnit
+}
+
+// Complex возвращает значение как complex128
+func (c ComplexNumber) Complex() complex128 {
+	return c.z
+}
+
+// String печатает число в виде a+bi или, если включён PolarOutput, r∠θ
+func (c ComplexNumber) String() string {
+	return c.Text(formatFloat)
+}
+
+// Text печатает значение, форматируя числа функцией number
+func (c ComplexNumber) Text(number func(float64) string) string {
+	re, im := real(c.z), imag(c.z)
+	if magnitude := cmplx.Abs(c.z); !math.IsInf(magnitude, 0) {
+		if math.Abs(re) < magnitude*complexPrintEpsilon {
+			re = 0
+		}
+		if math.Abs(im) < magnitude*complexPrintEpsilon {
+			im = 0
+		}
+	}
+
+	if c.polar {
+		theta := math.Atan2(im, re)
+		theta = c.angle.fromRadians(theta)
+		return number(math.Hypot(re, im)) + "∠" + number(theta)
+	}
+
+	if im == 0 {
+		return number(re)
+	}
+
+	var b strings.Builder
+	if re != 0 {
+		b.WriteString(number(re))
+		if im > 0 {
+			b.WriteByte('+')
+		}
+	}
+	// коэффициент 1 опускается, только если number печатает его как "1";
+	// в записи с фиксированными знаками он печатается как 1.00, как и
+	// вещественная часть
+	switch coef := number(im); coef {
+	case "1":
+	case "-1":
+		b.WriteByte('-')
+	default:
+		b.WriteString(coef)
+	}
+	b.WriteByte('i')
+	return b.String()
+}
+
+func (c ComplexNumber) Float64() (float64, bool) {
+	return real(c.z), imag(c.z) == 0
+}
+
+func formatFloat(x float64) string {
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
+
+// complexDomain - вычисления над complex128
+type complexDomain struct {
+	angle AngleUnit
+	polar bool
+}
+
+func newComplexDomain(config CalculatorConfig) *complexDomain {
+	return &complexDomain{angle: config.AngleUnits, polar: config.PolarOutput}
+}
+
+func (d *complexDomain) number(n *NumberLit) (complex128, error) {
+	x, err := n.float()
+	if err != nil {
+		return 0, err
+	}
+	if n.Imaginary {
+		return complex(0, x), nil
+	}
+	return complex(x, 0), nil
+}
+
+func (d *complexDomain) constant(name string) (complex128, error) {
+	switch name {
+	case "i":
+		return 1i, nil
+	case "e":
+		return math.E, nil
+	}
+	return math.Pi, nil
+}
+
+func (d *complexDomain) fromFloat(x float64) (complex128, bool) {
+	return complex(x, 0), !math.IsNaN(x)
+}
+
+func (d *complexDomain) approx(x float64) (complex128, error) {
+	if math.IsNaN(x) {
+		return 0, errNotANumber
+	}
+	return complex(x, 0), nil
+}
+
+func (d *complexDomain) toFloat(z complex128) (float64, bool) {
+	return real(z), imag(z) == 0
+}
+
+// neg не даёт отрицательных нулей: -4 должно быть -4+0i, а не -4-0i,
+// иначе sqrt(-4) окажется по другую сторону разреза и станет -2i
+func (d *complexDomain) neg(z complex128) (complex128, error) {
+	return complex(-real(z)+0, -imag(z)+0), nil
+}
+
+func (d *complexDomain) binary(op string, x, y complex128) (complex128, error) {
+	var z complex128
+	switch op {
+	case "+":
+		z = x + y
+	case "-":
+		z = x - y
+	case "*":
+		z = x * y
+	case "/":
+		if y == 0 {
+			return 0, errDivisionByZero
+		}
+		z = x / y
+	case "^":
+		z = complexPow(x, y)
+	default:
+		return 0, errUnsupported
+	}
+	return checkComplex(z)
+}
+
+func (d *complexDomain) call(name string, args []complex128) (complex128, error) {
+	z := args[0]
+	var result complex128
+	switch name {
+	case "sqrt":
+		result = cmplx.Sqrt(z)
+	case "ln":
+		if z == 0 {
+			return 0, errors.New("natural logarithm of zero")
+		}
+		result = cmplx.Log(z)
+	case "exp":
+		result = cmplx.Exp(z)
+	case "sin", "cos", "tg", "ctg":
+		return d.trig(name, d.toRadians(z))
+	case "arcsin", "arccos", "arctg", "arcctg":
+		angle, err := d.inverseTrig(name, z)
+		if err != nil {
+			return 0, err
+		}
+		result = d.fromRadians(angle)
+	case "sinh":
+		result = cmplx.Sinh(z)
+	case "cosh":
+		result = cmplx.Cosh(z)
+	case "tanh":
+		result = cmplx.Tanh(z)
+	case "coth":
+		if z == 0 {
+			return 0, errors.New("hyperbolic cotangent of zero")
+		}
+		result = 1 / cmplx.Tanh(z)
+	case "arsinh":
+		result = cmplx.Asinh(z)
+	case "arcosh":
+		result = cmplx.Acosh(z)
+	case "artanh":
+		if z == 1 || z == -1 {
+			return 0, errors.New("artanh of ±1")
+		}
+		result = cmplx.Atanh(z)
+	case "arcoth":
+		if z == 1 || z == -1 {
+			return 0, errors.New("arcoth of ±1")
+		}
+		if z == 0 {
+			// arcoth 0 = artanh(1/0) = i*pi/2
+			return complex(0, math.Pi/2), nil
+		}
+		result = cmplx.Atanh(1 / z)
+	case "log", "log10", "log2":
+		base, x := logArgs(name, args, func(x float64) complex128 { return complex(x, 0) })
+		if base == 0 || base == 1 {
+			return 0, errors.New("logarithm base must be non-zero and not equal to 1")
+		}
+		if x == 0 {
+			return 0, errors.New("logarithm of zero")
+		}
+		result = cmplx.Log(x) / cmplx.Log(base)
+	case "abs":
+		result = complex(cmplx.Abs(z), 0)
+	case "sign":
+		// z/|z|, для действительных чисел это -1, 0 или 1
+		if z != 0 {
+			result = z / complex(cmplx.Abs(z), 0)
+		}
+	case "re":
+		result = complex(real(z), 0)
+	case "im":
+		result = complex(imag(z), 0)
+	case "conj":
+		result = cmplx.Conj(z)
+	case "sum", "mean":
+		// действительные и мнимые части складываются отдельно, с компенсацией
+		re := make([]float64, 0, len(args))
+		im := make([]float64, 0, len(args))
+		for _, arg := range args {
+			re = append(re, real(arg))
+			im = append(im, imag(arg))
+		}
+		fn := Sum
+		if name == "mean" {
+			fn = Mean
+		}
+		x, err := fn(re...)
+		if err != nil {
+			return 0, err
+		}
+		y, err := fn(im...)
+		if err != nil {
+			return 0, err
+		}
+		result = complex(x, y)
+	default:
+		return 0, errUnsupported
+	}
+	return checkComplex(result)
+}
+
+func (d *complexDomain) trig(name string, z complex128) (complex128, error) {
+	// на действительной оси используются действительные функции,
+	// чтобы проверки полюсов совпадали с обычным режимом
+	if imag(z) == 0 {
+		var x float64
+		var err error
+		switch name {
+		case "sin":
+			x = Sin(real(z))
+		case "cos":
+			x = Cos(real(z))
+		case "tg":
+			x, err = Tg(real(z))
+		default:
+			x, err = Cot(real(z))
+		}
+		return complex(x, 0), err
+	}
+
+	var result complex128
+	switch name {
+	case "sin":
+		result = cmplx.Sin(z)
+	case "cos":
+		result = cmplx.Cos(z)
+	case "tg":
+		result = cmplx.Tan(z)
+	default:
+		result = cmplx.Cot(z)
+	}
+	return checkComplex(result)
+}
+
+// inverseTrig вычисляет обратные тригонометрические функции в радианах.
+// Для действительных аргументов из области определения используются
+// действительные функции, вне её значения комплексные: arcsin 2 = pi/2-1.317i
+func (d *complexDomain) inverseTrig(name string, z complex128) (complex128, error) {
+	if x := real(z); imag(z) == 0 {
+		switch {
+		case name == "arctg":
+			return complex(Atan(x), 0), nil
+		case name == "arcctg":
+			return complex(Acot(x), 0), nil
+		case name == "arcsin" && x >= -1 && x <= 1:
+			return complex(math.Asin(x), 0), nil
+		case name == "arccos" && x >= -1 && x <= 1:
+			return complex(math.Acos(x), 0), nil
+		}
+	}
+
+	var result complex128
+	switch name {
+	case "arcsin":
+		result = cmplx.Asin(z)
+	case "arccos":
+		result = cmplx.Acos(z)
+	default:
+		// у arctg и arcctg точки ±i - логарифмические особенности
+		if z == 1i || z == -1i {
+			return 0, newError("%s of ±i", name)
+		}
+		if name == "arctg" {
+			result = cmplx.Atan(z)
+		} else {
+			result = cmplx.Atan(1 / z)
+		}
+	}
+	return checkComplex(result)
+}
+
+func (d *complexDomain) fromRadians(z complex128) complex128 {
+	if d.angle.isRadian() {
+		return z
+	}
+	return z * complex(d.angle.halfTurn(), 0) / math.Pi
+}
+
+func (d *complexDomain) toRadians(z complex128) complex128 {
+	if d.angle.isRadian() {
+		return z
+	}
+	return z * math.Pi / complex(d.angle.halfTurn(), 0)
+}
+
+func (d *complexDomain) list(elems []complex128) (complex128, error) {
+	return 0, errUnsupported
+}
+
+func (d *complexDomain) value(z complex128) Value {
+	return ComplexNumber{z: z, polar: d.polar, angle: d.angle}
+}
+
+// complexPow возводит в степень. Для действительных чисел, где это
+// возможно, используется math.Pow, чтобы 2^3 было ровно 8, небольшие
+// целые степени считаются умножением, чтобы i^2 было ровно -1.
+func complexPow(x, y complex128) complex128 {
+	if imag(y) == 0 {
+		n := real(y)
+		if imag(x) == 0 && (real(x) >= 0 || n == math.Trunc(n)) {
+			return complex(math.Pow(real(x), n), 0)
+		}
+		if n == math.Trunc(n) && math.Abs(n) <= 1024 {
+			return complexPowInt(x, int(n))
+		}
+	}
+	if x == 0 && real(y) > 0 {
+		return 0
+	}
+	return cmplx.Pow(x, y)
+}
+
+func complexPowInt(x complex128, n int) complex128 {
+	if n < 0 {
+		return 1 / complexPowInt(x, -n)
+	}
+	result := complex128(1)
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			result *= x
+		}
+		x *= x
+	}
+	return result
+}
+
+func checkComplex(z complex128) (complex128, error) {
+	if cmplx.IsNaN(z) {
+		return 0, errNotANumber
+	}
+	if cmplx.IsInf(z) {
+		return 0, errOverflow
+	}
+	return z, nil
+}
@@ -0,0 +1,80 @@
+package calculator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComplex(t *testing.T) {
+	type CaseComplex struct {
+		expression string
+		config     CalculatorConfig
+		result     string
+		err        string
+	}
+	polar := CalculatorConfig{PolarOutput: true}
+	degrees := CalculatorConfig{AngleUnits: "degree"}
+	cases := []CaseComplex{
+		{expression: "sqrt(-4)", result: "2i"},
+		{expression: "ln(-1)", result: "3.141592653589793i"},
+		{expression: "i^2", result: "-1"},
+		{expression: "exp(i * pi)", result: "-1"},
+		{expression: "(1 + 2i) * (3 - i)", result: "5+5i"},
+		{expression: "1 / (2i)", result: "-0.5i"},
+		{expression: "-i", result: "-i"},
+		{expression: "2.5i - 1", result: "-1+2.5i"},
+		{expression: "2^10", result: "1024"},
+		{expression: "(-8)^(1/3)", result: "1+1.732050807568877i"},
+		{expression: "i^i", result: "0.20787957635076193"},
+		{expression: "sin(i)", result: "1.1752011936438014i"},
+		{expression: "cos(i)", result: "1.5430806348152437"},
+		{expression: "sin(30)", config: degrees, result: "0.49999999999999994"},
+		{expression: "log(2, 8)", result: "3"},
+		{expression: "re(3 + 4i) + im(3 + 4i)", result: "7"},
+		{expression: "z = 3 + 4i; z * conj(z)", result: "25"},
+		{expression: "f(z) = z^2 + 1; f(i)", result: "0"},
+		{expression: "max(1, 2) + i", result: "2+i"},
+		{expression: "1 + i", config: polar, result: "1.4142135623730951∠0.7853981633974483"},
+		{expression: "-2", config: CalculatorConfig{PolarOutput: true, AngleUnits: "degree"}, result: "2∠180"},
+
+		{expression: "1 / (i - i)", err: "1:1: calculating division: division by zero"},
+		{expression: "ln(0)", err: "1:1: calculating ln: natural logarithm of zero"},
+		{expression: "tg(pi/2)", err: "calculating tg: tangent of pi/2 * k"},
+		{expression: "ctg(0)", err: "calculating ctg: cotangent of pi * k"},
+		{expression: "atan2(i, 1)", err: "1:1: calculating atan2: operation is not supported for i"},
+		{expression: "i = 2", err: "1:1: cannot assign to constant i"},
+		{expression: "2 i", err: "unexpected token: i"},
+	}
+
+	for _, c := range cases {
+		c.config.Mode = Complex
+		result, err := Evaluate(c.expression, c.config)
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.result, result.String(), c.expression)
+	}
+}
+
+func TestComplexValue(t *testing.T) {
+	result, err := Evaluate("sqrt(-4) + 1", CalculatorConfig{Mode: Complex})
+	require.NoError(t, err)
+	require.Equal(t, complex(1, 2), result.(ComplexNumber).Complex())
+
+	_, err = Calculate("sqrt(-4)", CalculatorConfig{Mode: Complex})
+	require.ErrorContains(t, err, "does not fit in float64")
+
+	value, err := Calculate("(2i)^2", CalculatorConfig{Mode: Complex})
+	require.NoError(t, err)
+	require.Equal(t, -4.0, value)
+
+	// i - обычная переменная вне режима Complex
+	value, err = CalculateWithVars("2 * i", CalculatorConfig{}, map[string]float64{"i": math.Pi})
+	require.NoError(t, err)
+	require.Equal(t, 2*math.Pi, value)
+}
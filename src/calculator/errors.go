@@ -0,0 +1,204 @@
+package calculator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Стадии вычисления, на которых возникают ошибки; с них начинается текст ошибки
+var (
+	errParsing     = errors.New("error while parsing")
+	errCalculating = errors.New("error while calculating")
+)
+
+// Ошибки операций, общие для всех режимов; по ним KindOf определяет категорию
+var (
+	errOverflow       = errors.New("got overflow")
+	errDivisionByZero = errors.New("division by zero")
+)
+
+// ErrorKind - категория ошибки для машиночитаемого вывода. Значения стабильны.
+type ErrorKind string
+
+const (
+	// KindSyntax - ошибка разбора выражения
+	KindSyntax ErrorKind = "syntax"
+	// KindUndefined - неизвестная переменная, функция или оператор
+	KindUndefined      ErrorKind = "undefined"
+	KindDivisionByZero ErrorKind = "division_by_zero"
+	KindOverflow       ErrorKind = "overflow"
+	// KindUnsupported - операция не определена в текущем режиме
+	KindUnsupported ErrorKind = "unsupported"
+	// KindMath - остальные ошибки вычисления: корень из отрицательного числа и т.п.
+	KindMath ErrorKind = "math"
+)
+
+// KindOf возвращает категорию ошибки, которую вернул калькулятор
+func KindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) && e.Kind != "" {
+		return e.Kind
+	}
+	switch {
+	case errors.Is(err, errDivisionByZero):
+		return KindDivisionByZero
+	case errors.Is(err, errOverflow), errors.Is(err, errIntegerOverflow):
+		return KindOverflow
+	case errors.Is(err, errInexact), errors.Is(err, errTolerance), errors.Is(err, errOperationUnsupported):
+		return KindUnsupported
+	case errors.Is(err, errParsing):
+		return KindSyntax
+	}
+	return KindMath
+}
+
+// Span - фрагмент исходного выражения, смещения в рунах, End не включается
+type Span struct {
+	Start int
+	End   int
+}
+
+// Position возвращает сам фрагмент. Узлы дерева встраивают Span,
+// и через этот метод реализуют Node.Position.
+func (s Span) Position() Span {
+	return s
+}
+
+// message - шаблон текста ошибки и его аргументы. Шаблон служит ключом
+// каталога переводов, поэтому Localize переводит ошибку по нему, а не
+// разбирает готовый английский текст.
+type message struct {
+	format string
+	args   []any
+}
+
+func (m message) String() string {
+	return fmt.Sprintf(m.format, m.args...)
+}
+
+// messageError - ошибка с текстом из message. Заменяет fmt.Errorf:
+// текст и обёрнутые через %w ошибки те же, но шаблон сохраняется.
+type messageError struct {
+	msg message
+	err error
+}
+
+func newError(format string, args ...any) error {
+	return &messageError{msg: message{format, args}, err: fmt.Errorf(format, args...)}
+}
+
+// Errorf создаёт ошибку так же, как fmt.Errorf, но с переводом по каталогу.
+// Нужна другим пакетам, чьи ошибки печатаются через Localize.
+func Errorf(format string, args ...any) error {
+	return newError(format, args...)
+}
+
+func (e *messageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *messageError) Unwrap() []error {
+	switch err := e.err.(type) {
+	case interface{ Unwrap() []error }:
+		return err.Unwrap()
+	case interface{ Unwrap() error }:
+		return []error{err.Unwrap()}
+	}
+	return nil
+}
+
+// Error - ошибка разбора или вычисления с привязкой к месту в выражении.
+// Line и Column считаются с 1, Column - в рунах.
+type Error struct {
+	Line   int
+	Column int
+	Span   Span
+	Msg    string
+	Err    error
+	// категория ошибки, если она не следует из Err
+	Kind ErrorKind
+	// Msg без Err, ключ для перевода
+	msg message
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func errorAt(span Span, format string, args ...any) *Error {
+	msg := message{format, args}
+	return &Error{Span: span, Msg: msg.String(), msg: msg}
+}
+
+// kindErrorAt - errorAt с известной категорией ошибки
+func kindErrorAt(kind ErrorKind, span Span, format string, args ...any) *Error {
+	e := errorAt(span, format, args...)
+	e.Kind = kind
+	return e
+}
+
+// wrapErrorAt оборачивает ошибку из operations.go, сохраняя её для errors.Is
+func wrapErrorAt(span Span, err error, format string, args ...any) *Error {
+	e := errorAt(span, format, args...)
+	e.Msg += ": " + err.Error()
+	e.Err = err
+	return e
+}
+
+func locateError(err error, src string) error {
+	if e, ok := err.(*Error); ok {
+		return e.locate(src)
+	}
+	return err
+}
+
+// locate вычисляет строку и колонку ошибки по исходному выражению
+func (e *Error) locate(src string) *Error {
+	runes := []rune(src)
+	e.Line, e.Column = 1, 1
+	for i := 0; i < e.Span.Start && i < len(runes); i++ {
+		if runes[i] == '\n' {
+			e.Line++
+			e.Column = 1
+		} else {
+			e.Column++
+		}
+	}
+	return e
+}
+
+// Caret возвращает строку выражения, в которой произошла ошибка,
+// и подчёркивание вида ^~~~ под ошибочным фрагментом
+func (e *Error) Caret(src string) string {
+	lines := strings.Split(src, "\n")
+	if e.Line < 1 || e.Line > len(lines) {
+		return ""
+	}
+	line := []rune(lines[e.Line-1])
+
+	var marker strings.Builder
+	for i := 0; i < e.Column-1 && i < len(line); i++ {
+		// табуляции сохраняются, чтобы каретка не съезжала
+		if line[i] == '\t' {
+			marker.WriteRune('\t')
+		} else {
+			marker.WriteRune(' ')
+		}
+	}
+	marker.WriteRune('^')
+
+	length := e.Span.End - e.Span.Start
+	if rest := len(line) - (e.Column - 1); length > rest {
+		length = rest
+	}
+	for i := 1; i < length; i++ {
+		marker.WriteRune('~')
+	}
+
+	return string(line) + "\n" + marker.String()
+}
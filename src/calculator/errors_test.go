@@ -0,0 +1,44 @@
+package calculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKindOf(t *testing.T) {
+	type CaseKindOf struct {
+		expression string
+		mode       Mode
+		precision  uint
+		kind       ErrorKind
+	}
+	cases := []CaseKindOf{
+		{expression: "1 +", kind: KindSyntax},
+		{expression: "(1 + 2", kind: KindSyntax},
+		{expression: "1 $ 2", kind: KindSyntax},
+		{expression: "x + 1", kind: KindUndefined},
+		{expression: "x + 1", mode: Rational, kind: KindUndefined},
+		{expression: "foo(1)", kind: KindUndefined},
+		{expression: "foo(1)", mode: Complex, kind: KindUndefined},
+		{expression: "1 / 0", kind: KindDivisionByZero},
+		{expression: "1 / 0", mode: Rational, kind: KindDivisionByZero},
+		{expression: "1 / 0", mode: Integer, kind: KindDivisionByZero},
+		{expression: "1 / 0", precision: 100, kind: KindDivisionByZero},
+		{expression: "exp(1000)", kind: KindOverflow},
+		{expression: "10^400", kind: KindOverflow},
+		{expression: "1e400", kind: KindOverflow},
+		{expression: "1e400", mode: Complex, kind: KindOverflow},
+		{expression: "sqrt(-1)", kind: KindMath},
+		{expression: "log(0)", kind: KindMath},
+		{expression: "sin(1)", mode: Rational, kind: KindUnsupported},
+		{expression: "[1, 2]", kind: KindUnsupported},
+		{expression: "2 ± 1", kind: KindUnsupported},
+	}
+
+	for _, c := range cases {
+		_, err := Evaluate(c.expression, CalculatorConfig{Mode: c.mode, Precision: c.precision})
+		require.Error(t, err, c.expression)
+		require.Equal(t, c.kind, KindOf(err), "%s: %v", c.expression, err)
+	}
+}
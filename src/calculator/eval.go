@@ -0,0 +1,419 @@
+package calculator
+
+import (
+	"errors"
+	"maps"
+	"slices"
+)
+
+// errUnsupported возвращается доменом, если операция в нём не определена;
+// тогда вычисление идёт через float64-реализацию из реестра
+var errUnsupported = errors.New("unsupported operation")
+
+var errTolerance = errors.New("values with tolerance need interval or uncertainty mode")
+
+// errNegativeTolerance - отрицательный допуск у ± в режимах Interval и Uncertainty
+var errNegativeTolerance = errors.New("tolerance must be non-negative")
+
+// errOperationUnsupported - операции нет ни в домене, ни для значения в float64
+var errOperationUnsupported = errors.New("operation is not supported")
+
+// domain - арифметика одного из режимов вычисления над значениями типа T
+type domain[T any] interface {
+	number(n *NumberLit) (T, error)
+	constant(name string) (T, error)
+	fromFloat(x float64) (T, bool)
+	// approx переводит в домен результат операции, вычисленной через float64
+	approx(x float64) (T, error)
+	toFloat(x T) (float64, bool)
+	neg(x T) (T, error)
+	binary(op string, x, y T) (T, error)
+	call(name string, args []T) (T, error)
+	list(elems []T) (T, error)
+	value(x T) Value
+}
+
+// evaluator обходит дерево и вычисляет его в домене dom. В режиме float64
+// используется Program, evaluator нужен для остальных режимов.
+type evaluator[T any] struct {
+	calc  *Calculator
+	dom   domain[T]
+	vars  map[string]T
+	funcs map[string]*FuncDef
+	depth int
+}
+
+// evaluateTree проверяет дерево компилятором, чтобы ошибки разбора были
+// такими же, как в режиме float64, и вычисляет его в домене dom с
+// переменными и функциями сессии. При persist присвоенные переменные,
+// определённые функции и результат (в ans и _) сохраняются в сессии, а
+// непредставимые в домене переменные пропускаются; иначе это ошибка.
+func evaluateTree[T any](s *Session, dom domain[T], tree Node, source string, persist bool) (Value, bool, error) {
+	if _, err := s.calc.compileTree(tree, source, s.funcs); err != nil {
+		return nil, false, err
+	}
+
+	e := &evaluator[T]{calc: s.calc, dom: dom, vars: map[string]T{}, funcs: maps.Clone(s.funcs)}
+	for name, x := range s.vars {
+		value, ok := dom.fromFloat(x)
+		if !ok && !persist {
+			return nil, false, newError("%w: variable %s: %v is not representable", errCalculating, name, x)
+		}
+		if ok {
+			e.vars[name] = value
+		}
+	}
+	for name, v := range s.values {
+		if value, ok := v.raw.(T); ok {
+			e.vars[name] = value
+		}
+	}
+
+	result, hasValue, err := e.statement(tree)
+	if err != nil {
+		return nil, false, newError("%w: %w", errCalculating, locateError(err, source))
+	}
+	if persist {
+		if hasValue {
+			e.vars["ans"], e.vars["_"] = result, result
+		}
+		for name, value := range e.vars {
+			s.setValue(name, value, dom.value(value))
+		}
+		s.funcs = e.funcs
+	}
+	if !hasValue {
+		return nil, false, nil
+	}
+	return dom.value(result), true, nil
+}
+
+// statement вычисляет оператор и сообщает, есть ли у него значение
+func (e *evaluator[T]) statement(node Node) (T, bool, error) {
+	var zero T
+	switch n := node.(type) {
+	case *Block:
+		var result T
+		hasValue := false
+		for _, stmt := range n.Stmts {
+			var err error
+			result, hasValue, err = e.statement(stmt)
+			if err != nil {
+				return zero, false, err
+			}
+		}
+		return result, hasValue, nil
+	case *Assign:
+		value, err := e.eval(n.Value, nil)
+		if err != nil {
+			return zero, false, err
+		}
+		e.vars[n.Name] = value
+		return value, true, nil
+	case *FuncDef:
+		e.funcs[n.Name] = n
+		return zero, false, nil
+	}
+
+	result, err := e.eval(node, nil)
+	return result, err == nil, err
+}
+
+func (e *evaluator[T]) eval(node Node, params map[string]T) (T, error) {
+	var zero T
+	switch n := node.(type) {
+	case *NumberLit:
+		return e.dom.number(n)
+	case *AngleLit:
+		x, err := e.dom.number(n.Value)
+		if err != nil {
+			return zero, err
+		}
+		result, err := e.convertAngle(x, n.Unit, e.calc.config.AngleUnits)
+		if err != nil {
+			return zero, wrapErrorAt(n.Span, err, "angle %s", n)
+		}
+		return result, nil
+	case *Constant:
+		value, err := e.dom.constant(n.Name)
+		if err != nil {
+			return zero, wrapErrorAt(n.Span, err, "constant %s", n.Name)
+		}
+		return value, nil
+	case *Variable:
+		if value, ok := params[n.Name]; ok {
+			return value, nil
+		}
+		if value, ok := e.vars[n.Name]; ok {
+			return value, nil
+		}
+		return zero, kindErrorAt(KindUndefined, n.Span, "undefined variable %s", n.Name)
+	case *UnaryOp:
+		x, err := e.eval(n.X, params)
+		if err != nil {
+			return zero, err
+		}
+		if n.Op == "~" {
+			return e.complement(n, x)
+		}
+		result, err := e.dom.neg(x)
+		if err != nil {
+			return zero, wrapErrorAt(n.Span, err, "calculating negation")
+		}
+		return result, nil
+	case *BinaryOp:
+		return e.binary(n, params)
+	case *FuncCall:
+		return e.call(n, params)
+	case *List:
+		elems := make([]T, 0, len(n.Elems))
+		for _, elem := range n.Elems {
+			value, err := e.eval(elem, params)
+			if err != nil {
+				return zero, err
+			}
+			elems = append(elems, value)
+		}
+		result, err := e.dom.list(elems)
+		if errors.Is(err, errUnsupported) {
+			return zero, kindErrorAt(KindUnsupported, n.Span, "list literals are not supported in %s mode", e.calc.config.Mode)
+		}
+		if err != nil {
+			return zero, wrapErrorAt(n.Span, err, "list")
+		}
+		return result, nil
+	case *Unit, *QuantityLit, *Convert:
+		return e.units(node, params)
+	}
+	panic("unknown node")
+}
+
+// units вычисляет единицы измерения и перевод между ними (режим Units)
+func (e *evaluator[T]) units(node Node, params map[string]T) (T, error) {
+	var zero T
+	dom, ok := e.dom.(unitDomain[T])
+	if !ok {
+		return zero, kindErrorAt(KindUnsupported, node.Position(), "units are not supported in %s mode", e.calc.config.Mode)
+	}
+
+	switch n := node.(type) {
+	case *Unit:
+		result, err := dom.unit(n)
+		if err != nil {
+			return zero, wrapErrorAt(n.Span, err, "unit %s", n.Name)
+		}
+		return result, nil
+	case *QuantityLit:
+		result, err := e.dom.number(n.Value)
+		if err != nil {
+			return zero, err
+		}
+		for _, unit := range n.Units {
+			y, err := e.units(unit, params)
+			if err != nil {
+				return zero, err
+			}
+			if result, err = e.dom.binary("*", result, y); err != nil {
+				return zero, wrapErrorAt(n.Span, err, "calculating %s", n)
+			}
+		}
+		return result, nil
+	}
+
+	n := node.(*Convert)
+	x, err := e.eval(n.X, params)
+	if err != nil {
+		return zero, err
+	}
+	target, err := e.eval(n.Target, params)
+	if err != nil {
+		return zero, err
+	}
+	result, err := dom.convert(x, target, unitText(n.Target))
+	if errors.Is(err, errNoUnit) {
+		return zero, errorAt(n.X.Position(), "%s has no unit to convert to %s", n.X, unitText(n.Target))
+	}
+	if err != nil {
+		return zero, wrapErrorAt(n.Span, err, "conversion")
+	}
+	return result, nil
+}
+
+// complementDomain - домен со своим побитовым отрицанием (режим Integer)
+type complementDomain[T any] interface {
+	complement(x T) (T, error)
+}
+
+// complement вычисляет ~x; в доменах без побитовых операций - через float64
+func (e *evaluator[T]) complement(n *UnaryOp, x T) (T, error) {
+	var zero T
+	result, err := zero, errUnsupported
+	if dom, ok := e.dom.(complementDomain[T]); ok {
+		result, err = dom.complement(x)
+	}
+	if errors.Is(err, errUnsupported) {
+		result, err = e.viaFloat(func(args []float64) (float64, error) {
+			return BitNot(args[0])
+		}, []T{x})
+	}
+	if err != nil {
+		return zero, wrapErrorAt(n.Span, err, "calculating bitwise not")
+	}
+	return result, nil
+}
+
+func (e *evaluator[T]) binary(n *BinaryOp, params map[string]T) (T, error) {
+	var zero T
+	x, err := e.eval(n.X, params)
+	if err != nil {
+		return zero, err
+	}
+	y, err := e.eval(n.Y, params)
+	if err != nil {
+		return zero, err
+	}
+
+	// заменённый через RegisterOperator встроенный оператор домен не вычисляет
+	op, _ := e.calc.operator(n.Op)
+	result, err := zero, errUnsupported
+	if op.isBuiltin() {
+		result, err = e.dom.binary(n.Op, x, y)
+	}
+	if errors.Is(err, errUnsupported) {
+		result, err = e.viaFloat(func(args []float64) (float64, error) {
+			return op.Fn(args[0], args[1])
+		}, []T{x, y})
+	}
+	if err != nil {
+		return zero, wrapErrorAt(n.Span, err, "calculating %s", op.name)
+	}
+	return result, nil
+}
+
+func (e *evaluator[T]) call(n *FuncCall, params map[string]T) (T, error) {
+	var zero T
+	nodes := n.Args
+	f, isBuiltin := e.calc.functions[n.Name]
+	if isBuiltin {
+		nodes = e.calc.callArgs(f, nodes)
+	}
+	args := make([]T, 0, len(nodes))
+	for _, arg := range nodes {
+		value, err := e.eval(arg, params)
+		if err != nil {
+			return zero, err
+		}
+		args = append(args, value)
+	}
+
+	if isBuiltin {
+		result, err := zero, errUnsupported
+		switch {
+		case f != builtinFunctions[n.Name]:
+		case f.Name == "deg":
+			result, err = e.convertAngle(args[0], Radian, Degree)
+		case f.Name == "rad":
+			result, err = e.convertAngle(args[0], Degree, Radian)
+		default:
+			result, err = e.dom.call(f.Name, args)
+		}
+		if errors.Is(err, errUnsupported) {
+			result, err = e.viaFloat(func(args []float64) (float64, error) {
+				return e.calc.callFloat(f, args)
+			}, args)
+		}
+		if err != nil {
+			return zero, wrapErrorAt(n.Span, err, "calculating %s", n.Name)
+		}
+		return result, nil
+	}
+
+	def := e.funcs[n.Name]
+	if e.depth >= maxCallDepth {
+		return zero, errorAt(n.Span, "call depth limit exceeded in %s", n.Name)
+	}
+	frame := make(map[string]T, len(def.Params))
+	for i, param := range def.Params {
+		frame[param] = args[i]
+	}
+
+	e.depth++
+	defer func() { e.depth-- }()
+	return e.eval(def.Body, frame)
+}
+
+// convertAngle переводит угол из единиц from в единицы to арифметикой
+// домена: x * to.halfTurn() / from.halfTurn(), у радиан вместо halfTurn - pi
+func (e *evaluator[T]) convertAngle(x T, from, to AngleUnit) (T, error) {
+	if from.halfTurn() == to.halfTurn() {
+		return x, nil
+	}
+	halfTurn := func(unit AngleUnit) (T, error) {
+		if unit.isRadian() {
+			return e.dom.constant("pi")
+		}
+		h := unit.halfTurn()
+		return e.dom.number(&NumberLit{Value: h, Text: formatFloat(h)})
+	}
+
+	var zero T
+	num, err := halfTurn(to)
+	if err != nil {
+		return zero, err
+	}
+	den, err := halfTurn(from)
+	if err != nil {
+		return zero, err
+	}
+	if x, err = e.dom.binary("*", x, num); err != nil {
+		return zero, err
+	}
+	return e.dom.binary("/", x, den)
+}
+
+// logArgs возвращает основание и аргумент логарифма: log(x) и log10(x) -
+// по основанию 10, log2(x) - по основанию 2, log(base, x) - по base
+func logArgs[T any](name string, args []T, number func(x float64) T) (T, T) {
+	switch {
+	case name == "log2":
+		return number(2), args[0]
+	case len(args) == 1:
+		return number(10), args[0]
+	}
+	return args[0], args[1]
+}
+
+// viaFloat вычисляет операцию, которой нет в домене, через float64
+func (e *evaluator[T]) viaFloat(fn func(args []float64) (float64, error), args []T) (T, error) {
+	var zero T
+	floats := make([]float64, 0, len(args))
+	for _, arg := range args {
+		x, ok := e.dom.toFloat(arg)
+		if !ok {
+			return zero, newError("%w for %s", errOperationUnsupported, e.dom.value(arg))
+		}
+		floats = append(floats, x)
+	}
+
+	result, err := fn(floats)
+	if err != nil {
+		return zero, err
+	}
+	return e.dom.approx(result)
+}
+
+// callFloat вызывает функцию из реестра с переводом углов, как это делает Program
+func (c *Calculator) callFloat(f *Function, args []float64) (float64, error) {
+	unit := c.config.AngleUnits
+	if f.isTrig && !unit.isRadian() {
+		args = slices.Clone(args)
+		for i := range args {
+			args[i] = unit.toRadians(args[i])
+		}
+	}
+	result, err := f.Fn(args...)
+	if err == nil && f.returnsAngle {
+		result = unit.fromRadians(result)
+	}
+	return result, err
+}
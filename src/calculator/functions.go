@@ -1,337 +1,181 @@
 package calculator
 
 import (
-	structs "calcWithTests/src/commonStructs"
 	"fmt"
 	"math"
-	"reflect"
-	"strconv"
-	"strings"
-	"unicode"
 )
 
-type CalculatorConfig struct {
-	AngleUnits string
-}
-
-// Приоритет операторов
-var precedence = map[string]int{
-	"(": 0,
-	"+": 1,
-	"-": 1,
-	"*": 2,
-	"/": 2,
-	"~": 3,
-	"^": 4,
-	"m": 4,
-	"d": 4,
-	"q": 4, // sqrt
-	"l": 4, // ln
-	"x": 4, // exp
-	"s": 4, // sin
-	"c": 4, // cos
-	"t": 4, // tg
-	"g": 4, // ctg
-}
+// Mode - в каких числах вычисляются выражения
+type Mode int
+
+const (
+	// Float64 - обычные вычисления в float64 или в big.Float, если задана Precision
+	Float64 Mode = iota
+	// Rational - точные вычисления в рациональных дробях big.Rat
+	Rational
+	// Complex - комплексные числа complex128, мнимая единица - i
+	Complex
+	// Interval - интервальная арифметика с гарантированными границами
+	Interval
+	// Uncertainty - значения со стандартной неопределённостью, которая
+	// распространяется линейно (по формуле Гаусса)
+	Uncertainty
+	// Units - числа с единицами измерения: 5 km / 2 h, 60 mph to m/s
+	Units
+	// Integer - целые числа разрядности IntWidth с побитовыми операциями
+	Integer
+)
 
-func degreesToRadians(degrees float64) float64 {
-	return degrees * math.Pi / 180
+var modeNames = map[Mode]string{
+	Float64:     "float",
+	Rational:    "rational",
+	Complex:     "complex",
+	Interval:    "interval",
+	Uncertainty: "uncertainty",
+	Units:       "units",
+	Integer:     "integer",
 }
 
-func Calculate(expression string, config CalculatorConfig) (float64, error) {
-	tokens := tokenize(expression)
-	postfix, err := infixToPostfix(tokens)
-	if err != nil {
-		return 0, fmt.Errorf("error while parsing: %v", err)
-	}
-
-	result, err := evaluatePostfix(postfix, config.AngleUnits)
-	if err != nil {
-		return 0, fmt.Errorf("error while calculating: %v", err)
+func (m Mode) String() string {
+	if name, ok := modeNames[m]; ok {
+		return name
 	}
-
-	return result, nil
+	return fmt.Sprintf("Mode(%d)", int(m))
 }
 
-func infixToPostfix(tokens []string) ([]string, error) {
-	var output []string
-	stack := make(structs.Stack, 0, len(tokens))
-
-	for _, token := range tokens {
-		if _, err := strconv.ParseFloat(token, 64); err == nil || token == "e" || token == "pi" {
-			output = append(output, token)
-		} else if token == "(" {
-			stack.Push(token)
-		} else if token == ")" {
-			for len(stack) != 0 {
-				stackTop, _ := stack.Pop()
-				if stackTop == "(" {
-					break
-				}
-				output = append(output, stackTop)
-			}
-		} else if tokenPrec, ok := precedence[token]; ok {
-			stackTop, _ := stack.Peek()
-			for len(stack) != 0 && precedence[stackTop] >= tokenPrec {
-				output = append(output, stackTop)
-				stack.Pop()
-				stackTop, _ = stack.Peek()
-			}
-			stack.Push(token)
-		} else {
-			return nil, fmt.Errorf("invalid operation or number: %s", token)
+// ParseMode возвращает режим по названию, которое печатает Mode.String
+func ParseMode(name string) (Mode, error) {
+	for mode, modeName := range modeNames {
+		if modeName == name {
+			return mode, nil
 		}
 	}
-
-	for len(stack) != 0 {
-		stackTop, _ := stack.Pop()
-		output = append(output, stackTop)
-	}
-
-	return output, nil
+	return 0, newError("unknown mode %q", name)
 }
 
-func evaluatePostfix(tokens []string, angleUnits string) (float64, error) {
-	numsStack := make(structs.FloatStack, 0, len(tokens))
+// AngleUnit - единица измерения углов для тригонометрических функций
+type AngleUnit string
 
-	for _, token := range tokens {
-		if num, err := strconv.ParseFloat(token, 64); err == nil {
-			numsStack.Push(num)
-			continue
-		}
-
-		if token == "e" {
-			numsStack.Push(math.E)
-			continue
-		}
-		if token == "pi" {
-			numsStack.Push(math.Pi)
-			continue
-		}
-
-		var result float64
-		var err error
-
-		switch token {
-		case "+", "-", "*", "/", "^", "m", "d":
-			if len(numsStack) < 2 {
-				return 0, fmt.Errorf("not enough operands for operation %s", token)
-			}
-			operand2, _ := numsStack.Pop()
-			operand1, _ := numsStack.Pop()
-
-			switch token {
-			case "+":
-				result, err = Add(operand1, operand2)
-				if err != nil {
-					return 0, fmt.Errorf("calculating addition: %v", err)
-				}
-			case "-":
-				result, err = Sub(operand1, operand2)
-				if err != nil {
-					return 0, fmt.Errorf("calculating substraction: %v", err)
-				}
-			case "*", "m":
-				result, err = Mul(operand1, operand2)
-				if err != nil {
-					return 0, fmt.Errorf("calculating multiplication: %v", err)
-				}
-			case "/", "d":
-				result, err = Div(operand1, operand2)
-				if err != nil {
-					return 0, fmt.Errorf("calculating division: %v", err)
-				}
-			case "^":
-				result, err = Pow(operand1, operand2)
-				if err != nil {
-					return 0, fmt.Errorf("calculating power: %v", err)
-				}
-			}
-		case "q", "l", "x", "s", "c", "t", "g", "~":
-			if len(numsStack) < 1 {
-				return 0, fmt.Errorf("not enough operands for operation %s", token)
-			}
-			operand, _ := numsStack.Pop()
-
-			switch token {
-			case "~":
-				result = -operand
-			case "q":
-				result, err = Sqrt(operand)
-				if err != nil {
-					return 0, fmt.Errorf("calculating sqrt: %v", err)
-				}
-			case "l":
-				result, err = Ln(operand)
-				if err != nil {
-					return 0, fmt.Errorf("calculating ln: %v", err)
-				}
-			case "x":
-				result, err = Exp(operand)
-				if err != nil {
-					return 0, fmt.Errorf("calculating exp: %v", err)
-				}
-			case "s", "c", "t", "g":
-				if angleUnits == "degree" {
-					operand = degreesToRadians(operand)
-				}
-				switch token {
-				case "s":
-					result = Sin(operand)
-				case "c":
-					result = Cos(operand)
-				case "t":
-					result, err = Tg(operand)
-					if err != nil {
-						return 0, fmt.Errorf("calculating tg: %v", err)
-					}
-				case "g":
-					result, err = Cot(operand)
-					if err != nil {
-						return 0, fmt.Errorf("calculating ctg: %v", err)
-					}
-				}
-			}
-		default:
-			panic("unknown operator")
-		}
+const (
+	Radian AngleUnit = "radian"
+	Degree AngleUnit = "degree"
+	// Gradian - град, 1/400 оборота
+	Gradian AngleUnit = "gradian"
+	Turn    AngleUnit = "turn"
+)
 
-		numsStack.Push(result)
+// ParseAngleUnit проверяет название единицы углов
+func ParseAngleUnit(name string) (AngleUnit, error) {
+	switch unit := AngleUnit(name); unit {
+	case Radian, Degree, Gradian, Turn:
+		return unit, nil
 	}
+	return "", newError("unknown angle unit %q, expected radian, degree, gradian or turn", name)
+}
 
-	if len(numsStack) != 1 {
-		return 0, fmt.Errorf("invalid expression")
+// halfTurn - сколько единиц в пол-оборота, то есть в pi радиан; 0 у радиан.
+// Пустая и неизвестная единица считаются радианами.
+func (u AngleUnit) halfTurn() float64 {
+	switch u {
+	case Degree:
+		return 180
+	case Gradian:
+		return 200
+	case Turn:
+		return 0.5
 	}
-
-	res, _ := numsStack.Pop()
-	return res, nil
+	return 0
 }
 
-func tokenize(input string) []string {
-	tokens := make([]string, 0, len(input))
-	var currNumToken strings.Builder
-	var currLetToken strings.Builder
-
-	runes := []rune(input)
-
-	for i := 0; i < len(input); i++ {
-		if unicode.IsDigit(runes[i]) || runes[i] == '.' {
-			if currLetToken.Len() > 0 {
-				tokens = append(tokens, getLetToken(&currLetToken))
-				currLetToken.Reset()
-			}
-
-			currNumToken.WriteRune(runes[i])
-		} else if strings.Contains("(+-*/^)", string(runes[i])) {
-
-			if currNumToken.Len() > 0 {
-				tokens = append(tokens, currNumToken.String())
-				currNumToken.Reset()
-			}
-
-			if currLetToken.Len() > 0 {
-				tokens = append(tokens, getLetToken(&currLetToken))
-				currLetToken.Reset()
-			}
-
-			if runes[i] == '-' {
-				if len(tokens) == 0 {
-					tokens = append(tokens, string('~'))
-					continue
-				}
-
-				if lastToken := []rune(tokens[len(tokens)-1]); (lastToken[len(lastToken)-1] != '.' && !unicode.IsDigit(lastToken[len(lastToken)-1])) && !reflect.DeepEqual(lastToken, []rune("e")) {
-					tokens = append(tokens, string('~'))
-					continue
-				}
-
-				tokens = append(tokens, string('-'))
-				continue
-			}
-
-			tokens = append(tokens, string(runes[i]))
-		} else {
-
-			if currNumToken.Len() > 0 {
-				tokens = append(tokens, currNumToken.String())
-				currNumToken.Reset()
-			}
-
-			if i-1 >= 0 && unicode.IsDigit(runes[i-1]) && runes[i] == 'e' &&
-				i+1 < len(input) && (runes[i+1] == '+' || runes[i+1] == '-') {
-				eInd := i
-				eTokens := make([]string, 0, 2)
-				i++
-
-				switch runes[i] {
-				case '+':
-					eTokens = append(eTokens, string('m'))
-				case '-':
-					eTokens = append(eTokens, string('d'))
-				}
-				i++
-
-				for i < len(input) {
-					if unicode.IsDigit(runes[i]) || runes[i] == '.' {
-						currNumToken.WriteRune(runes[i])
-					} else {
-						break
-					}
-					i++
-				}
-				i--
-
-				if currNumToken.Len() > 0 {
-					powStr := currNumToken.String()
-					currNumToken.Reset()
-					pow, _ := strconv.ParseFloat(powStr, 64)
-					num := math.Pow(10, pow)
-					eTokens = append(eTokens, strconv.FormatFloat(num, 'f', -1, 64))
-					tokens = append(tokens, eTokens...)
-				} else {
-					tokens = append(tokens, "e")
-					i = eInd
-				}
-				continue
-			}
+func (u AngleUnit) isRadian() bool {
+	return u.halfTurn() == 0
+}
 
-			if !unicode.IsSpace(runes[i]) {
-				currLetToken.WriteRune(runes[i])
-			}
-		}
+// toRadians переводит угол x из единиц u в радианы
+func (u AngleUnit) toRadians(x float64) float64 {
+	if u.isRadian() {
+		return x
 	}
+	return x * math.Pi / u.halfTurn()
+}
 
-	if currNumToken.Len() > 0 {
-		tokens = append(tokens, currNumToken.String())
+// fromRadians переводит угол x из радиан в единицы u
+func (u AngleUnit) fromRadians(x float64) float64 {
+	if u.isRadian() {
+		return x
 	}
+	return x * u.halfTurn() / math.Pi
+}
 
-	if currLetToken.Len() > 0 {
-		tokens = append(tokens, currLetToken.String())
+// convertAngle переводит угол x из единиц from в единицы to. Между
+// градусами, градами и оборотами перевод идёт без pi: 90° = 100 grad точно.
+func convertAngle(x float64, from, to AngleUnit) float64 {
+	switch {
+	case from.halfTurn() == to.halfTurn():
+		return x
+	case from.isRadian():
+		return to.fromRadians(x)
+	case to.isRadian():
+		return from.toRadians(x)
 	}
+	return x * to.halfTurn() / from.halfTurn()
+}
 
-	return tokens
+type CalculatorConfig struct {
+	// AngleUnits - единица углов тригонометрических функций, по умолчанию радианы
+	AngleUnits AngleUnit
+	Mode       Mode
+	// Precision - точность мантиссы в битах для вычислений через math/big;
+	// 0 - обычный режим float64
+	Precision uint
+
+	// MixedFractions печатает рациональные результаты смешанными дробями: 3 1/2 вместо 7/2
+	MixedFractions bool
+	// AllowInexact разрешает в режиме Rational функции с иррациональным
+	// результатом: они считаются в float64, результат помечается как неточный
+	AllowInexact bool
+	// PolarOutput печатает комплексные результаты в полярной форме r∠θ,
+	// угол - в AngleUnits
+	PolarOutput bool
+	// UnitDatabase - единицы измерения режима Units; nil - встроенная база
+	UnitDatabase *UnitDatabase
+
+	// IntWidth - разрядность режима Integer: 8, 16, 32 или 64 (0 - 64)
+	IntWidth uint
+	// Unsigned - беззнаковые целые в режиме Integer
+	Unsigned bool
+	// OverflowError делает переполнение в режиме Integer ошибкой,
+	// иначе результат переносится по модулю 2^IntWidth
+	OverflowError bool
+	// Base - основание, в котором печатаются результаты режима Integer:
+	// 2, 8, 10 или 16 (0 - 10)
+	Base int
+
+	// Locale - десятичный разделитель и разделители групп во входных выражениях
+	Locale Locale
 }
 
-func getLetToken(token *strings.Builder) string {
-	var res string
-	switch token.String() {
-	case "sqrt":
-		res = "q"
-	case "ln":
-		res = "l"
-	case "exp":
-		res = "x"
-	case "sin":
-		res = "s"
-	case "cos":
-		res = "c"
-	case "tg":
-		res = "t"
-	case "ctg":
-		res = "g"
-	default:
-		res = token.String()
-	}
+func Calculate(expression string, config CalculatorConfig) (float64, error) {
+	return defaultCalculator(config).Calculate(expression)
+}
+
+// Evaluate вычисляет выражение в режиме, заданном config,
+// и возвращает результат без перевода в float64
+func Evaluate(expression string, config CalculatorConfig) (Value, error) {
+	return defaultCalculator(config).Evaluate(expression)
+}
+
+// CalculateWithVars вычисляет выражение, в котором можно ссылаться на
+// заранее заданные переменные vars. Сама vars не изменяется.
+func CalculateWithVars(expression string, config CalculatorConfig, vars map[string]float64) (float64, error) {
+	return defaultCalculator(config).CalculateWithVars(expression, vars)
+}
+
+// Compile разбирает выражение один раз и готовит его к многократному вычислению
+func Compile(expression string, config CalculatorConfig) (*Program, error) {
+	return defaultCalculator(config).Compile(expression)
+}
 
-	return res
+func NewSession(config CalculatorConfig) *Session {
+	return defaultCalculator(config).NewSession()
 }
@@ -0,0 +1,369 @@
+package calculator
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	errIntegerOverflow = errors.New("integer overflow")
+	errNotInteger      = errors.New("result is not an integer")
+	errBitwiseOperand  = errors.New("bitwise operations need integer operands")
+)
+
+// Word - результат вычисления в режиме Integer: машинное слово
+// разрядности Width в дополнительном коде
+type Word struct {
+	// биты значения, старшие биты за Width - нули
+	Bits   uint64
+	Width  uint
+	Signed bool
+	// основание, в котором печатается значение: 2, 8, 10 или 16
+	Base int
+}
+
+// Int возвращает значение слова с учётом знака
+func (w Word) Int() *big.Int {
+	x := new(big.Int).SetUint64(w.Bits)
+	if w.Signed && w.Bits>>(w.Width-1)&1 == 1 {
+		x.Sub(x, new(big.Int).Lsh(big.NewInt(1), w.Width))
+	}
+	return x
+}
+
+// String печатает десятичные значения со знаком, а двоичные, восьмеричные
+// и шестнадцатеричные - как набор битов: -1 в int8 это 0xff
+func (w Word) String() string {
+	switch w.Base {
+	case 2:
+		return "0b" + strconv.FormatUint(w.Bits, 2)
+	case 8:
+		return "0o" + strconv.FormatUint(w.Bits, 8)
+	case 16:
+		return "0x" + strconv.FormatUint(w.Bits, 16)
+	}
+	return w.Int().String()
+}
+
+func (w Word) Float64() (float64, bool) {
+	f, _ := new(big.Float).SetInt(w.Int()).Float64()
+	return f, true
+}
+
+// ParseBase возвращает основание по названию: hex, bin, oct или dec
+func ParseBase(name string) (int, error) {
+	switch name {
+	case "bin":
+		return 2, nil
+	case "oct":
+		return 8, nil
+	case "dec":
+		return 10, nil
+	case "hex":
+		return 16, nil
+	}
+	return 0, newError("unknown base %q", name)
+}
+
+// integerDomain - целые числа фиксированной разрядности. Значения хранятся
+// битами, результат операции считается точно в big.Int и затем либо
+// переносится по модулю 2^width, либо даёт ошибку переполнения.
+type integerDomain struct {
+	width    uint
+	signed   bool
+	overflow bool
+	base     int
+	mask     uint64
+	// допустимый диапазон значений
+	min, max *big.Int
+}
+
+func newIntegerDomain(config CalculatorConfig) (*integerDomain, error) {
+	width := config.IntWidth
+	if width == 0 {
+		width = 64
+	}
+	if width != 8 && width != 16 && width != 32 && width != 64 {
+		return nil, newError("unsupported integer width %d, expected 8, 16, 32 or 64", width)
+	}
+	base := config.Base
+	if base == 0 {
+		base = 10
+	}
+	if base != 2 && base != 8 && base != 10 && base != 16 {
+		return nil, newError("unsupported base %d, expected 2, 8, 10 or 16", base)
+	}
+
+	d := &integerDomain{
+		width:    width,
+		signed:   !config.Unsigned,
+		overflow: config.OverflowError,
+		base:     base,
+		mask:     math.MaxUint64 >> (64 - width),
+	}
+	if d.signed {
+		d.min = new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), width-1))
+		d.max = new(big.Int).Lsh(big.NewInt(1), width-1)
+	} else {
+		d.min = new(big.Int)
+		d.max = new(big.Int).Lsh(big.NewInt(1), width)
+	}
+	d.max.Sub(d.max, big.NewInt(1))
+	return d, nil
+}
+
+// typeName - название типа для сообщений об ошибках: int32, uint8
+func (d *integerDomain) typeName() string {
+	if d.signed {
+		return "int" + strconv.Itoa(int(d.width))
+	}
+	return "uint" + strconv.Itoa(int(d.width))
+}
+
+func (d *integerDomain) int(x uint64) *big.Int {
+	return Word{Bits: x, Width: d.width, Signed: d.signed}.Int()
+}
+
+// fit приводит точный результат к разрядности домена
+func (d *integerDomain) fit(x *big.Int) (uint64, error) {
+	if x.Cmp(d.min) < 0 || x.Cmp(d.max) > 0 {
+		if d.overflow {
+			return 0, newError("%w: %s does not fit in %s", errIntegerOverflow, x, d.typeName())
+		}
+		// младшие биты дополнительного кода; And с маской даёт неотрицательное число
+		x = new(big.Int).And(x, new(big.Int).SetUint64(d.mask))
+	}
+	if x.Sign() < 0 {
+		return uint64(x.Int64()) & d.mask, nil
+	}
+	return x.Uint64(), nil
+}
+
+func (d *integerDomain) number(n *NumberLit) (uint64, error) {
+	text := n.Text
+	if text == "" {
+		text = strconv.FormatFloat(n.Value, 'g', -1, 64)
+	}
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return 0, errorAt(n.Span, "invalid number: %s", text)
+	}
+	if !r.IsInt() {
+		return 0, errorAt(n.Span, "integer mode needs integer numbers, got %s", text)
+	}
+
+	// 0xff в int8 - это набор битов, то есть -1
+	if isPrefixedNumber(text) && r.Num().BitLen() <= int(d.width) {
+		return r.Num().Uint64(), nil
+	}
+	x, err := d.fit(r.Num())
+	if err != nil {
+		return 0, wrapErrorAt(n.Span, err, "number %s", text)
+	}
+	return x, nil
+}
+
+func (d *integerDomain) constant(name string) (uint64, error) {
+	return 0, errors.New("not an integer")
+}
+
+func (d *integerDomain) fromFloat(x float64) (uint64, bool) {
+	if x != math.Trunc(x) || math.IsInf(x, 0) {
+		return 0, false
+	}
+	value, _ := new(big.Float).SetFloat64(x).Int(nil)
+	result, err := d.fit(value)
+	return result, err == nil
+}
+
+func (d *integerDomain) approx(x float64) (uint64, error) {
+	if math.IsNaN(x) {
+		return 0, errNotANumber
+	}
+	if math.IsInf(x, 0) {
+		return 0, errOverflow
+	}
+	if x != math.Trunc(x) {
+		return 0, errNotInteger
+	}
+	value, _ := new(big.Float).SetFloat64(x).Int(nil)
+	return d.fit(value)
+}
+
+func (d *integerDomain) toFloat(x uint64) (float64, bool) {
+	return Word{Bits: x, Width: d.width, Signed: d.signed}.Float64()
+}
+
+func (d *integerDomain) neg(x uint64) (uint64, error) {
+	return d.fit(new(big.Int).Neg(d.int(x)))
+}
+
+func (d *integerDomain) complement(x uint64) (uint64, error) {
+	return ^x & d.mask, nil
+}
+
+func (d *integerDomain) binary(op string, x, y uint64) (uint64, error) {
+	a, b := d.int(x), d.int(y)
+	z := new(big.Int)
+	switch op {
+	case "+":
+		z.Add(a, b)
+	case "-":
+		z.Sub(a, b)
+	case "*":
+		z.Mul(a, b)
+	case "/":
+		if b.Sign() == 0 {
+			return 0, errDivisionByZero
+		}
+		// деление с отбрасыванием дробной части, как в Go и C
+		z.Quo(a, b)
+	case "^":
+		return d.pow(a, b)
+	case "&":
+		return x & y, nil
+	case "|":
+		return x | y, nil
+	case "xor":
+		return x ^ y, nil
+	case "<<", ">>":
+		return d.shift(op, x, b)
+	default:
+		return 0, errUnsupported
+	}
+	return d.fit(z)
+}
+
+// pow возводит в неотрицательную целую степень. При переносе степень
+// считается по модулю 2^width, иначе - точно, пока результат не велик.
+func (d *integerDomain) pow(a, b *big.Int) (uint64, error) {
+	if b.Sign() < 0 {
+		return 0, errors.New("negative exponent in integer mode")
+	}
+	if !d.overflow {
+		modulus := new(big.Int).Lsh(big.NewInt(1), d.width)
+		return d.fit(new(big.Int).Exp(a, b, modulus))
+	}
+	if a.CmpAbs(big.NewInt(1)) > 0 && b.Cmp(big.NewInt(int64(d.width))) > 0 {
+		return 0, newError("%w: %s^%s does not fit in %s", errIntegerOverflow, a, b, d.typeName())
+	}
+	return d.fit(new(big.Int).Exp(a, b, nil))
+}
+
+// shift сдвигает биты: >> у знаковых чисел арифметический, у беззнаковых - логический
+func (d *integerDomain) shift(op string, x uint64, count *big.Int) (uint64, error) {
+	if count.Sign() < 0 || count.Cmp(big.NewInt(int64(d.width))) >= 0 {
+		return 0, newError("shift count %s out of range for %s", count, d.typeName())
+	}
+	n := uint(count.Uint64())
+	if op == "<<" {
+		if d.overflow {
+			return d.fit(new(big.Int).Lsh(d.int(x), n))
+		}
+		return x << n & d.mask, nil
+	}
+	if d.signed {
+		return uint64(d.int(x).Int64()>>n) & d.mask, nil
+	}
+	return x >> n, nil
+}
+
+func (d *integerDomain) call(name string, args []uint64) (uint64, error) {
+	switch name {
+	case "max", "min":
+		result := args[0]
+		for _, arg := range args[1:] {
+			if c := d.int(arg).Cmp(d.int(result)); c > 0 && name == "max" || c < 0 && name == "min" {
+				result = arg
+			}
+		}
+		return result, nil
+	case "re", "conj":
+		return args[0], nil
+	case "im":
+		return 0, nil
+	}
+
+	rats := make([]*big.Rat, 0, len(args))
+	for _, arg := range args {
+		rats = append(rats, new(big.Rat).SetInt(d.int(arg)))
+	}
+	result, err := ratFunction(name, rats)
+	if err != nil {
+		return 0, err
+	}
+	// mean и median от целых чисел могут быть дробными
+	if !result.IsInt() {
+		return 0, errNotInteger
+	}
+	return d.fit(result.Num())
+}
+
+func (d *integerDomain) list(elems []uint64) (uint64, error) {
+	return 0, errUnsupported
+}
+
+func (d *integerDomain) value(x uint64) Value {
+	return Word{Bits: x, Width: d.width, Signed: d.signed, Base: d.base}
+}
+
+// isPrefixedNumber проверяет, что литерал записан с префиксом 0x, 0b или 0o
+func isPrefixedNumber(text string) bool {
+	return len(text) > 2 && text[0] == '0' && strings.ContainsRune("xXbBoO", rune(text[1]))
+}
+
+// bitwiseOperand переводит float64-операнд побитовой операции в int64
+func bitwiseOperand(x float64) (int64, error) {
+	if x != math.Trunc(x) || x < math.MinInt64 || x >= math.MaxInt64 {
+		return 0, errBitwiseOperand
+	}
+	return int64(x), nil
+}
+
+// bitwise возвращает float64-реализацию побитового оператора над int64
+func bitwise(fn func(a, b int64) int64) func(a, b float64) (float64, error) {
+	return func(a, b float64) (float64, error) {
+		x, err := bitwiseOperand(a)
+		if err != nil {
+			return 0, err
+		}
+		y, err := bitwiseOperand(b)
+		if err != nil {
+			return 0, err
+		}
+		return float64(fn(x, y)), nil
+	}
+}
+
+// shift возвращает float64-реализацию сдвига int64
+func shift(left bool) func(a, b float64) (float64, error) {
+	return func(a, b float64) (float64, error) {
+		x, err := bitwiseOperand(a)
+		if err != nil {
+			return 0, err
+		}
+		n, err := bitwiseOperand(b)
+		if err != nil {
+			return 0, err
+		}
+		if n < 0 || n > 63 {
+			return 0, newError("shift count %d out of range for int64", n)
+		}
+		if left {
+			return float64(x << n), nil
+		}
+		return float64(x >> n), nil
+	}
+}
+
+// BitNot - побитовое отрицание целого числа ~x
+func BitNot(x float64) (float64, error) {
+	n, err := bitwiseOperand(x)
+	if err != nil {
+		return 0, err
+	}
+	return float64(^n), nil
+}
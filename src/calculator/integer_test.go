@@ -0,0 +1,158 @@
+package calculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInteger(t *testing.T) {
+	type CaseInteger struct {
+		expression string
+		width      uint
+		unsigned   bool
+		overflow   bool
+		base       int
+		result     string
+		err        string
+	}
+	cases := []CaseInteger{
+		{expression: "0xFF & 0x0F", result: "15"},
+		{expression: "0b1010 | 0b0101", result: "15"},
+		{expression: "0b1010 xor 0b0110", result: "12"},
+		{expression: "0o17 + 1", result: "16"},
+		{expression: "1 << 2 + 1", result: "8"},
+		{expression: "0xF0 >> 4 & 0x3", result: "3"},
+		{expression: "~0", result: "-1"},
+		{expression: "~0x0F & 0xFF", result: "240"},
+		{expression: "7 / 2", result: "3"},
+		{expression: "-7 / 2", result: "-3"},
+		{expression: "2^10", result: "1024"},
+		{expression: "max(3, -5, 2)", result: "3"},
+		{expression: "sqrt(16)", result: "4"},
+		{expression: "x = 0x10; x * x", result: "256"},
+
+		// перенос по модулю 2^width
+		{expression: "2^63", result: "-9223372036854775808"},
+		{expression: "0x7F + 1", width: 8, result: "-128"},
+		{expression: "0xFF", width: 8, result: "-1"},
+		{expression: "200", width: 8, result: "-56"},
+		{expression: "0 - 1", width: 16, unsigned: true, result: "65535"},
+		{expression: "-1 >> 1", width: 8, result: "-1"},
+		{expression: "-1 >> 1", width: 8, unsigned: true, result: "127"},
+		{expression: "1 << 31", width: 32, result: "-2147483648"},
+		{expression: "3^40", width: 32, unsigned: true, result: "689956897"},
+		{expression: "-128 / -1", width: 8, result: "-128"},
+
+		// основание
+		{expression: "255", base: 16, result: "0xff"},
+		{expression: "-1", width: 8, base: 16, result: "0xff"},
+		{expression: "10", base: 2, result: "0b1010"},
+		{expression: "8", base: 8, result: "0o10"},
+
+		// переполнение как ошибка
+		{expression: "100 + 100", width: 8, overflow: true, err: "1:1: calculating addition: integer overflow: 200 does not fit in int8"},
+		{expression: "0 - 1", width: 32, unsigned: true, overflow: true, err: "integer overflow: -1 does not fit in uint32"},
+		{expression: "-128 / -1", width: 8, overflow: true, err: "integer overflow: 128 does not fit in int8"},
+		{expression: "1 << 8", width: 16, overflow: true, result: "256"},
+		{expression: "1 << 15", width: 16, overflow: true, err: "calculating left shift: integer overflow: 32768 does not fit in int16"},
+		{expression: "2^62", overflow: true, result: "4611686018427387904"},
+		{expression: "2^63", overflow: true, err: "integer overflow: 9223372036854775808 does not fit in int64"},
+		{expression: "3^1000", overflow: true, err: "integer overflow: 3^1000 does not fit in int64"},
+		{expression: "300", width: 8, unsigned: true, overflow: true, err: "1:1: number 300: integer overflow: 300 does not fit in uint8"},
+		{expression: "0xFF", width: 8, overflow: true, result: "-1"},
+
+		{expression: "1 / 0", err: "calculating division: division by zero"},
+		{expression: "2^-1", err: "calculating power: negative exponent in integer mode"},
+		{expression: "1 << 64", err: "calculating left shift: shift count 64 out of range for int64"},
+		{expression: "1 >> -1", err: "calculating right shift: shift count -1 out of range for int64"},
+		{expression: "2.5", err: "1:1: integer mode needs integer numbers, got 2.5"},
+		{expression: "pi", err: "1:1: constant pi: not an integer"},
+		{expression: "sqrt(2)", err: "calculating sqrt: result is not an integer"},
+		{expression: "0b102", err: "error while parsing: 1:1: invalid number: 0b102"},
+		{expression: "1", width: 12, err: "unsupported integer width 12, expected 8, 16, 32 or 64"},
+		{expression: "1", base: 3, err: "unsupported base 3, expected 2, 8, 10 or 16"},
+	}
+
+	for _, c := range cases {
+		result, err := Evaluate(c.expression, CalculatorConfig{
+			Mode:          Integer,
+			IntWidth:      c.width,
+			Unsigned:      c.unsigned,
+			OverflowError: c.overflow,
+			Base:          c.base,
+		})
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.result, result.String(), c.expression)
+	}
+}
+
+func TestIntegerWord(t *testing.T) {
+	result, err := Evaluate("0x80", CalculatorConfig{Mode: Integer, IntWidth: 8})
+	require.NoError(t, err)
+	require.Equal(t, Word{Bits: 0x80, Width: 8, Signed: true, Base: 10}, result)
+	require.Equal(t, int64(-128), result.(Word).Int().Int64())
+
+	value, err := Calculate("0xFFFF", CalculatorConfig{Mode: Integer, IntWidth: 16, Unsigned: true})
+	require.NoError(t, err)
+	require.Equal(t, 65535.0, value)
+
+	_, err = CalculateWithVars("x + 1", CalculatorConfig{Mode: Integer}, map[string]float64{"x": 0.5})
+	require.ErrorContains(t, err, "variable x: 0.5 is not representable")
+}
+
+func TestBitwiseOperators(t *testing.T) {
+	type CaseBitwise struct {
+		expression string
+		mode       Mode
+		result     float64
+		err        string
+	}
+	cases := []CaseBitwise{
+		{expression: "6 | 1 xor 3", mode: Integer, result: 6 | (1 ^ 3)},
+		{expression: "-16 >> 2", mode: Integer, result: -4},
+		{expression: "-~5", mode: Integer, result: 6},
+		{expression: "1 + 2 & 3 * 1", mode: Integer, result: 3},
+
+		// в остальных режимах побитовых операторов нет, их имена свободны
+		{expression: "0x10 + 0b11 + 0o7", result: 26},
+		{expression: "xor = 1; xor + 1", result: 2},
+		{expression: "xor = 1; xor + 1", mode: Rational, result: 2},
+		{expression: "0xFF & 0x0F", err: "unexpected character: &"},
+		{expression: "1 << 2", mode: Complex, err: "unexpected character: <"},
+		{expression: "~5", err: "unexpected character: ~"},
+		{expression: "0x", err: "1:2: unexpected token: x"},
+		{expression: "xor = 1", mode: Integer, err: "cannot assign to operator xor"},
+	}
+
+	for _, c := range cases {
+		result, err := Calculate(c.expression, CalculatorConfig{Mode: c.mode})
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.result, result, c.expression)
+	}
+
+	calc := NewCalculator(CalculatorConfig{Mode: Integer})
+	tree, err := calc.Parse("~a & b | c << 2")
+	require.NoError(t, err)
+	require.Equal(t, "~a & b | c << 2", tree.String())
+	tree, err = calc.Parse("(a | b) & c")
+	require.NoError(t, err)
+	require.Equal(t, "(a | b) & c", tree.String())
+
+	// на уровнях побитовых операторов можно регистрировать свои
+	calc = NewCalculator(CalculatorConfig{})
+	require.NoError(t, calc.RegisterOperator("xor", 2, LeftAssoc, func(a, b float64) (float64, error) {
+		return float64(int64(a) ^ int64(b)), nil
+	}))
+	result, err := calc.Calculate("1 + 2 xor 3")
+	require.NoError(t, err)
+	require.Equal(t, 0.0, result)
+}
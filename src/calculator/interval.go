@@ -0,0 +1,589 @@
+package calculator
+
+import (
+	"errors"
+	"math"
+	"math/big"
+)
+
+// Bounds - результат вычисления в режиме Interval: точное значение
+// выражения гарантированно лежит между Lo и Hi
+type Bounds struct {
+	Lo float64
+	Hi float64
+}
+
+func (b Bounds) String() string {
+	return b.Text(formatFloat)
+}
+
+// Text печатает границы, форматируя их функцией number
+func (b Bounds) Text(number func(float64) string) string {
+	return "[" + number(b.Lo) + ", " + number(b.Hi) + "]"
+}
+
+func (b Bounds) Float64() (float64, bool) {
+	return b.Lo, b.Lo == b.Hi
+}
+
+type interval struct {
+	lo float64
+	hi float64
+}
+
+func point(x float64) interval {
+	return interval{x, x}
+}
+
+// intervalDomain - интервальная арифметика. Границы округляются наружу:
+// для + - * / и sqrt погрешность округления вычисляется точно и граница
+// сдвигается на ulp только при необходимости, результаты функций из math
+// расширяются на outwardULPs.
+type intervalDomain struct {
+	angle AngleUnit
+}
+
+// на сколько ulp расширяются результаты функций из math, их погрешность меньше 1 ulp
+const outwardULPs = 2
+
+func newIntervalDomain(config CalculatorConfig) *intervalDomain {
+	return &intervalDomain{angle: config.AngleUnits}
+}
+
+func (d *intervalDomain) number(n *NumberLit) (interval, error) {
+	x, err := n.float()
+	if err != nil {
+		return interval{}, err
+	}
+	if n.Text == "" {
+		return point(x), nil
+	}
+	exact, ok := new(big.Rat).SetString(n.Text)
+	if !ok {
+		return interval{}, errorAt(n.Span, "invalid number: %s", n.Text)
+	}
+
+	// 0.1 не представимо в float64: интервал берётся между соседними числами
+	switch new(big.Rat).SetFloat64(x).Cmp(exact) {
+	case 1:
+		return interval{nextDown(x), x}, nil
+	case -1:
+		return interval{x, nextUp(x)}, nil
+	}
+	return point(x), nil
+}
+
+// math.Pi и math.E меньше точных значений, поэтому константы
+// лежат между ними и следующими числами float64
+func (d *intervalDomain) constant(name string) (interval, error) {
+	if name == "e" {
+		return interval{math.E, nextUp(math.E)}, nil
+	}
+	return interval{math.Pi, nextUp(math.Pi)}, nil
+}
+
+func (d *intervalDomain) fromFloat(x float64) (interval, bool) {
+	return point(x), !math.IsNaN(x) && !math.IsInf(x, 0)
+}
+
+func (d *intervalDomain) approx(x float64) (interval, error) {
+	return checkInterval(outward(x, x))
+}
+
+func (d *intervalDomain) toFloat(x interval) (float64, bool) {
+	return x.lo, x.lo == x.hi
+}
+
+func (d *intervalDomain) neg(x interval) (interval, error) {
+	return interval{-x.hi + 0, -x.lo + 0}, nil
+}
+
+func (d *intervalDomain) binary(op string, x, y interval) (interval, error) {
+	var z interval
+	switch op {
+	case "+":
+		z = interval{addRound(x.lo, y.lo, false), addRound(x.hi, y.hi, true)}
+	case "-":
+		z = interval{addRound(x.lo, -y.hi, false), addRound(x.hi, -y.lo, true)}
+	case "*":
+		z = intervalMul(x, y)
+	case "/":
+		if y.lo <= 0 && y.hi >= 0 {
+			return interval{}, errDivisionByZero
+		}
+		z = corners(x, y, divRound)
+	case "^":
+		var err error
+		if z, err = intervalPow(x, y); err != nil {
+			return interval{}, err
+		}
+	case "±":
+		if y.lo < 0 {
+			return interval{}, errNegativeTolerance
+		}
+		z = interval{addRound(x.lo, -y.hi, false), addRound(x.hi, y.hi, true)}
+	default:
+		return interval{}, errUnsupported
+	}
+	return checkInterval(z)
+}
+
+func (d *intervalDomain) call(name string, args []interval) (interval, error) {
+	x := args[0]
+	var z interval
+	switch name {
+	case "sqrt":
+		if x.lo < 0 {
+			return interval{}, errSqrtDomain
+		}
+		z = interval{sqrtRound(x.lo, false), sqrtRound(x.hi, true)}
+	case "ln":
+		if x.lo <= 0 {
+			return interval{}, errLnDomain
+		}
+		z = outward(math.Log(x.lo), math.Log(x.hi))
+	case "exp":
+		// exp положительна, а ноль у неё - результат потери значимости
+		z = outward(math.Exp(x.lo), math.Exp(x.hi))
+		z.lo = math.Max(z.lo, 0)
+		if z.hi == 0 {
+			z.hi = nextUp(0)
+		}
+	case "sin", "cos", "tg", "ctg":
+		var err error
+		if x, err = d.toRadians(x); err != nil {
+			return interval{}, err
+		}
+		if z, err = intervalTrig(name, x); err != nil {
+			return interval{}, err
+		}
+	case "arcsin", "arccos", "arctg", "arcctg":
+		var err error
+		if z, err = intervalInverseTrig(name, x); err != nil {
+			return interval{}, err
+		}
+		if z, err = d.fromRadians(z); err != nil {
+			return interval{}, err
+		}
+	case "sinh", "cosh", "tanh", "coth", "arsinh", "arcosh", "artanh", "arcoth":
+		var err error
+		if z, err = intervalHyperbolic(name, x); err != nil {
+			return interval{}, err
+		}
+	case "log", "log10", "log2":
+		base, x := logArgs(name, args, point)
+		if base.lo <= 0 || base.lo <= 1 && base.hi >= 1 {
+			return interval{}, errLogBase
+		}
+		if x.lo <= 0 {
+			return interval{}, errLogDomain
+		}
+		z = corners(outward(math.Log(x.lo), math.Log(x.hi)), outward(math.Log(base.lo), math.Log(base.hi)), divRound)
+	case "max":
+		z = x
+		for _, arg := range args[1:] {
+			z = interval{math.Max(z.lo, arg.lo), math.Max(z.hi, arg.hi)}
+		}
+	case "min":
+		z = x
+		for _, arg := range args[1:] {
+			z = interval{math.Min(z.lo, arg.lo), math.Min(z.hi, arg.hi)}
+		}
+	case "abs":
+		switch {
+		case x.lo >= 0:
+			z = x
+		case x.hi <= 0:
+			z = interval{-x.hi, -x.lo}
+		default:
+			z = interval{0, math.Max(-x.lo, x.hi)}
+		}
+	case "floor", "ceil", "trunc", "sign":
+		// функции неубывающие и точные, достаточно вычислить их на концах
+		f := map[string]func(float64) float64{"floor": Floor, "ceil": Ceil, "trunc": Trunc, "sign": Sign}[name]
+		z = interval{f(x.lo), f(x.hi)}
+	case "round":
+		var digits float64
+		if len(args) == 2 {
+			if args[1].lo != args[1].hi {
+				return interval{}, errors.New("number of digits must be an exact number")
+			}
+			digits = args[1].lo
+		}
+		lo, err := Round(x.lo, digits)
+		if err != nil {
+			return interval{}, err
+		}
+		hi, _ := Round(x.hi, digits)
+		z = interval{lo, hi}
+		// 0.1 и другие десятичные дроби в float64 неточны
+		if digits > 0 {
+			z = outward(lo, hi)
+		}
+	case "re", "conj":
+		z = x
+	case "im":
+		z = point(0)
+	case "sum", "mean", "median", "percentile":
+		var err error
+		if z, err = intervalStatistic(name, args); err != nil {
+			return interval{}, err
+		}
+	default:
+		return interval{}, errUnsupported
+	}
+	return checkInterval(z)
+}
+
+// intervalStatistic вычисляет sum, mean, median и percentile: они не убывают
+// по каждому аргументу, поэтому достаточно вычислить их на нижних границах
+// с округлением вниз и на верхних - с округлением вверх
+func intervalStatistic(name string, args []interval) (interval, error) {
+	if name == "percentile" && (args[0].lo < 0 || args[0].hi > 100) {
+		return interval{}, newError("percentile must be between 0 and 100")
+	}
+	bound := func(up bool) float64 {
+		xs := make([]float64, 0, len(args))
+		for _, arg := range args {
+			if up {
+				xs = append(xs, arg.hi)
+			} else {
+				xs = append(xs, arg.lo)
+			}
+		}
+		switch name {
+		case "sum", "mean":
+			sum := 0.0
+			for _, x := range xs {
+				sum = addRound(sum, x, up)
+			}
+			if name == "mean" {
+				sum = divRound(sum, float64(len(xs)), up)
+			}
+			return sum
+		case "median":
+			return percentileRound(0.5, sorted(xs), up)
+		}
+		return percentileRound(divRound(xs[0], 100, up), sorted(xs[1:]), up)
+	}
+	return interval{bound(false), bound(true)}, nil
+}
+
+// percentileRound - percentile с направленным округлением
+func percentileRound(q float64, xs []float64, up bool) float64 {
+	h := mulRound(q, float64(len(xs)-1), up)
+	i := int(h)
+	if i >= len(xs)-1 {
+		return xs[len(xs)-1]
+	}
+	diff := addRound(xs[i+1], -xs[i], up)
+	return addRound(xs[i], mulRound(h-float64(i), diff, up), up)
+}
+
+// list разбирает литерал [lo, hi]
+func (d *intervalDomain) list(elems []interval) (interval, error) {
+	if len(elems) != 2 {
+		return interval{}, newError("interval needs 2 bounds, got %d", len(elems))
+	}
+	if elems[0].lo > elems[1].hi {
+		return interval{}, errors.New("lower bound is greater than upper bound")
+	}
+	return interval{elems[0].lo, elems[1].hi}, nil
+}
+
+func (d *intervalDomain) value(x interval) Value {
+	return Bounds{Lo: x.lo, Hi: x.hi}
+}
+
+func (d *intervalDomain) toRadians(x interval) (interval, error) {
+	if d.angle.isRadian() {
+		return x, nil
+	}
+	pi, _ := d.constant("pi")
+	return checkInterval(corners(intervalMul(x, pi), point(d.angle.halfTurn()), divRound))
+}
+
+func (d *intervalDomain) fromRadians(x interval) (interval, error) {
+	if d.angle.isRadian() {
+		return x, nil
+	}
+	pi, _ := d.constant("pi")
+	return checkInterval(corners(intervalMul(x, point(d.angle.halfTurn())), pi, divRound))
+}
+
+// intervalInverseTrig - обратные тригонометрические функции монотонны:
+// arcsin и arctg возрастают, arccos и arcctg убывают
+func intervalInverseTrig(name string, x interval) (interval, error) {
+	switch name {
+	case "arcsin", "arccos":
+		if x.lo < -1 || x.hi > 1 {
+			return interval{}, outsideUnitError(name)
+		}
+		if name == "arcsin" {
+			return outward(math.Asin(x.lo), math.Asin(x.hi)), nil
+		}
+		z := outward(math.Acos(x.hi), math.Acos(x.lo))
+		// arccos 1 = 0 точно
+		return interval{math.Max(z.lo, 0), z.hi}, nil
+	case "arctg":
+		return outward(math.Atan(x.lo), math.Atan(x.hi)), nil
+	default:
+		z := outward(Acot(x.hi), Acot(x.lo))
+		return interval{math.Max(z.lo, 0), z.hi}, nil
+	}
+}
+
+// intervalHyperbolic - гиперболические функции и обратные к ним. Все они,
+// кроме cosh, монотонны на каждом участке области определения.
+func intervalHyperbolic(name string, x interval) (interval, error) {
+	switch name {
+	case "sinh":
+		return outward(math.Sinh(x.lo), math.Sinh(x.hi)), nil
+	case "cosh":
+		a, b := math.Cosh(x.lo), math.Cosh(x.hi)
+		z := outward(math.Min(a, b), math.Max(a, b))
+		if x.lo <= 0 && x.hi >= 0 {
+			z.lo = 1
+		}
+		return interval{math.Max(z.lo, 1), z.hi}, nil
+	case "tanh":
+		z := outward(math.Tanh(x.lo), math.Tanh(x.hi))
+		return interval{math.Max(z.lo, -1), math.Min(z.hi, 1)}, nil
+	case "coth":
+		if x.lo <= 0 && x.hi >= 0 {
+			return interval{}, errors.New("interval contains zero, a pole of hyperbolic cotangent")
+		}
+		return outward(1/math.Tanh(x.hi), 1/math.Tanh(x.lo)), nil
+	case "arsinh":
+		return outward(math.Asinh(x.lo), math.Asinh(x.hi)), nil
+	case "arcosh":
+		if x.lo < 1 {
+			return interval{}, errAcoshDomain
+		}
+		z := outward(math.Acosh(x.lo), math.Acosh(x.hi))
+		return interval{math.Max(z.lo, 0), z.hi}, nil
+	case "artanh":
+		if x.lo <= -1 || x.hi >= 1 {
+			return interval{}, errAtanhDomain
+		}
+		return outward(math.Atanh(x.lo), math.Atanh(x.hi)), nil
+	default:
+		if x.lo <= 1 && x.hi >= -1 {
+			return interval{}, errAcothDomain
+		}
+		// 1/x округляется наружу отдельно: у ±1 atanh усиливает погрешность
+		return outward(math.Atanh(divRound(1, x.hi, false)), math.Atanh(divRound(1, x.lo, true))), nil
+	}
+}
+
+func intervalTrig(name string, x interval) (interval, error) {
+	switch name {
+	case "sin":
+		// максимумы синуса в pi/2 + 2pi*k, минимумы в -pi/2 + 2pi*k
+		return trigRange(x, math.Sin, math.Pi/2, -math.Pi/2), nil
+	case "cos":
+		return trigRange(x, math.Cos, 0, math.Pi), nil
+	case "tg":
+		if x.hi-x.lo >= math.Pi || reaches(x, math.Pi/2, math.Pi) {
+			return interval{}, errors.New("interval contains a pole of tangent at pi/2 * k")
+		}
+		return outward(math.Tan(x.lo), math.Tan(x.hi)), nil
+	default:
+		if x.hi-x.lo >= math.Pi || reaches(x, 0, math.Pi) {
+			return interval{}, errors.New("interval contains a pole of cotangent at pi * k")
+		}
+		return outward(1/math.Tan(x.hi), 1/math.Tan(x.lo)), nil
+	}
+}
+
+// trigRange - область значений синуса или косинуса на отрезке
+func trigRange(x interval, f func(float64) float64, peak, trough float64) interval {
+	if x.hi-x.lo >= 2*math.Pi {
+		return interval{-1, 1}
+	}
+
+	a, b := f(x.lo), f(x.hi)
+	z := outward(math.Min(a, b), math.Max(a, b))
+	if reaches(x, peak, 2*math.Pi) {
+		z.hi = 1
+	}
+	if reaches(x, trough, 2*math.Pi) {
+		z.lo = -1
+	}
+	return interval{math.Max(z.lo, -1), math.Min(z.hi, 1)}
+}
+
+// reaches проверяет, содержит ли отрезок точку offset + period*k при целом k.
+// Сравнение идёт с запасом: лишняя точка только расширяет результат.
+func reaches(x interval, offset, period float64) bool {
+	k := math.Ceil((x.lo - offset) / period)
+	for _, k := range []float64{k - 1, k, k + 1} {
+		t := offset + period*k
+		slack := 1e-12 * math.Max(1, math.Abs(t))
+		if t >= x.lo-slack && t <= x.hi+slack {
+			return true
+		}
+	}
+	return false
+}
+
+func intervalMul(x, y interval) interval {
+	return corners(x, y, mulRound)
+}
+
+// corners применяет монотонную по каждому аргументу операцию к концам отрезков
+func corners(x, y interval, op func(a, b float64, up bool) float64) interval {
+	z := interval{math.Inf(1), math.Inf(-1)}
+	for _, a := range []float64{x.lo, x.hi} {
+		for _, b := range []float64{y.lo, y.hi} {
+			z.lo = math.Min(z.lo, op(a, b, false))
+			z.hi = math.Max(z.hi, op(a, b, true))
+		}
+	}
+	return z
+}
+
+func intervalPow(x, y interval) (interval, error) {
+	if n := y.lo; y.lo == y.hi && n == math.Trunc(n) && math.Abs(n) <= 1<<20 {
+		return intervalPowInt(x, int(n))
+	}
+
+	if x.lo < 0 {
+		return interval{}, errNegativePower
+	}
+	if x.lo == 0 && y.lo < 0 {
+		return interval{}, errDivisionByZero
+	}
+
+	// x^y монотонна по каждому аргументу при x >= 0
+	z := interval{math.Inf(1), math.Inf(-1)}
+	for _, a := range []float64{x.lo, x.hi} {
+		for _, b := range []float64{y.lo, y.hi} {
+			v := math.Pow(a, b)
+			z.lo, z.hi = math.Min(z.lo, v), math.Max(z.hi, v)
+		}
+	}
+	z = outward(z.lo, z.hi)
+	return interval{math.Max(z.lo, 0), z.hi}, nil
+}
+
+func intervalPowInt(x interval, n int) (interval, error) {
+	switch {
+	case n == 0:
+		return point(1), nil
+	case n < 0:
+		z, err := intervalPowInt(x, -n)
+		if err != nil {
+			return interval{}, err
+		}
+		if z.lo <= 0 && z.hi >= 0 {
+			return interval{}, errDivisionByZero
+		}
+		return corners(point(1), z, divRound), nil
+	case n%2 == 1:
+		return interval{powRound(x.lo, n, false), powRound(x.hi, n, true)}, nil
+	case x.lo >= 0:
+		return interval{powRound(x.lo, n, false), powRound(x.hi, n, true)}, nil
+	case x.hi <= 0:
+		return interval{powRound(-x.hi, n, false), powRound(-x.lo, n, true)}, nil
+	}
+	return interval{0, powRound(math.Max(-x.lo, x.hi), n, true)}, nil
+}
+
+// powRound возводит в натуральную степень с округлением в сторону up
+func powRound(a float64, n int, up bool) float64 {
+	if a < 0 {
+		// n нечётно: a^n = -(|a|^n), округление в другую сторону
+		return -powRound(-a, n, !up)
+	}
+	result := 1.0
+	for ; n > 0; n >>= 1 {
+		if n&1 == 1 {
+			result = mulRound(result, a, up)
+		}
+		a = mulRound(a, a, up)
+	}
+	return result
+}
+
+// Операции с направленным округлением: up - вверх, иначе вниз. Ошибка
+// округления находится точно (TwoSum, FMA), и результат сдвигается
+// на ulp, только если округление ушло не в ту сторону.
+func addRound(a, b float64, up bool) float64 {
+	s := a + b
+	if math.IsInf(s, 0) {
+		return s
+	}
+	bb := s - a
+	return adjust(s, (a-(s-bb))+(b-bb), up)
+}
+
+func mulRound(a, b float64, up bool) float64 {
+	p := a * b
+	if math.IsInf(p, 0) {
+		return p
+	}
+	return adjust(p, math.FMA(a, b, -p), up)
+}
+
+func divRound(a, b float64, up bool) float64 {
+	q := a / b
+	if math.IsInf(q, 0) {
+		return q
+	}
+	// a - q*b вычисляется точно, его знак вместе со знаком b дают знак ошибки
+	r := math.FMA(-q, b, a)
+	if b < 0 {
+		r = -r
+	}
+	return adjust(q, r, up)
+}
+
+func sqrtRound(x float64, up bool) float64 {
+	s := math.Sqrt(x)
+	return adjust(s, math.FMA(-s, s, x), up)
+}
+
+// adjust сдвигает результат r на ulp, если точное значение r + err
+// лежит по другую сторону от направления округления
+func adjust(r, err float64, up bool) float64 {
+	if up && err > 0 {
+		return nextUp(r)
+	}
+	if !up && err < 0 {
+		return nextDown(r)
+	}
+	return r
+}
+
+// outward расширяет результат функции из math. Ноль не расширяется:
+// ln, sin и tg возвращают его только там, где он точный.
+func outward(lo, hi float64) interval {
+	for range outwardULPs {
+		if lo != 0 {
+			lo = nextDown(lo)
+		}
+		if hi != 0 {
+			hi = nextUp(hi)
+		}
+	}
+	return interval{lo, hi}
+}
+
+func nextUp(x float64) float64 {
+	return math.Nextafter(x, math.Inf(1))
+}
+
+func nextDown(x float64) float64 {
+	return math.Nextafter(x, math.Inf(-1))
+}
+
+func checkInterval(x interval) (interval, error) {
+	if math.IsNaN(x.lo) || math.IsNaN(x.hi) {
+		return interval{}, errNotANumber
+	}
+	if math.IsInf(x.lo, 0) || math.IsInf(x.hi, 0) {
+		return interval{}, errOverflow
+	}
+	return x, nil
+}
@@ -0,0 +1,120 @@
+package calculator
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterval(t *testing.T) {
+	type CaseInterval struct {
+		expression string
+		angleUnits AngleUnit
+		result     string
+		err        string
+	}
+	cases := []CaseInterval{
+		{expression: "[1.9, 2.1]", result: "[1.9, 2.1]"},
+		{expression: "2±0.1", result: "[1.9, 2.1]"},
+		{expression: "1 + 1", result: "[2, 2]"},
+		{expression: "0.1 + 0.2", result: "[0.29999999999999993, 0.30000000000000004]"},
+		{expression: "[1, 2] - [1, 2]", result: "[-1, 1]"},
+		{expression: "-[0, 1]", result: "[-1, 0]"},
+		{expression: "[-1, 2] * [3, 4]", result: "[-4, 8]"},
+		{expression: "1 / [2, 4]", result: "[0.25, 0.5]"},
+		{expression: "[-1, 2]^2", result: "[0, 4]"},
+		{expression: "[-2, 1]^3", result: "[-8, 1]"},
+		{expression: "[2, 4]^-1", result: "[0.25, 0.5]"},
+		{expression: "2±1 * 2", result: "[2, 6]"},
+		{expression: "sqrt([4, 9])", result: "[2, 3]"},
+		{expression: "sqrt(2)", result: "[1.414213562373095, 1.4142135623730951]"},
+		{expression: "ln([1, 1])", result: "[0, 0]"},
+		{expression: "sin([0, 4])", result: "[-0.7568024953079284, 1]"},
+		{expression: "cos([-1, 1])", result: "[0.5403023058681395, 1]"},
+		{expression: "sin([0, 7])", result: "[-1, 1]"},
+		{expression: "sin(90)", angleUnits: "degree", result: "[0.9999999999999998, 1]"},
+		{expression: "max([1, 3], [2, 2])", result: "[2, 3]"},
+		{expression: "pi", result: "[3.141592653589793, 3.1415926535897936]"},
+		{expression: "x = 2±0.5; x - x", result: "[-1, 1]"},
+
+		{expression: "1 / [-1, 1]", err: "1:1: calculating division: division by zero"},
+		{expression: "sqrt([-1, 1])", err: "1:1: calculating sqrt: square root of negative number"},
+		{expression: "ln([0, 1])", err: "1:1: calculating ln: natural logarithm of non-positive number"},
+		{expression: "tg([1.5, 1.6])", err: "1:1: calculating tg: interval contains a pole of tangent at pi/2 * k"},
+		{expression: "tg(pi/2)", err: "interval contains a pole of tangent"},
+		{expression: "ctg([-0.1, 0.1])", err: "interval contains a pole of cotangent at pi * k"},
+		{expression: "[-1, 1]^0.5", err: "calculating power: negative number to a non-integer power"},
+		{expression: "[1, 2, 3]", err: "1:1: list: interval needs 2 bounds, got 3"},
+		{expression: "[2, 1]", err: "1:1: list: lower bound is greater than upper bound"},
+		{expression: "2±-1", err: "1:1: calculating tolerance: tolerance must be non-negative"},
+	}
+
+	for _, c := range cases {
+		result, err := Evaluate(c.expression, CalculatorConfig{Mode: Interval, AngleUnits: c.angleUnits})
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.result, result.String(), c.expression)
+	}
+}
+
+// Границы должны содержать точное значение, посчитанное с большой точностью
+func TestIntervalEnclosure(t *testing.T) {
+	expressions := []string{
+		"0.1 * 3", "1 / 3", "2 / 3 - 0.7", "sqrt(0.2)", "0.1^7", "1.1^-3",
+		"ln(10)", "exp(0.3)", "sin(1)", "cos(2.5)", "tg(1.2)", "ctg(0.4)", "sin(100)",
+		"2^0.3", "log(3, 7)", "pi * e",
+	}
+
+	for _, expression := range expressions {
+		result, err := Evaluate(expression, CalculatorConfig{Mode: Interval})
+		require.NoError(t, err, expression)
+		bounds := result.(Bounds)
+
+		exact, err := Evaluate(expression, CalculatorConfig{Precision: 256})
+		require.NoError(t, err, expression)
+		x := exact.(BigFloat).Big()
+
+		require.LessOrEqual(t, big.NewFloat(bounds.Lo).Cmp(x), 0, "%s: %v", expression, bounds)
+		require.GreaterOrEqual(t, big.NewFloat(bounds.Hi).Cmp(x), 0, "%s: %v", expression, bounds)
+		require.Less(t, bounds.Hi-bounds.Lo, 1e-14*math.Max(1, math.Abs(bounds.Lo)), expression)
+	}
+}
+
+func TestToleranceOutsideIntervalMode(t *testing.T) {
+	_, err := Calculate("2±0.1", CalculatorConfig{})
+	require.EqualError(t, err, "error while calculating: 1:1: calculating tolerance: values with tolerance need interval or uncertainty mode")
+
+	_, err = Calculate("[1, 2]", CalculatorConfig{})
+	require.EqualError(t, err, "error while parsing: 1:1: list literals are not supported in float mode")
+
+	tree, err := Parse("2±0.1^2 * 3")
+	require.NoError(t, err)
+	require.Equal(t, "(* (^ (± 2 0.1) 2) 3)", sexpr(tree))
+}
+
+func TestIntervalSharesDomainErrors(t *testing.T) {
+	cases := map[string]error{
+		"sqrt(-1)":    errSqrtDomain,
+		"ln(0)":       errLnDomain,
+		"log(1, 2)":   errLogBase,
+		"log(2, -1)":  errLogDomain,
+		"arcosh(0.5)": errAcoshDomain,
+		"artanh(1)":   errAtanhDomain,
+		"arcoth(0.5)": errAcothDomain,
+		"1 ± -0.1":    errNegativeTolerance,
+	}
+	for expression, sentinel := range cases {
+		_, err := Evaluate(expression, CalculatorConfig{Mode: Interval})
+		require.ErrorIs(t, err, sentinel, expression)
+		if sentinel != errNegativeTolerance {
+			_, err = Evaluate(expression, CalculatorConfig{})
+			require.ErrorIs(t, err, sentinel, expression)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package calculator
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOperator
+	tokLParen
+	tokRParen
+	tokAssign
+	tokSemicolon
+	tokComma
+	tokLBracket
+	tokRBracket
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	span Span
+}
+
+// tokenize разбивает выражение на токены; symbols - знаки операторов,
+// при совпадении нескольких берётся самый длинный. Числа записываются
+// по правилам locale, в тексте токена они приводятся к виду 1234.5.
+func tokenize(input string, symbols []string, locale Locale) ([]token, error) {
+	tokens := make([]token, 0, len(input))
+	runes := []rune(input)
+	// глубина скобок: с десятичной запятой ; внутри скобок разделяет аргументы
+	depth := 0
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || locale.isDecimal(runes, i):
+			start := i
+			var text string
+			i, text = scanNumber(runes, i, locale)
+			tokens = append(tokens, token{kind: tokNumber, text: text, span: Span{start, i}})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: string(runes[start:i]), span: Span{start, i}})
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", span: Span{i, i + 1}})
+			depth++
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", span: Span{i, i + 1}})
+			depth--
+			i++
+		case r == '=':
+			tokens = append(tokens, token{kind: tokAssign, text: "=", span: Span{i, i + 1}})
+			i++
+		case r == locale.ArgumentSeparator() && (r == ',' || depth > 0):
+			tokens = append(tokens, token{kind: tokComma, text: string(r), span: Span{i, i + 1}})
+			i++
+		case r == '[':
+			tokens = append(tokens, token{kind: tokLBracket, text: "[", span: Span{i, i + 1}})
+			depth++
+			i++
+		case r == ']':
+			tokens = append(tokens, token{kind: tokRBracket, text: "]", span: Span{i, i + 1}})
+			depth--
+			i++
+		case r == ';':
+			tokens = append(tokens, token{kind: tokSemicolon, text: ";", span: Span{i, i + 1}})
+			i++
+		case r == ',':
+			return nil, errorAt(Span{i, i + 1}, "unexpected character: , (arguments are separated by ;)")
+		default:
+			symbol := matchSymbol(runes[i:], symbols)
+			if symbol == "" {
+				return nil, errorAt(Span{i, i + 1}, "unexpected character: %c", r)
+			}
+			length := utf8.RuneCountInString(symbol)
+			tokens = append(tokens, token{kind: tokOperator, text: symbol, span: Span{i, i + length}})
+			i += length
+		}
+	}
+
+	return append(tokens, token{kind: tokEOF, span: Span{len(runes), len(runes) + 1}}), nil
+}
+
+func isAlnum(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func matchSymbol(runes []rune, symbols []string) string {
+	longest := ""
+	for _, symbol := range symbols {
+		if len(symbol) > len(longest) && hasPrefix(runes, symbol) {
+			longest = symbol
+		}
+	}
+	return longest
+}
+
+// hasPrefix - strings.HasPrefix для рун без перевода их в строку
+func hasPrefix(runes []rune, prefix string) bool {
+	i := 0
+	for _, r := range prefix {
+		if i >= len(runes) || runes[i] != r {
+			return false
+		}
+		i++
+	}
+	return true
+}
+
+// scanNumber возвращает индекс первой руны после числа, начинающегося с i,
+// и текст числа с десятичной точкой и без разделителей групп. Десятичные
+// разделители поглощаются жадно, чтобы "23.3.5" стало одним (некорректным)
+// токеном.
+func scanNumber(runes []rune, i int, locale Locale) (int, string) {
+	start := i
+	// 0xff, 0b1010, 0o17: буквы и цифры после префикса - одно число,
+	// неверные цифры вроде 0b12 найдёт разбор
+	if i+2 < len(runes) && runes[i] == '0' && strings.ContainsRune("xXbBoO", runes[i+1]) && isAlnum(runes[i+2]) {
+		i += 2
+		for i < len(runes) && isAlnum(runes[i]) {
+			i++
+		}
+		return i, string(runes[start:i])
+	}
+
+	var text strings.Builder
+	// цифр в текущей группе целой части; группы кончаются на десятичном разделителе
+	group, grouped, fraction := 0, false, false
+	for i < len(runes) {
+		switch r := runes[i]; {
+		case unicode.IsDigit(r):
+			text.WriteRune(r)
+			group++
+		case locale.isDecimal(runes, i):
+			text.WriteByte('.')
+			fraction = true
+		case locale.isGroupSeparator(r) && !fraction && isGroup(runes, i+1) && (group == 3 || !grouped && group < 3):
+			group, grouped = 0, true
+		default:
+			return scanExponent(runes, i, locale, &text)
+		}
+		i++
+	}
+	return i, text.String()
+}
+
+// isGroup сообщает, что с i идут ровно три цифры
+func isGroup(runes []rune, i int) bool {
+	if i+3 > len(runes) || i+3 < len(runes) && unicode.IsDigit(runes[i+3]) {
+		return false
+	}
+	for _, r := range runes[i : i+3] {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// scanExponent дописывает к числу экспоненту, если она начинается с i
+func scanExponent(runes []rune, i int, locale Locale, text *strings.Builder) (int, string) {
+	// экспоненциальная запись: 1e5, 2e+02, 5e-02
+	if i < len(runes) && runes[i] == 'e' {
+		j := i + 1
+		if j < len(runes) && (runes[j] == '+' || runes[j] == '-') {
+			j++
+		}
+		if j < len(runes) && unicode.IsDigit(runes[j]) {
+			for j < len(runes) && unicode.IsDigit(runes[j]) {
+				j++
+			}
+			text.WriteString(string(runes[i:j]))
+			i = j
+			// дробная часть экспоненты, как в 2e+02.0: нули отбрасываются,
+			// остальное делает запись некорректной, 10^0.5 не десятичное число
+			if i+1 < len(runes) && locale.isDecimal(runes, i) && unicode.IsDigit(runes[i+1]) {
+				j = i + 1
+				for j < len(runes) && unicode.IsDigit(runes[j]) {
+					j++
+				}
+				if fraction := string(runes[i+1 : j]); strings.Trim(fraction, "0") != "" {
+					text.WriteString("." + fraction)
+				}
+				i = j
+			}
+		}
+	}
+
+	return i, text.String()
+}
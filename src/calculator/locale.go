@@ -0,0 +1,73 @@
+package calculator
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Locale - как записываются числа во входных выражениях. Нулевое значение -
+// десятичная точка, аргументы через запятую, без разделителей групп.
+type Locale struct {
+	// DecimalComma - десятичный разделитель запятая: 3,5 + 1,25. Тогда
+	// аргументы функций и элементы списков разделяются точкой с запятой:
+	// max(1,5; 2)
+	DecimalComma bool
+	// GroupSeparators - символы, которыми можно разделять группы по три
+	// цифры в целой части числа: с "' " принимаются 1'000'000 и 1 000 000
+	GroupSeparators string
+}
+
+// locales - готовые настройки для флага -locale
+var locales = map[string]Locale{
+	"en": {},
+	"ru": {DecimalComma: true, GroupSeparators: " "},
+	"de": {DecimalComma: true, GroupSeparators: "."},
+	"ch": {GroupSeparators: "'"},
+}
+
+// ParseLocale возвращает настройки по названию: en, ru, de или ch
+func ParseLocale(name string) (Locale, error) {
+	if locale, ok := locales[name]; ok {
+		return locale, nil
+	}
+	return Locale{}, newError("unknown locale %q, expected en, ru, de or ch", name)
+}
+
+// DecimalSeparator возвращает десятичный разделитель
+func (l Locale) DecimalSeparator() rune {
+	if l.DecimalComma {
+		return ','
+	}
+	return '.'
+}
+
+// ArgumentSeparator возвращает разделитель аргументов функций и элементов списков
+func (l Locale) ArgumentSeparator() rune {
+	if l.DecimalComma {
+		return ';'
+	}
+	return ','
+}
+
+// isDecimal сообщает, что на i стоит десятичный разделитель. Запятая -
+// разделитель, только если за ней цифра: в "1,)" это ошибка, а не 1.
+func (l Locale) isDecimal(runes []rune, i int) bool {
+	if runes[i] != l.DecimalSeparator() {
+		return false
+	}
+	return !l.DecimalComma || i+1 < len(runes) && unicode.IsDigit(runes[i+1])
+}
+
+func (l Locale) isGroupSeparator(r rune) bool {
+	return strings.ContainsRune(l.GroupSeparators, r)
+}
+
+// validate проверяет, что разделители групп не спутать с другими символами
+func (l Locale) validate() error {
+	for _, r := range l.GroupSeparators {
+		if unicode.IsDigit(r) || unicode.IsLetter(r) || r == l.DecimalSeparator() || r == l.ArgumentSeparator() || r == ';' {
+			return newError("invalid group separator %q", r)
+		}
+	}
+	return nil
+}
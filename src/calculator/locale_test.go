@@ -0,0 +1,66 @@
+package calculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocale(t *testing.T) {
+	type CaseLocale struct {
+		expression string
+		locale     string
+		mode       Mode
+		result     string
+		err        string
+	}
+	cases := []CaseLocale{
+		// десятичная запятая, аргументы через точку с запятой
+		{expression: "3,5 + 1,25", locale: "ru", result: "4.75"},
+		{expression: "max(1,5; 2; 0,5)", locale: "ru", result: "2"},
+		{expression: "[1,5; 2,5]", locale: "ru", mode: Interval, result: "[1.5, 2.5]"},
+		{expression: "mean([1; 2]; 3)", locale: "ru", result: "2"},
+		{expression: "f(x; y) = x * y; f(1,5; 4)", locale: "ru", result: "6"},
+		{expression: "x = 0,5; x * 2", locale: "ru", result: "1"},
+		{expression: ",5 + 1", locale: "ru", result: "1.5"},
+		{expression: "1,5e3", locale: "ru", result: "1500"},
+		{expression: "1/3 + 0,5", locale: "ru", mode: Rational, result: "5/6"},
+		{expression: "max(1, 2)", locale: "ru", err: "error while parsing: 1:6: unexpected character: , (arguments are separated by ;)"},
+		{expression: "[1,5 2]", locale: "ru", mode: Interval, err: "error while parsing: 1:6: expected ], got 2"},
+		{expression: "1.5", locale: "ru", err: "error while parsing: 1:2: unexpected character: ."},
+
+		// разделители групп
+		{expression: "1 000 000 + 1", locale: "ru", mode: Rational, result: "1000001"},
+		{expression: "12 345,5 * 2", locale: "ru", result: "24691"},
+		{expression: "1.000.000,5", locale: "de", mode: Rational, result: "2000001/2"},
+		{expression: "1'000'000 / 4", locale: "ch", result: "250000"},
+		{expression: "max(1'000, 2)", locale: "ch", result: "1000"},
+		{expression: "0,000 001", locale: "ru", err: "error while parsing: 1:7: unexpected token: 001"},
+		{expression: "1234 567", locale: "ru", err: "error while parsing: 1:6: unexpected token: 567"},
+		{expression: "1 00", locale: "ru", err: "error while parsing: 1:3: unexpected token: 00"},
+		{expression: "1 0000", locale: "ru", err: "error while parsing: 1:3: unexpected token: 0000"},
+		{expression: "1.5", locale: "de", err: "error while parsing: 1:2: unexpected character: ."},
+		{expression: "1'5", locale: "ch", err: "error while parsing: 1:2: unexpected character: '"},
+
+		// en - как без локали
+		{expression: "max(1.5, 2)", locale: "en", result: "2"},
+		{expression: "1 000", locale: "en", err: "error while parsing: 1:3: unexpected token: 000"},
+	}
+
+	for _, c := range cases {
+		locale, err := ParseLocale(c.locale)
+		require.NoError(t, err)
+		result, err := Evaluate(c.expression, CalculatorConfig{Mode: c.mode, Locale: locale})
+		if c.err != "" {
+			require.EqualError(t, err, c.err, c.expression)
+			continue
+		}
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.result, result.String(), c.expression)
+	}
+
+	_, err := ParseLocale("fr")
+	require.EqualError(t, err, `unknown locale "fr", expected en, ru, de or ch`)
+	_, err = Evaluate("1", CalculatorConfig{Locale: Locale{GroupSeparators: ","}})
+	require.EqualError(t, err, `error while parsing: invalid group separator ','`)
+}
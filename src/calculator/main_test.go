@@ -1,8 +1,13 @@
 package calculator
 
 import (
+	"fmt"
+	"maps"
 	"math"
 	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -11,104 +16,262 @@ import (
 
 func TestTokenize(t *testing.T) {
 	type CaseTokenize struct {
-		expr   string
-		result []string
+		expr    string
+		result  []string
+		isError bool
 	}
 	cases := []CaseTokenize{
 		{expr: "1+2", result: []string{"1", "+", "2"}},
-		{expr: "-2   + 3.5", result: []string{"~", "2", "+", "3.5"}},
-		{expr: "2 * -23.3.5", result: []string{"2", "*", "~", "23.3.5"}},
+		{expr: "-2   + 3.5", result: []string{"-", "2", "+", "3.5"}},
+		{expr: "2 * -23.3.5", result: []string{"2", "*", "-", "23.3.5"}},
 		{expr: "2 *ab 5", result: []string{"2", "*", "ab", "5"}},
 		{expr: "2   4 / 5", result: []string{"2", "4", "/", "5"}},
-		{expr: "5e-02", result: []string{"5", "d", "100"}},
+		{expr: "5e-02", result: []string{"5e-02"}},
 		{expr: "e-02", result: []string{"e", "-", "02"}},
-		{expr: "2e+02.0", result: []string{"2", "m", "100"}},
+		{expr: "2e+02.0", result: []string{"2e+02"}},
+		{expr: "2e+0.5", result: []string{"2e+0.5"}},
 		{expr: "2^3", result: []string{"2", "^", "3"}},
-		{expr: "2e+02 +  28", result: []string{"2", "m", "100", "+", "28"}},
+		{expr: "2e+02 +  28", result: []string{"2e+02", "+", "28"}},
 		{expr: "2 e+ 2", result: []string{"2", "e", "+", "2"}},
 		{expr: "3 *(2+ 4)", result: []string{"3", "*", "(", "2", "+", "4", ")"}},
-		{expr: "sqrt(  cos(3  +e-20) ) ", result: []string{"q", "(", "c", "(", "3", "+", "e", "-", "20", ")", ")"}},
-		{expr: "e +tg (250) / pi", result: []string{"e", "+", "t", "(", "250", ")", "/", "pi"}},
+		{expr: "sqrt(  cos(3  +e-20) ) ", result: []string{"sqrt", "(", "cos", "(", "3", "+", "e", "-", "20", ")", ")"}},
+		{expr: "e +tg (250) / pi", result: []string{"e", "+", "tg", "(", "250", ")", "/", "pi"}},
 		{expr: "etg(2)", result: []string{"etg", "(", "2", ")"}},
-		{expr: "sqrt(ln(e))", result: []string{"q", "(", "l", "(", "e", ")", ")"}},
-		{expr: "3e+sqrt(4)", result: []string{"3", "e", "+", "q", "(", "4", ")"}},
+		{expr: "sqrt(ln(e))", result: []string{"sqrt", "(", "ln", "(", "e", ")", ")"}},
+		{expr: "3e+sqrt(4)", result: []string{"3", "e", "+", "sqrt", "(", "4", ")"}},
+
+		{expr: "2 $ 3", isError: true},
 	}
 
 	for _, c := range cases {
-		result := tokenize(c.expr)
+		tokens, err := tokenize(c.expr, defaultCalculator(CalculatorConfig{}).symbols(), Locale{})
+		if c.isError {
+			require.Error(t, err, c.expr)
+			continue
+		}
+		require.NoError(t, err, c.expr)
+
+		result := make([]string, 0, len(tokens))
+		for _, tok := range tokens {
+			if tok.kind != tokEOF {
+				result = append(result, tok.text)
+			}
+		}
 		if slices.Compare(result, c.result) != 0 {
 			t.Errorf("tokenize(%q) = %q, expected %q", c.expr, result, c.result)
 		}
 	}
 }
 
-func TestInfixToPostfix(t *testing.T) {
-	type CaseInfixToPostfix struct {
-		tokens  []string
-		result  []string
+func TestParse(t *testing.T) {
+	type CaseParse struct {
+		expr    string
+		result  string
 		isError bool
 	}
-	cases := []CaseInfixToPostfix{
-		{tokens: []string{"1", "+", "2"}, result: []string{"1", "2", "+"}, isError: false},
-		{tokens: []string{"~", "2", "+", "3.5"}, result: []string{"2", "~", "3.5", "+"}, isError: false},
-		{tokens: []string{"2", "4", "/", "5"}, result: []string{"2", "4", "5", "/"}, isError: false},
-		{tokens: []string{"3", "*", "(", "2", "+", "4", ")"}, result: []string{"3", "2", "4", "+", "*"}, isError: false},
-		{tokens: []string{"2", "^", "3"}, result: []string{"2", "3", "^"}, isError: false},
-		{tokens: []string{"q", "(", "c", "(", "3", "+", "e", "-", "20", ")", ")"}, result: []string{"3", "e", "+", "20", "-", "c", "q"}, isError: false},
-		{tokens: []string{"e", "+", "t", "(", "250", ")", "/", "pi"}, result: []string{"e", "250", "t", "pi", "/", "+"}, isError: false},
-		{tokens: []string{"q", "(", "l", "(", "e", ")", ")"}, result: []string{"e", "l", "q"}, isError: false},
+	cases := []CaseParse{
+		{expr: "1+2", result: "1 + 2", isError: false},
+		{expr: "-2 + 3.5", result: "-2 + 3.5", isError: false},
+		{expr: "3*(2+4)", result: "3 * (2 + 4)", isError: false},
+		{expr: "2^3", result: "2 ^ 3", isError: false},
+		{expr: "1-(2-3)", result: "1 - (2 - 3)", isError: false},
+		{expr: "(1-2)-3", result: "1 - 2 - 3", isError: false},
+		{expr: "-2^2", result: "-2 ^ 2", isError: false},
+		{expr: "(-2)^2", result: "(-2) ^ 2", isError: false},
+		{expr: "-(2+3)", result: "-(2 + 3)", isError: false},
+		{expr: "sqrt(cos(3+e-20))", result: "sqrt(cos(3 + e - 20))", isError: false},
+		{expr: "e+tg(250)/pi", result: "e + tg(250) / pi", isError: false},
+		{expr: "sqrt(ln(e))", result: "sqrt(ln(e))", isError: false},
+		{expr: "5e-02 * 2", result: "5e-02 * 2", isError: false},
+		{expr: "x = 3; y = x^2 + 1; y*2", result: "x = 3; y = x ^ 2 + 1; y * 2", isError: false},
+		{expr: "x = 3;", result: "x = 3", isError: false},
 
-		{tokens: []string{"2", "*", "~", "23.3.5"}, result: nil, isError: true},
-		{tokens: []string{"2", "*", "ab", "5"}, result: nil, isError: true},
-		{tokens: []string{"etg", "(", "2", ")"}, result: nil, isError: true},
+		{expr: "2 * -23.3.5", isError: true},
+		{expr: "2 *ab 5", isError: true},
+		{expr: "2   4 / 5", isError: true},
+		{expr: "etg(2)", result: "etg(2)", isError: false},
+		{expr: "sqrt 4", isError: true},
+		{expr: "(1+2", isError: true},
+		{expr: "1+2))", isError: true},
+		{expr: "1)+2", isError: true},
+		{expr: "1+", isError: true},
+		{expr: "", isError: true},
+		{expr: "pi = 3", isError: true},
+		{expr: "sin = 3", isError: true},
+		{expr: "x = ", isError: true},
+		{expr: "1 = 2", isError: true},
+		{expr: "x = 1;; 2", isError: true},
 	}
+
 	for _, c := range cases {
-		result, err := infixToPostfix(c.tokens)
+		tree, err := Parse(c.expr)
 		if c.isError && err == nil {
-			t.Errorf("infixToPostfix(%q), expected error", c.tokens)
+			t.Errorf("Parse(%q), expected error", c.expr)
 			continue
 		} else if !c.isError && err != nil {
-			t.Errorf("infixToPostfix(%q), unexpected error: %s", c.tokens, err)
+			t.Errorf("Parse(%q), unexpected error: %s", c.expr, err)
 			continue
 		}
 
-		if slices.Compare(result, c.result) != 0 {
-			t.Errorf("infixToPostfix(%q) = %q, expected %q", c.tokens, result, c.result)
+		if !c.isError && tree.String() != c.result {
+			t.Errorf("Parse(%q) = %q, expected %q", c.expr, tree.String(), c.result)
 		}
 	}
 }
 
-func TestEvaluatePostfix(t *testing.T) {
-	type CaseEvaluatePostfix struct {
-		tokens     []string
+func TestParseTree(t *testing.T) {
+	tree, err := Parse("-sqrt(2) * pi")
+	require.NoError(t, err)
+
+	require.Equal(t, &BinaryOp{
+		Span: Span{0, 13},
+		Op:   "*",
+		X: &UnaryOp{
+			Span: Span{0, 8},
+			Op:   "-",
+			X: &FuncCall{
+				Span: Span{1, 8},
+				Name: "sqrt",
+				Args: []Node{&NumberLit{Span: Span{6, 7}, Value: 2, Text: "2"}},
+			},
+		},
+		Y: &Constant{Span: Span{11, 13}, Name: "pi"},
+	}, tree)
+
+	var calls []string
+	Inspect(tree, func(n Node) bool {
+		if call, ok := n.(*FuncCall); ok {
+			calls = append(calls, call.Name)
+		}
+		return true
+	})
+	require.Equal(t, []string{"sqrt"}, calls)
+}
+
+// sexpr печатает дерево со всеми скобками, чтобы был виден порядок разбора
+func sexpr(node Node) string {
+	switch n := node.(type) {
+	case *UnaryOp:
+		return "(" + n.Op + " " + sexpr(n.X) + ")"
+	case *BinaryOp:
+		return "(" + n.Op + " " + sexpr(n.X) + " " + sexpr(n.Y) + ")"
+	case *FuncCall:
+		args := make([]string, 0, len(n.Args))
+		for _, arg := range n.Args {
+			args = append(args, sexpr(arg))
+		}
+		return "(" + strings.Join(append([]string{n.Name}, args...), " ") + ")"
+	}
+	return node.String()
+}
+
+func TestGrammarConformance(t *testing.T) {
+	type CaseGrammar struct {
+		expr   string
+		tree   string
+		result float64
+	}
+	cases := []CaseGrammar{
+		{expr: "1 - 2 - 3", tree: "(- (- 1 2) 3)", result: -4},
+		{expr: "1 + 2 * 3", tree: "(+ 1 (* 2 3))", result: 7},
+		{expr: "8 / 4 / 2", tree: "(/ (/ 8 4) 2)", result: 1},
+		{expr: "8 / 4 * 2", tree: "(* (/ 8 4) 2)", result: 4},
+		{expr: "2 ^ 3 ^ 2", tree: "(^ 2 (^ 3 2))", result: 512},
+		{expr: "(2 ^ 3) ^ 2", tree: "(^ (^ 2 3) 2)", result: 64},
+		{expr: "2 * 3 ^ 2", tree: "(* 2 (^ 3 2))", result: 18},
+		{expr: "-2 ^ 2", tree: "(- (^ 2 2))", result: -4},
+		{expr: "(-2) ^ 2", tree: "(^ (- 2) 2)", result: 4},
+		{expr: "2 ^ -2", tree: "(^ 2 (- 2))", result: 0.25},
+		{expr: "-2 ^ -2", tree: "(- (^ 2 (- 2)))", result: -0.25},
+		{expr: "2 ^ -1 ^ 2", tree: "(^ 2 (- (^ 1 2)))", result: 0.5},
+		{expr: "-2 * 3", tree: "(* (- 2) 3)", result: -6},
+		{expr: "2 * -3", tree: "(* 2 (- 3))", result: -6},
+		{expr: "--2", tree: "(- (- 2))", result: 2},
+		{expr: "1 - -2", tree: "(- 1 (- 2))", result: 3},
+		{expr: "-sqrt(4) ^ 2", tree: "(- (^ (sqrt 4) 2))", result: -4},
+		{expr: "sqrt(4) ^ 3 ^ 0", tree: "(^ (sqrt 4) (^ 3 0))", result: 2},
+		{expr: "max(1, 2) * -min(3, 4)", tree: "(* (max 1 2) (- (min 3 4)))", result: -6},
+	}
+
+	for _, c := range cases {
+		tree, err := Parse(c.expr)
+		require.NoError(t, err, c.expr)
+		require.Equal(t, c.tree, sexpr(tree), c.expr)
+
+		// печать и повторный разбор сохраняют структуру дерева
+		reparsed, err := Parse(tree.String())
+		require.NoError(t, err, tree.String())
+		require.Equal(t, c.tree, sexpr(reparsed), tree.String())
+
+		result, err := Calculate(c.expr, CalculatorConfig{AngleUnits: "radian"})
+		require.NoError(t, err, c.expr)
+		require.Equal(t, c.result, result, c.expr)
+	}
+}
+
+func TestErrorPosition(t *testing.T) {
+	type CaseErrorPosition struct {
+		expr   string
+		line   int
+		column int
+		caret  string
+	}
+	cases := []CaseErrorPosition{
+		{expr: "2 * ab + 5", line: 1, column: 5, caret: "2 * ab + 5\n    ^~"},
+		{expr: "2 * -23.3.5", line: 1, column: 6, caret: "2 * -23.3.5\n     ^~~~~~"},
+		{expr: "1 + (2 * 3", line: 1, column: 11, caret: "1 + (2 * 3\n          ^"},
+		{expr: "1 + 5/(2-2)", line: 1, column: 5, caret: "1 + 5/(2-2)\n    ^~~~~~"},
+		{expr: "1 +\nsqrt(-4) * 2", line: 2, column: 1, caret: "sqrt(-4) * 2\n^~~~~~~~"},
+		{expr: "ln(0) + π", line: 1, column: 9, caret: "ln(0) + π\n        ^"},
+		{expr: "1+2))", line: 1, column: 4, caret: "1+2))\n   ^"},
+	}
+
+	for _, c := range cases {
+		_, err := Calculate(c.expr, CalculatorConfig{AngleUnits: "radian"})
+		require.Error(t, err, c.expr)
+
+		var posErr *Error
+		require.ErrorAs(t, err, &posErr, c.expr)
+		require.Equal(t, c.line, posErr.Line, c.expr)
+		require.Equal(t, c.column, posErr.Column, c.expr)
+		require.Equal(t, c.caret, posErr.Caret(c.expr), c.expr)
+	}
+
+	_, err := Calculate("5/0", CalculatorConfig{AngleUnits: "radian"})
+	require.ErrorContains(t, err, "1:1: calculating division: division by zero")
+}
+
+func TestEval(t *testing.T) {
+	type CaseEval struct {
+		expr       string
 		result     float64
-		angleUnits string
+		angleUnits AngleUnit
 		isError    bool
 	}
-	cases := []CaseEvaluatePostfix{
-		{tokens: []string{"1", "2", "+"}, result: 3.0, angleUnits: "radians", isError: false},
-		{tokens: []string{"2", "~", "3.5", "-"}, result: -5.5, angleUnits: "radians", isError: false},
-		{tokens: []string{"3", "~", "4", "9", "*", "+", "20", "~", "0.5", "/", "-"}, result: 73, angleUnits: "radians", isError: false},
-		{tokens: []string{"2", "3", "^"}, result: 8, angleUnits: "radians", isError: false},
-		{tokens: []string{"e", "17", "+", "c", "q"}, result: 0.8036167306671608, angleUnits: "radians", isError: false},
-		{tokens: []string{"e", "250", "t", "pi", "/", "+"}, result: 1.4363579480746753, angleUnits: "radians", isError: false},
-		{tokens: []string{"e", "l", "q"}, result: 1, angleUnits: "radians", isError: false},
+	cases := []CaseEval{
+		{expr: "1 + 2", result: 3.0, angleUnits: "radians", isError: false},
+		{expr: "-2 - 3.5", result: -5.5, angleUnits: "radians", isError: false},
+		{expr: "-3 + 4*9 - -20/0.5", result: 73, angleUnits: "radians", isError: false},
+		{expr: "2^3", result: 8, angleUnits: "radians", isError: false},
+		{expr: "sqrt(cos(e + 17))", result: 0.8036167306671608, angleUnits: "radians", isError: false},
+		{expr: "e + tg(250)/pi", result: 1.4363579480746753, angleUnits: "radians", isError: false},
+		{expr: "sqrt(ln(e))", result: 1, angleUnits: "radians", isError: false},
 
-		{tokens: []string{"5", "0", "/"}, result: 0, angleUnits: "radians", isError: true},
-		{tokens: []string{"1", "2", "+", "5"}, result: 0, angleUnits: "radians", isError: true},
-		{tokens: []string{"~"}, result: 0, angleUnits: "radians", isError: true},
-		{tokens: []string{"+"}, result: 0, angleUnits: "radians", isError: true},
-		{tokens: []string{"pi", "2", "/", "t"}, result: 0, angleUnits: "radians", isError: true},
-		{tokens: []string{"pi", "g"}, result: 0, angleUnits: "radians", isError: true},
+		{expr: "5/0", result: 0, angleUnits: "radians", isError: true},
+		{expr: "tg(pi/2)", result: 0, angleUnits: "radians", isError: true},
+		{expr: "ctg(pi)", result: 0, angleUnits: "radians", isError: true},
 	}
 
 	for _, c := range cases {
-		result, err := evaluatePostfix(c.tokens, c.angleUnits)
+		program, err := Compile(c.expr, CalculatorConfig{AngleUnits: c.angleUnits})
+		require.NoError(t, err, c.expr)
+
+		result, err := program.Eval(nil)
 		if c.isError && err == nil {
-			t.Errorf("evaluatePostfix(%q), expected error", c.tokens)
+			t.Errorf("Eval(%q), expected error", c.expr)
 			continue
 		} else if !c.isError && err != nil {
-			t.Errorf("evaluatePostfix(%q), unexpected error: %s", c.tokens, err)
+			t.Errorf("Eval(%q), unexpected error: %s", c.expr, err)
 			continue
 		}
 
@@ -120,12 +283,14 @@ func TestCalculate(t *testing.T) {
 	type CaseCalculate struct {
 		expression string
 		result     float64
-		angleUnits string
+		angleUnits AngleUnit
 		isError    bool
 	}
 	cases := []CaseCalculate{
 		{expression: "1+2", result: 3, angleUnits: "radian", isError: false},
 		{expression: "3.375e+09^(1/3)", result: 1500, angleUnits: "radian", isError: false},
+		{expression: "2e+02.0 + 1", result: 201, angleUnits: "radian", isError: false},
+		{expression: "2e+0.5", angleUnits: "radian", isError: true},
 		{expression: "sqrt(cos(17+e) ) ", result: 0.8036167306671608, angleUnits: "radian", isError: false},
 		{expression: "e+tg(250)/pi", result: 1.4363579480746753, angleUnits: "radian", isError: false},
 		{expression: "e+tg(250)/pi", result: 3.592831053138179, angleUnits: "degree", isError: false},
@@ -160,33 +325,183 @@ func TestCalculate(t *testing.T) {
 	}
 }
 
-func TestBench(t *testing.T) {
-	type CaseCalculate struct {
+func TestCalculateWithVars(t *testing.T) {
+	type CaseCalculateWithVars struct {
 		expression string
+		vars       map[string]float64
 		result     float64
-		isError    bool
+		err        string
 	}
-	cases := []CaseCalculate{
-		{expression: "1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1",
-			result: 250, isError: false},
-		{expression: "1000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000+1000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000+1000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000+1000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
-			result: 4e+249, isError: false},
-		{expression: "1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1",
-			result: 1026, isError: false},
-		{expression: "2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2",
-			result: 9.134385233318143e+46, isError: false},
-		{expression: "((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((1+1)))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))",
-			result: 2, isError: false},
-		{expression: "1+2*3-4/5+6*7-sqrt(1+1)^32+5+6+7+8+9+10+11+12+13+14+15+16+17+18+19+20+21+22+23+24+25+26+27+28+29+30+31+32+33+34+35+36+37+38+39+40+41+42+43+44+45+46+47+48+49+50+51+52+53+54+55+56+(4*3+e*3e+21+4*3+e+4*3+e+4*3+e+4*3+e)/2*3-4/5+6*7-sqrt(1+1)^32+5+6+7+8+9+10+11+12+13+14+15+16+17+18+19+20+21+22+23+24+25+26+27+28+29+30+31+32+33+34+35+36+37+38+39+40+41+42+43+44+45+46+47+48+49+50+51+52+53+54+5",
-			result: 1.2232268228065703e+22, isError: false},
-		{expression: "sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(256))))))))))))))))))))+(2^100)/((3+4*5)^10-sqrt(10000))/(3+4*5)^8*3-4/5+6*7-sqrt(1+1)^32",
-			result: 1.1067549351347717e+06, isError: false},
+	cases := []CaseCalculateWithVars{
+		{expression: "x = 3; y = x^2 + 1; y*2", result: 20},
+		{expression: "x = 3", result: 3},
+		{expression: "r^2 * pi", vars: map[string]float64{"r": 2}, result: 4 * math.Pi},
+		{expression: "x = x + 1; x * 2", vars: map[string]float64{"x": 1}, result: 4},
+		{expression: "a = 2; b = a; a = 5; a + b", result: 7},
+		{expression: "rate = 0.2; price * (1 + rate)", vars: map[string]float64{"price": 100}, result: 120},
+
+		{expression: "y = x + 1; y", err: "1:5: undefined variable x"},
+		{expression: "x = 1; z * x", err: "1:8: undefined variable z"},
+		{expression: "x = 0; 1 / x", err: "1:8: calculating division: division by zero"},
+	}
+
+	for _, c := range cases {
+		vars := maps.Clone(c.vars)
+		result, err := CalculateWithVars(c.expression, CalculatorConfig{AngleUnits: "radian"}, vars)
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
 
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.result, result, c.expression)
+		require.Equal(t, c.vars, vars, c.expression)
 	}
+}
+
+func TestMultiArgFunctions(t *testing.T) {
+	type CaseMultiArg struct {
+		expression string
+		result     float64
+		angleUnits AngleUnit
+		err        string
+	}
+	cases := []CaseMultiArg{
+		{expression: "log(2, 8)", result: 3, angleUnits: "radian"},
+		{expression: "max(1, 2, 3)", result: 3, angleUnits: "radian"},
+		{expression: "min(4)", result: 4, angleUnits: "radian"},
+		{expression: "atan2(1, 1) * 4", result: math.Pi, angleUnits: "radian"},
+		{expression: "atan2(1, 0)", result: 90, angleUnits: "degree"},
+		{expression: "a = 9; b = 2; c = 16; max(min(a, b), sqrt(c))", result: 4, angleUnits: "radian"},
+		{expression: "max(-1, -(2), 1 - 3)", result: -1, angleUnits: "radian"},
+		{expression: "f(x, y) = max(x, y, 0); f(-1, -2)", result: 0, angleUnits: "radian"},
+
+		{expression: "max()", err: "function max expects at least 1 argument, got 0", angleUnits: "radian"},
+		{expression: "atan2(1, 2, 3)", err: "function atan2 expects 2 arguments, got 3", angleUnits: "radian"},
+		{expression: "log(1, 8)", err: "calculating log: logarithm base must be positive and not equal to 1", angleUnits: "radian"},
+		{expression: "log(2, -8)", err: "calculating log: logarithm of non-positive number", angleUnits: "radian"},
+		{expression: "max(1, )", err: "1:8: unexpected token: )", angleUnits: "radian"},
+		{expression: "1+2))", err: "error while parsing: 1:4: unexpected token: )", angleUnits: "radian"},
+		{expression: "max(1 2)", err: "1:7: expected ), got 2", angleUnits: "radian"},
+	}
+
+	for _, c := range cases {
+		result, err := Calculate(c.expression, CalculatorConfig{AngleUnits: c.angleUnits})
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+
+		require.NoError(t, err, c.expression)
+		require.InDelta(t, c.result, result, 1e-12, c.expression)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	calc := NewCalculator(CalculatorConfig{AngleUnits: "radian"})
+
+	require.NoError(t, calc.RegisterFunction("hypot", 2, func(args ...float64) (float64, error) {
+		return math.Hypot(args[0], args[1]), nil
+	}))
+	require.NoError(t, calc.RegisterFunction("answer", 0, func(args ...float64) (float64, error) {
+		return 42, nil
+	}))
+	require.NoError(t, calc.RegisterVariadicFunction("avg", 1, Unlimited, func(args ...float64) (float64, error) {
+		sum := 0.0
+		for _, arg := range args {
+			sum += arg
+		}
+		return sum / float64(len(args)), nil
+	}))
+	require.NoError(t, calc.RegisterOperator("%", 6, LeftAssoc, func(a, b float64) (float64, error) {
+		if b == 0 {
+			return 0, fmt.Errorf("modulo by zero")
+		}
+		return math.Mod(a, b), nil
+	}))
+	require.NoError(t, calc.RegisterOperator("**", 8, RightAssoc, Pow))
+	require.NoError(t, calc.RegisterOperator("rem", 6, LeftAssoc, func(a, b float64) (float64, error) {
+		return math.Mod(a, b), nil
+	}))
+
+	type CaseRegistry struct {
+		expression string
+		result     float64
+		err        string
+	}
+	cases := []CaseRegistry{
+		{expression: "hypot(3, 4)", result: 5},
+		{expression: "answer() / 2", result: 21},
+		{expression: "avg(1, 2, 3, 4)", result: 2.5},
+		{expression: "10 % 4 + 1", result: 3},
+		{expression: "2 ** 3 ** 2", result: 512},
+		{expression: "2 ^ 3 ^ 2", result: 512},
+		{expression: "2 ** 3 ^ 2", result: 512},
+		{expression: "17 rem 5 * 2", result: 4},
+		{expression: "f(x) = x rem 3; f(10)", result: 1},
+
+		{expression: "5 % 0", err: "1:1: calculating operator %: modulo by zero"},
+		{expression: "hypot(3)", err: "function hypot expects 2 arguments, got 1"},
+		{expression: "rem = 3", err: "cannot assign to operator rem"},
+		{expression: "2 + rem", err: "unexpected operator rem"},
+	}
+
+	for _, c := range cases {
+		result, err := calc.Calculate(c.expression)
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.result, result, c.expression)
+	}
+
+	// регистрация не влияет на другие калькуляторы
+	_, err := Calculate("hypot(3, 4)", CalculatorConfig{AngleUnits: "radian"})
+	require.ErrorContains(t, err, "unknown function hypot")
+	_, err = NewCalculator(CalculatorConfig{}).Calculate("10 % 4")
+	require.ErrorContains(t, err, "unexpected character: %")
+
+	require.Error(t, calc.RegisterFunction("pi", 1, nil))
+	require.Error(t, calc.RegisterFunction("2x", 1, nil))
+	require.Error(t, calc.RegisterFunction("rem", 1, nil))
+	require.Error(t, calc.RegisterVariadicFunction("bad", 3, 2, nil))
+	require.Error(t, calc.RegisterOperator("(", 1, LeftAssoc, Add))
+	require.Error(t, calc.RegisterOperator("@", 0, LeftAssoc, Add))
+	require.Error(t, calc.RegisterOperator("sin", 1, LeftAssoc, Add))
+
+	tree, err := calc.Parse("(1 % 2) * 3 - -4")
+	require.NoError(t, err)
+	require.Equal(t, "(1 % 2) * 3 - -4", tree.String())
+}
+
+var benchCases = []struct {
+	expression string
+	result     float64
+	isError    bool
+}{
+	{expression: "1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1 + 1",
+		result: 250, isError: false},
+	{expression: "1000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000+1000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000+1000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000+1000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		result: 4e+249, isError: false},
+	{expression: "1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1+1",
+		result: 1026, isError: false},
+	{expression: "2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2*2",
+		result: 9.134385233318143e+46, isError: false},
+	{expression: "((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((((1+1))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))))",
+		result: 2, isError: false},
+	{expression: "1+2*3-4/5+6*7-sqrt(1+1)^32+5+6+7+8+9+10+11+12+13+14+15+16+17+18+19+20+21+22+23+24+25+26+27+28+29+30+31+32+33+34+35+36+37+38+39+40+41+42+43+44+45+46+47+48+49+50+51+52+53+54+55+56+(4*3+e*3e+21+4*3+e+4*3+e+4*3+e+4*3+e)/2*3-4/5+6*7-sqrt(1+1)^32+5+6+7+8+9+10+11+12+13+14+15+16+17+18+19+20+21+22+23+24+25+26+27+28+29+30+31+32+33+34+35+36+37+38+39+40+41+42+43+44+45+46+47+48+49+50+51+52+53+54+5",
+		result: 1.2232268228065703e+22, isError: false},
+	{expression: "sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(sqrt(256))))))))))))))))))))+(2^100)/((3+4*5)^10-sqrt(10000))/(3+4*5)^8*3-4/5+6*7-sqrt(1+1)^32",
+		result: 1.1067549351347717e+06, isError: false},
+}
+
+func TestBench(t *testing.T) {
 	// "(((...(1+1)...)))" (100 уровней вложенности)
 	
 
-	for _, c := range cases {
+	for _, c := range benchCases {
 		start := time.Now()
 		result, err := Calculate(c.expression, CalculatorConfig{AngleUnits: "radian"})
 		duration := time.Since(start)
@@ -202,6 +517,94 @@ func TestBench(t *testing.T) {
 	}
 }
 
+func TestProgram(t *testing.T) {
+	program, err := Compile("x^2 + 3*x*y - sqrt(y)", CalculatorConfig{AngleUnits: "radian"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"x", "y"}, program.Variables())
+
+	result, err := program.Eval(map[string]float64{"x": 2, "y": 4})
+	require.NoError(t, err)
+	require.Equal(t, 26.0, result)
+
+	stack := make([]float64, program.StackSize())
+	result, err = program.EvalInto(stack, []float64{1, 9})
+	require.NoError(t, err)
+	require.Equal(t, 25.0, result)
+
+	_, err = program.Eval(map[string]float64{"x": 2})
+	require.ErrorContains(t, err, "1:11: undefined variable y")
+
+	_, err = program.Eval(map[string]float64{"x": 2, "y": -1})
+	require.ErrorContains(t, err, "calculating sqrt: square root of negative number")
+
+	_, err = program.EvalInto(stack[:1], []float64{1, 9})
+	require.Error(t, err)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = program.EvalInto(stack, []float64{1, 9})
+	})
+	require.Zero(t, allocs)
+}
+
+func TestProgramConcurrent(t *testing.T) {
+	program, err := Compile("sin(x) * 2 + x", CalculatorConfig{AngleUnits: "degree"})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				result, err := program.Eval(map[string]float64{"x": 90})
+				if err != nil || result != 92 {
+					t.Errorf("Eval() = %v, %v, expected 92", result, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkCalculate(b *testing.B) {
+	for _, c := range benchCases[:4] {
+		b.Run(strconv.Itoa(len(c.expression)), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = Calculate(c.expression, CalculatorConfig{AngleUnits: "radian"})
+			}
+		})
+	}
+}
+
+func BenchmarkProgramEval(b *testing.B) {
+	for _, c := range benchCases[:4] {
+		program, err := Compile(c.expression, CalculatorConfig{AngleUnits: "radian"})
+		require.NoError(b, err)
+
+		b.Run(strconv.Itoa(len(c.expression)), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = program.Eval(nil)
+			}
+		})
+	}
+}
+
+func BenchmarkProgramEvalInto(b *testing.B) {
+	for _, c := range benchCases[:4] {
+		program, err := Compile(c.expression, CalculatorConfig{AngleUnits: "radian"})
+		require.NoError(b, err)
+		stack := make([]float64, program.StackSize())
+
+		b.Run(strconv.Itoa(len(c.expression)), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = program.EvalInto(stack, nil)
+			}
+		})
+	}
+}
+
 func TestAdd(t *testing.T) {
 	type CaseAdd struct {
 		a, b    float64
@@ -0,0 +1,144 @@
+package calculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMathFunctions(t *testing.T) {
+	type CaseMath struct {
+		expression string
+		result     float64
+		err        string
+	}
+	cases := []CaseMath{
+		{expression: "log(1000)", result: 3},
+		{expression: "log10(0.001)", result: -3},
+		{expression: "log2(1024)", result: 10},
+		{expression: "log(2, 8)", result: 3},
+		{expression: "log(10, 1e-5)", result: -5},
+		{expression: "abs(-2.5)", result: 2.5},
+		{expression: "floor(-2.5)", result: -3},
+		{expression: "ceil(-2.5)", result: -2},
+		{expression: "trunc(-2.5)", result: -2},
+		{expression: "sign(-0.1) + sign(0) + sign(7)", result: 0},
+		{expression: "round(2.5)", result: 3},
+		{expression: "round(-2.5)", result: -3},
+		{expression: "round(3.14159, 2)", result: 3.14},
+		{expression: "round(0.125, 2)", result: 0.13},
+		// 2.675 в float64 чуть меньше 2.675
+		{expression: "round(2.675, 2)", result: 2.67},
+		{expression: "round(1250, -2)", result: 1300},
+		{expression: "mod(7, 3)", result: 1},
+		{expression: "mod(-7, 3)", result: 2},
+		{expression: "mod(7, -3)", result: -2},
+		{expression: "mod(5.5, 2)", result: 1.5},
+		{expression: "gcd(12, 18)", result: 6},
+		{expression: "gcd(12, -18, 8)", result: 2},
+		{expression: "gcd(0, 5)", result: 5},
+		{expression: "lcm(4, 6, 10)", result: 60},
+		{expression: "lcm(4, 0)", result: 0},
+		{expression: "factorial(0)", result: 1},
+		{expression: "5!", result: 120},
+		{expression: "3!!", result: 720},
+		{expression: "-3!", result: -6},
+		{expression: "2^3!", result: 64},
+		{expression: "(1 + 2)! / 3!", result: 1},
+		{expression: "170!", result: 7.257415615307994e306},
+		{expression: "nCr(5, 2)", result: 10},
+		{expression: "nCr(52, 5)", result: 2598960},
+		{expression: "nCr(3, 5)", result: 0},
+		{expression: "nCr(100, 98)", result: 4950},
+		{expression: "nPr(5, 2)", result: 20},
+		{expression: "nPr(5, 0)", result: 1},
+
+		{expression: "log(0)", err: "1:1: calculating log: logarithm of non-positive number"},
+		{expression: "log10(-1)", err: "calculating log10: logarithm of non-positive number"},
+		{expression: "log2(0)", err: "calculating log2: logarithm of non-positive number"},
+		{expression: "log(1, 5)", err: "logarithm base must be positive and not equal to 1"},
+		{expression: "log(1, 2, 3)", err: "function log expects 1 to 2 arguments, got 3"},
+		{expression: "round(1.5, 0.5)", err: "calculating round: number of digits must be an integer"},
+		{expression: "round(1.7e308, -308)", err: "calculating round: got overflow"},
+		{expression: "mod(1, 0)", err: "calculating mod: modulo by zero"},
+		{expression: "gcd(1.5, 3)", err: "calculating gcd: gcd of non-integer number"},
+		{expression: "lcm(2, 0.5)", err: "calculating lcm: lcm of non-integer number"},
+		{expression: "gcd(4)", err: "function gcd expects at least 2 arguments, got 1"},
+		{expression: "(-1)!", err: "1:1: calculating factorial: factorial of negative number"},
+		{expression: "2.5!", err: "calculating factorial: factorial of non-integer number"},
+		{expression: "171!", err: "calculating factorial: got overflow"},
+		{expression: "nCr(-1, 2)", err: "calculating nCr: nCr of negative number"},
+		{expression: "nPr(5, 1.5)", err: "calculating nPr: nPr of non-integer number"},
+		{expression: "nCr(5000, 2500)", err: "calculating nCr: got overflow"},
+		{expression: "nPr(200, 180)", err: "calculating nPr: got overflow"},
+		{expression: "!5", err: "error while parsing: 1:1: unexpected token: !"},
+	}
+
+	for _, c := range cases {
+		result, err := Calculate(c.expression, CalculatorConfig{})
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+		require.NoError(t, err, c.expression)
+		require.InDelta(t, c.result, result, 1e-12*max(1, c.result), c.expression)
+	}
+}
+
+func TestMathFunctionsModes(t *testing.T) {
+	type CaseModes struct {
+		expression string
+		config     CalculatorConfig
+		result     string
+		err        string
+	}
+	rational := CalculatorConfig{Mode: Rational}
+	integer := CalculatorConfig{Mode: Integer}
+	precise := CalculatorConfig{Precision: 200}
+	cases := []CaseModes{
+		{expression: "floor(-5/2) + ceil(7/3)", config: rational, result: "0"},
+		{expression: "round(2.675, 2)", config: rational, result: "67/25"},
+		{expression: "round(-1/3, 3)", config: rational, result: "-333/1000"},
+		{expression: "mod(-7/2, 2)", config: rational, result: "1/2"},
+		{expression: "abs(-2/3) * sign(-2/3)", config: rational, result: "-2/3"},
+		{expression: "30!", config: rational, result: "265252859812191058636308480000000"},
+		{expression: "nCr(100, 50)", config: rational, result: "100891344545564193334812497256"},
+		{expression: "gcd(2^64, 6^40)", config: rational, result: "1099511627776"},
+		{expression: "log2(8)", config: rational, err: "calculating log2: result is not exact in rational mode"},
+		{expression: "20001!", config: rational, err: "factorial argument is greater than 10000"},
+		{expression: "round(1, 100000)", config: rational, err: "number of digits is too large"},
+
+		{expression: "20!", config: integer, result: "2432902008176640000"},
+		{expression: "mod(-7, 3) + gcd(12, 18)", config: integer, result: "8"},
+		{expression: "round(1250, -2)", config: integer, result: "1300"},
+		{expression: "nPr(10, 3)", config: integer, result: "720"},
+		{expression: "21!", config: CalculatorConfig{Mode: Integer, OverflowError: true}, err: "integer overflow: 51090942171709440000 does not fit in int64"},
+
+		{expression: "30! + 0.5", config: precise, result: "265252859812191058636308480000000.5"},
+		{expression: "round(pi, 20)", config: precise, result: "3.14159265358979323846"},
+		{expression: "log(1000) + log2(1024)", config: precise, result: "13"},
+
+		{expression: "abs(3 + 4i)", config: CalculatorConfig{Mode: Complex}, result: "5"},
+		{expression: "sign(-3i)", config: CalculatorConfig{Mode: Complex}, result: "-i"},
+		{expression: "abs([-2, 1])", config: CalculatorConfig{Mode: Interval}, result: "[0, 2]"},
+		{expression: "floor([1.5, 2.5])", config: CalculatorConfig{Mode: Interval}, result: "[1, 2]"},
+		{expression: "abs(-2±0.5)", config: CalculatorConfig{Mode: Uncertainty}, result: "2.00±0.50"},
+		{expression: "abs(-3 km) to m", config: CalculatorConfig{Mode: Units}, result: "3000 m"},
+	}
+
+	for _, c := range cases {
+		result, err := Evaluate(c.expression, c.config)
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.result, result.String(), c.expression)
+	}
+}
+
+func TestFactorialParse(t *testing.T) {
+	tree, err := Parse("-n! + 2^3!")
+	require.NoError(t, err)
+	require.Equal(t, "-factorial(n) + 2 ^ factorial(3)", tree.String())
+}
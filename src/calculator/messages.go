@@ -0,0 +1,281 @@
+package calculator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Language - язык сообщений об ошибках
+type Language string
+
+const (
+	English Language = "en"
+	Russian Language = "ru"
+)
+
+// ParseLanguage проверяет название языка
+func ParseLanguage(name string) (Language, error) {
+	switch lang := Language(name); lang {
+	case English, Russian:
+		return lang, nil
+	}
+	return "", newError("unknown language %q, expected en or ru", name)
+}
+
+// russianMessages - каталог переводов: шаблон fmt из конструктора ошибки
+// и русский перевод с теми же глаголами в том же порядке. Полноту каталога
+// проверяет TestCatalogComplete.
+var russianMessages = map[string]string{
+	// main и REPL
+	"Error":                                  "Ошибка",
+	"%d of %d lines failed":                  "ошибки в %d строках из %d",
+	"base is only supported in integer mode": "основание поддерживается только в режиме integer",
+	"unknown output format %q":               "неизвестный формат вывода %q",
+	"unknown mode or angle unit %q":          "неизвестный режим или единица углов %q",
+	"invalid precision %q":                   "неверная точность %q",
+	"unknown command :%s, type :help for the list of commands": "неизвестная команда :%s, список команд - :help",
+
+	// стадии и места вычисления
+	"error while parsing":     "ошибка разбора",
+	"error while calculating": "ошибка вычисления",
+	"calculating %s":          "вычисление %s",
+	"calculating negation":    "вычисление отрицания",
+	"calculating bitwise not": "вычисление побитового не",
+	"number %s":               "число %s",
+	"constant %s":             "константа %s",
+	"angle %s":                "угол %s",
+	"unit %s":                 "единица %s",
+	"list":                    "список",
+	"conversion":              "преобразование",
+
+	// разбор
+	"unexpected end of expression":                           "неожиданный конец выражения",
+	"unexpected token: %s":                                   "неожиданный токен: %s",
+	"unexpected character: %c":                               "неожиданный символ: %c",
+	"unexpected character: , (arguments are separated by ;)": "неожиданный символ: , (аргументы разделяются ;)",
+	"unexpected operator %s":                                 "неожиданный оператор %s",
+	"invalid number: %s":                                     "неверное число: %s",
+	"number out of range: %s":                                "число вне допустимого диапазона: %s",
+	"expected %s, got %s":                                    "ожидалось %s, получено %s",
+	"expected (, got %s":                                     "ожидалась (, получено %s",
+	"expected unit, got %s":                                  "ожидалась единица, получено %s",
+	"expected parameter name, got %s":                        "ожидалось имя параметра, получено %s",
+	"cannot assign to constant %s":                           "нельзя присвоить значение константе %s",
+	"cannot assign to function %s":                           "нельзя присвоить значение функции %s",
+	"cannot assign to operator %s":                           "нельзя присвоить значение оператору %s",
+	"cannot assign to unit %s":                               "нельзя присвоить значение единице %s",
+	"cannot redefine constant %s":                            "нельзя переопределить константу %s",
+	"cannot redefine built-in function %s":                   "нельзя переопределить встроенную функцию %s",
+	"cannot redefine operator %s":                            "нельзя переопределить оператор %s",
+	"cannot use constant %s as parameter":                    "константа %s не может быть параметром",
+	"cannot use unit %s as parameter":                        "единица %s не может быть параметром",
+	"duplicate parameter %s":                                 "повторяющийся параметр %s",
+	"recursive definition of %s: %s":                         "рекурсивное определение %s: %s",
+	"undefined variable %s":                                  "неизвестная переменная %s",
+	"unknown function %s":                                    "неизвестная функция %s",
+	"unknown operator %s":                                    "неизвестный оператор %s",
+	"%w: expression has no value":                            "%s: у выражения нет значения",
+	"call depth limit exceeded in %s":                        "превышена глубина вызовов в %s",
+
+	// число аргументов; по-русски после числа - "аргументов: N", чтобы не склонять
+	"function %s expects 1 argument, got %d":            "функция %s ожидает 1 аргумент, получено %d",
+	"function %s expects %d arguments, got %d":          "функция %s ожидает аргументов: %d, получено %d",
+	"function %s expects at least 1 argument, got %d":   "функция %s ожидает хотя бы 1 аргумент, получено %d",
+	"function %s expects at least %d arguments, got %d": "функция %s ожидает аргументов: не меньше %d, получено %d",
+	"function %s expects %d to %d arguments, got %d":    "функция %s ожидает аргументов: от %d до %d, получено %d",
+
+	// operations.go и общие ошибки вычислений
+	"got overflow":                                       "переполнение",
+	"division by zero":                                   "деление на ноль",
+	"modulo by zero":                                     "остаток от деления на ноль",
+	"negative number to a non-integer power":             "отрицательное число в нецелой степени",
+	"square root of negative number":                     "квадратный корень из отрицательного числа",
+	"logarithm base must be positive and not equal to 1": "основание логарифма должно быть положительным и не равным 1",
+	"logarithm base must be non-zero and not equal to 1": "основание логарифма должно быть ненулевым и не равным 1",
+	"logarithm of non-positive number":                   "логарифм неположительного числа",
+	"logarithm of zero":                                  "логарифм нуля",
+	"natural logarithm of non-positive number":           "натуральный логарифм неположительного числа",
+	"natural logarithm of zero":                          "натуральный логарифм нуля",
+	"tangent of pi/2 * k":                                "тангенс от pi/2 * k",
+	"cotangent of pi * k":                                "котангенс от pi * k",
+	"%s of value outside [-1,1]":                         "%s от значения вне [-1,1]",
+	"hyperbolic cotangent of zero":                       "гиперболический котангенс нуля",
+	"arcosh of value less than 1":                        "arcosh от значения меньше 1",
+	"artanh of value outside (-1,1)":                     "artanh от значения вне (-1,1)",
+	"arcoth of value inside [-1,1]":                      "arcoth от значения внутри [-1,1]",
+	"artanh of ±1":                                       "artanh от ±1",
+	"arcoth of ±1":                                       "arcoth от ±1",
+	"%s of ±i":                                           "%s от ±i",
+	"number of digits must be an integer":                "число знаков должно быть целым",
+	"number of digits must be an exact number":           "число знаков должно быть точным",
+	"number of digits is too large":                      "слишком большое число знаков",
+	"gcd of non-integer number":                          "НОД нецелого числа",
+	"lcm of non-integer number":                          "НОК нецелого числа",
+	"factorial of negative number":                       "факториал отрицательного числа",
+	"factorial of non-integer number":                    "факториал нецелого числа",
+	"factorial argument is greater than %d":              "аргумент факториала больше %d",
+	"%s of negative number":                              "%s от отрицательного числа",
+	"%s of non-integer number":                           "%s от нецелого числа",
+	"%s needs more than %d factors":                      "для %s нужно больше %d множителей",
+	"percentile must be between 0 and 100":               "перцентиль должен быть от 0 до 100",
+	"geometric mean of non-positive number":              "среднее геометрическое неположительного числа",
+	"harmonic mean of negative number":                   "среднее гармоническое отрицательного числа",
+	"result %v is not representable":                     "результат %v непредставим",
+	"%w: variable %s: %v is not representable":           "%s: переменная %s: %v непредставима",
+	"%w: result %s does not fit in float64":              "%s: результат %s не помещается в float64",
+
+	// режимы
+	"unsupported operation":                                   "неподдерживаемая операция",
+	"operation is not supported":                              "операция не поддерживается",
+	"%w for %s":                                               "%s для %s",
+	"result is not exact in rational mode":                    "результат неточен в режиме rational",
+	"result is not a number":                                  "результат не число",
+	"values with tolerance need interval or uncertainty mode": "значения с допуском вычисляются в режимах interval и uncertainty",
+	"list literals are not supported in %s mode":              "списки не поддерживаются в режиме %s",
+	"units are not supported in %s mode":                      "единицы не поддерживаются в режиме %s",
+	"integer mode needs integer numbers, got %s":              "в режиме integer нужны целые числа, получено %s",
+	"integer overflow":                                        "целочисленное переполнение",
+	"%w: %s does not fit in %s":                               "%s: %s не помещается в %s",
+	"%w: %s^%s does not fit in %s":                            "%s: %s^%s не помещается в %s",
+	"result is not an integer":                                "результат не целый",
+	"not an integer":                                          "не целое число",
+	"negative exponent in integer mode":                       "отрицательный показатель степени в режиме integer",
+	"bitwise operations need integer operands":                "побитовые операции выполняются только над целыми числами",
+	"shift count %s out of range for %s":                      "величина сдвига %s вне диапазона для %s",
+	"shift count %d out of range for int64":                   "величина сдвига %d вне диапазона для int64",
+	"unknown base %q":                                         "неизвестное основание %q",
+	"unsupported base %d, expected 2, 8, 10 or 16":            "неподдерживаемое основание %d, ожидается 2, 8, 10 или 16",
+	"unsupported integer width %d, expected 8, 16, 32 or 64":  "неподдерживаемая разрядность %d, ожидается 8, 16, 32 или 64",
+	"lower bound is greater than upper bound":                 "нижняя граница больше верхней",
+	"interval needs 2 bounds, got %d":                         "у интервала должно быть 2 границы, получено %d",
+	"interval contains zero, a pole of hyperbolic cotangent":  "интервал содержит ноль, полюс гиперболического котангенса",
+	"interval contains a pole of tangent at pi/2 * k":         "интервал содержит полюс тангенса в pi/2 * k",
+	"interval contains a pole of cotangent at pi * k":         "интервал содержит полюс котангенса в pi * k",
+	"tolerance must be non-negative":                          "допуск должен быть неотрицательным",
+	"tolerance must be an exact number":                       "допуск должен быть точным числом",
+	"uncertainty is undefined at this point":                  "неопределённость в этой точке не определена",
+	"uncertain exponent needs a positive base":                "для неточного показателя степени основание должно быть положительным",
+
+	// единицы
+	"value has no unit":                             "у значения нет единицы",
+	"%s has no unit to convert to %s":               "у %s нет единицы для преобразования в %s",
+	"unknown unit %s":                               "неизвестная единица %s",
+	"cannot convert %s to %s":                       "нельзя преобразовать %s в %s",
+	"incompatible units: %s and %s":                 "несовместимые единицы: %s и %s",
+	"exponent must be dimensionless, got %s":        "показатель степени должен быть безразмерным, получено %s",
+	"argument must be dimensionless, got %s":        "аргумент должен быть безразмерным, получено %s",
+	"percentile must be dimensionless, got %s":      "перцентиль должен быть безразмерным, получено %s",
+	"%s to a fractional power":                      "%s в дробной степени",
+	"unit power is too large":                       "слишком большая степень единицы",
+	"expected name = definition":                    "ожидалось имя = определение",
+	"invalid prefix name %q":                        "недопустимое имя приставки %q",
+	"prefix %s must be a dimensionless number":      "приставка %s должна быть безразмерным числом",
+	"invalid unit name %q":                          "недопустимое имя единицы %q",
+	"to is reserved for unit conversion":            "to зарезервировано для преобразования единиц",
+	"invalid dimension name %q":                     "недопустимое имя размерности %q",
+	"too many base units, at most %d are supported": "слишком много основных единиц, поддерживается не больше %d",
+	"unit %s is zero":                               "единица %s равна нулю",
+	"definition has no value":                       "у определения нет значения",
+
+	// регистрация функций и операторов
+	"invalid arity %d for function %s":             "неверное число аргументов %d у функции %s",
+	"invalid arity %d..%d for function %s":         "неверное число аргументов %d..%d у функции %s",
+	"invalid function name %q":                     "недопустимое имя функции %q",
+	"%s is already an operator":                    "%s уже оператор",
+	"function %s has no implementation":            "у функции %s нет реализации",
+	"invalid operator symbol %q":                   "недопустимый символ оператора %q",
+	"%s is already a function":                     "%s уже функция",
+	"operator precedence must be positive, got %d": "приоритет оператора должен быть положительным, получено %d",
+	"invalid associativity for operator %s":        "неверная ассоциативность оператора %s",
+	"operator %s has no implementation":            "у оператора %s нет реализации",
+	"stack too small: need %d, got %d":             "стек слишком мал: нужно %d, получено %d",
+	"expected %d variables, got %d":                "ожидалось переменных: %d, получено %d",
+
+	// настройки
+	"unknown mode %q": "неизвестный режим %q",
+	"unknown angle unit %q, expected radian, degree, gradian or turn": "неизвестная единица углов %q, ожидается radian, degree, gradian или turn",
+	"unknown language %q, expected en or ru":                          "неизвестный язык %q, ожидается en или ru",
+	"unknown locale %q, expected en, ru, de or ch":                    "неизвестная локаль %q, ожидается en, ru, de или ch",
+	"invalid group separator %q":                                      "недопустимый разделитель групп %q",
+
+	// formatter
+	"unknown notation %q":                     "неизвестная запись %q",
+	"unknown notation %v":                     "неизвестная запись %v",
+	"invalid number of digits %d":             "неверное число знаков %d",
+	"invalid number of significant digits %d": "неверное число значащих цифр %d",
+}
+
+// russianNames - названия операций в родительном падеже для "вычисление %s"
+var russianNames = map[string]string{
+	"addition":       "сложения",
+	"substraction":   "вычитания",
+	"multiplication": "умножения",
+	"division":       "деления",
+	"power":          "степени",
+	"tolerance":      "допуска",
+	"bitwise or":     "побитового или",
+	"bitwise xor":    "побитового исключающего или",
+	"bitwise and":    "побитового и",
+	"left shift":     "сдвига влево",
+	"right shift":    "сдвига вправо",
+	"bitwise not":    "побитового не",
+	"conversion":     "преобразования",
+	"operator %s":    "оператора %s",
+}
+
+var (
+	// catalogs - переводы шаблонов сообщений по языкам
+	catalogs = map[Language]map[string]string{Russian: russianMessages}
+	// nameCatalogs - переводы названий операций по языкам
+	nameCatalogs = map[Language]map[string]string{Russian: russianNames}
+)
+
+// Translate возвращает текст по шаблону format на языке lang с подстановкой
+// args. Шаблон без перевода остаётся английским.
+func Translate(format string, lang Language, args ...any) string {
+	return message{format, args}.translate(catalogs[lang], lang)
+}
+
+// Localize возвращает текст ошибки на языке lang. Ошибки калькулятора
+// переводятся по шаблону и аргументам, остальные ищутся в каталоге целиком.
+func Localize(err error, lang Language) string {
+	if lang == English {
+		return err.Error()
+	}
+	switch e := err.(type) {
+	case *Error:
+		text := fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.msg.translate(catalogs[lang], lang))
+		if e.Err != nil {
+			text += ": " + Localize(e.Err, lang)
+		}
+		return text
+	case *messageError:
+		return e.msg.translate(catalogs[lang], lang)
+	}
+	if text, ok := catalogs[lang][err.Error()]; ok {
+		return text
+	}
+	return err.Error()
+}
+
+// translate подставляет аргументы в перевод шаблона. Ошибки среди аргументов
+// переводятся целиком, вложенные сообщения - это названия операций.
+func (m message) translate(catalog map[string]string, lang Language) string {
+	format, ok := catalog[m.format]
+	if !ok {
+		format = m.format
+	}
+	args := make([]any, len(m.args))
+	for i, arg := range m.args {
+		switch arg := arg.(type) {
+		case error:
+			args[i] = Localize(arg, lang)
+		case message:
+			args[i] = arg.translate(nameCatalogs[lang], lang)
+		default:
+			args[i] = arg
+		}
+	}
+	// ошибки уже стали текстом, а Sprintf не понимает %w
+	return fmt.Sprintf(strings.ReplaceAll(format, "%w", "%v"), args...)
+}
@@ -0,0 +1,253 @@
+package calculator
+
+import (
+	"errors"
+	"go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalize(t *testing.T) {
+	type CaseLocalize struct {
+		expression string
+		mode       Mode
+		english    string
+		russian    string
+	}
+	cases := []CaseLocalize{
+		{
+			expression: "1 / 0",
+			english:    "error while calculating: 1:1: calculating division: division by zero",
+			russian:    "ошибка вычисления: 1:1: вычисление деления: деление на ноль",
+		},
+		{
+			expression: "sqrt(-1)",
+			english:    "error while calculating: 1:1: calculating sqrt: square root of negative number",
+			russian:    "ошибка вычисления: 1:1: вычисление sqrt: квадратный корень из отрицательного числа",
+		},
+		{
+			expression: "tan(pi / 2)",
+			english:    "error while calculating: 1:1: calculating tan: tangent of pi/2 * k",
+			russian:    "ошибка вычисления: 1:1: вычисление tan: тангенс от pi/2 * k",
+		},
+		{
+			expression: "nCr(-1, 2)",
+			english:    "error while calculating: 1:1: calculating nCr: nCr of negative number",
+			russian:    "ошибка вычисления: 1:1: вычисление nCr: nCr от отрицательного числа",
+		},
+		{
+			expression: "5 % 0",
+			english:    "error while parsing: 1:3: unexpected character: %",
+			russian:    "ошибка разбора: 1:3: неожиданный символ: %",
+		},
+		{
+			expression: "2 +",
+			english:    "error while parsing: 1:4: unexpected end of expression",
+			russian:    "ошибка разбора: 1:4: неожиданный конец выражения",
+		},
+		{
+			expression: "max(1 2)",
+			english:    "error while parsing: 1:7: expected ), got 2",
+			russian:    "ошибка разбора: 1:7: ожидалось ), получено 2",
+		},
+		{
+			expression: "y + 1",
+			english:    "error while calculating: 1:1: undefined variable y",
+			russian:    "ошибка вычисления: 1:1: неизвестная переменная y",
+		},
+		{
+			expression: "sin(1)",
+			mode:       Rational,
+			english:    "error while calculating: 1:1: calculating sin: result is not exact in rational mode",
+			russian:    "ошибка вычисления: 1:1: вычисление sin: результат неточен в режиме rational",
+		},
+		{
+			expression: "2.5",
+			mode:       Integer,
+			english:    "error while calculating: 1:1: integer mode needs integer numbers, got 2.5",
+			russian:    "ошибка вычисления: 1:1: в режиме integer нужны целые числа, получено 2.5",
+		},
+		{
+			expression: "sin(1, 2)",
+			english:    "error while parsing: 1:1: function sin expects 1 argument, got 2",
+			russian:    "ошибка разбора: 1:1: функция sin ожидает 1 аргумент, получено 2",
+		},
+		{
+			expression: "f(x) = f(x); f(1)",
+			english:    "error while parsing: 1:1: recursive definition of f: f -> f",
+			russian:    "ошибка разбора: 1:1: рекурсивное определение f: f -> f",
+		},
+		{
+			expression: "[1, 2] + 1",
+			mode:       Rational,
+			english:    "error while calculating: 1:1: list literals are not supported in rational mode",
+			russian:    "ошибка вычисления: 1:1: списки не поддерживаются в режиме rational",
+		},
+		{
+			expression: "1 / 0",
+			mode:       Rational,
+			english:    "error while calculating: 1:1: calculating division: division by zero",
+			russian:    "ошибка вычисления: 1:1: вычисление деления: деление на ноль",
+		},
+		{
+			expression: "harmean(-1, 2)",
+			mode:       Rational,
+			english:    "error while calculating: 1:1: calculating harmean: harmonic mean of negative number",
+			russian:    "ошибка вычисления: 1:1: вычисление harmean: среднее гармоническое отрицательного числа",
+		},
+		{
+			expression: "5 >> 70",
+			mode:       Integer,
+			english:    "error while calculating: 1:1: calculating right shift: shift count 70 out of range for int64",
+			russian:    "ошибка вычисления: 1:1: вычисление сдвига вправо: величина сдвига 70 вне диапазона для int64",
+		},
+	}
+
+	for _, c := range cases {
+		_, err := Evaluate(c.expression, CalculatorConfig{Mode: c.mode})
+		require.Error(t, err, c.expression)
+		require.Equal(t, c.english, Localize(err, English), c.expression)
+		require.Equal(t, c.russian, Localize(err, Russian), c.expression)
+	}
+
+	_, err := ParseMode("hex")
+	require.Equal(t, `неизвестный режим "hex"`, Localize(err, Russian))
+	_, err = ParseAngleUnit("grad")
+	require.Equal(t, `неизвестная единица углов "grad", ожидается radian, degree, gradian или turn`, Localize(err, Russian))
+	require.Equal(t, "ошибки в 2 строках из 10", Translate("%d of %d lines failed", Russian, 2, 10))
+	require.Equal(t, "2 of 10 lines failed", Translate("%d of %d lines failed", English, 2, 10))
+
+	calc := NewCalculator(CalculatorConfig{})
+	require.NoError(t, calc.RegisterOperator("%", 6, LeftAssoc, func(a, b float64) (float64, error) {
+		return 0, errors.New("modulo by zero")
+	}))
+	_, err = calc.Evaluate("5 % 0")
+	require.Equal(t, "ошибка вычисления: 1:1: вычисление оператора %: остаток от деления на ноль", Localize(err, Russian))
+
+	lang, err := ParseLanguage("ru")
+	require.NoError(t, err)
+	require.Equal(t, Russian, lang)
+	_, err = ParseLanguage("de")
+	require.EqualError(t, err, `unknown language "de", expected en or ru`)
+}
+
+// TestCatalogComplete проверяет по исходникам пакета, REPL, formatter и main, что у
+// каждого шаблона сообщения и названия операции есть русский перевод
+// с теми же аргументами, а в каталоге нет лишних шаблонов
+func TestCatalogComplete(t *testing.T) {
+	messages, names := map[string]bool{}, map[string]bool{}
+	literal := func(expr ast.Expr) {
+		if lit, ok := expr.(*ast.BasicLit); ok && lit.Kind == gotoken.STRING {
+			text, err := strconv.Unquote(lit.Value)
+			require.NoError(t, err)
+			messages[text] = true
+		}
+	}
+	// в пакете шаблоны передаются в конструкторы ошибок, в остальных -
+	// в Translate и Errorf
+	formatArgs := map[string]int{"errorAt": 1, "kindErrorAt": 2, "wrapErrorAt": 2, "newError": 0, "New": 0, "Translate": 0, "Errorf": 0}
+	scan := func(pattern string, all bool) {
+		paths, err := filepath.Glob(pattern)
+		require.NoError(t, err)
+		for _, path := range paths {
+			if strings.HasSuffix(path, "_test.go") {
+				continue
+			}
+			file, err := goparser.ParseFile(gotoken.NewFileSet(), path, nil, 0)
+			require.NoError(t, err)
+			ast.Inspect(file, func(node ast.Node) bool {
+				switch n := node.(type) {
+				case *ast.CallExpr:
+					var fn string
+					switch f := n.Fun.(type) {
+					case *ast.Ident:
+						fn = f.Name
+					case *ast.SelectorExpr:
+						fn = f.Sel.Name
+					}
+					if i, ok := formatArgs[fn]; ok && (all || fn == "Translate" || fn == "Errorf") && i < len(n.Args) {
+						literal(n.Args[i])
+					}
+					if fn == "registerBuiltinOperator" {
+						if lit, ok := n.Args[len(n.Args)-1].(*ast.BasicLit); ok {
+							names[lit.Value[1:len(lit.Value)-1]] = true
+						}
+					}
+				case *ast.KeyValueExpr:
+					// message в поле name - название операции
+					if key, ok := n.Key.(*ast.Ident); ok && key.Name == "name" {
+						if lit, ok := n.Value.(*ast.CompositeLit); ok && isMessage(lit) {
+							format := lit.Elts[0]
+							if kv, ok := format.(*ast.KeyValueExpr); ok {
+								format = kv.Value
+							}
+							if lit, ok := format.(*ast.BasicLit); ok {
+								names[lit.Value[1:len(lit.Value)-1]] = true
+							}
+							return false
+						}
+					}
+				case *ast.CompositeLit:
+					if isMessage(n) && len(n.Elts) > 0 {
+						literal(n.Elts[0])
+					}
+				}
+				return true
+			})
+		}
+	}
+	scan("*.go", true)
+	scan("../repl/*.go", false)
+	scan("../formatter/*.go", false)
+	scan("../main.go", false)
+
+	// шаблоны без слов, вроде "%w: %w", переводить не нужно
+	word := regexp.MustCompile(`[A-Za-z]{2,}`)
+	for format := range messages {
+		if !word.MatchString(verb.ReplaceAllString(format, "")) {
+			delete(messages, format)
+		}
+	}
+	delete(names, "%s")
+
+	for _, c := range []struct {
+		used    map[string]bool
+		catalog map[string]string
+	}{{messages, russianMessages}, {names, russianNames}} {
+		for format := range c.used {
+			translation, ok := c.catalog[format]
+			if assert.True(t, ok, "no translation for %q", format) {
+				assert.Equal(t, verbs(format), verbs(translation), format)
+			}
+		}
+		for format := range c.catalog {
+			assert.True(t, c.used[format], "unused translation for %q", format)
+		}
+	}
+}
+
+var verb = regexp.MustCompile(`%[a-z]`)
+
+// verbs возвращает глаголы шаблона; %w и %v печатаются так же, как %s
+func verbs(format string) []string {
+	found := verb.FindAllString(format, -1)
+	for i, v := range found {
+		if v == "%w" || v == "%v" {
+			found[i] = "%s"
+		}
+	}
+	return found
+}
+
+func isMessage(lit *ast.CompositeLit) bool {
+	typ, ok := lit.Type.(*ast.Ident)
+	return ok && typ.Name == "message"
+}
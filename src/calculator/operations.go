@@ -1,18 +1,35 @@
 package calculator
 
 import (
-	"fmt"
+	"errors"
 	"math"
+	"math/big"
 )
 
+// Ошибки областей определения, общие для float64 и остальных режимов
+var (
+	errLogBase     = errors.New("logarithm base must be positive and not equal to 1")
+	errLogDomain   = errors.New("logarithm of non-positive number")
+	errSqrtDomain  = errors.New("square root of negative number")
+	errLnDomain    = errors.New("natural logarithm of non-positive number")
+	errAcoshDomain = errors.New("arcosh of value less than 1")
+	errAtanhDomain = errors.New("artanh of value outside (-1,1)")
+	errAcothDomain = errors.New("arcoth of value inside [-1,1]")
+)
+
+// outsideUnitError - ошибка arcsin или arccos от значения вне [-1,1]
+func outsideUnitError(name string) error {
+	return newError("%s of value outside [-1,1]", name)
+}
+
 func Add(a, b float64) (float64, error) {
 	if b > 0 {
 		if a > math.MaxFloat64-b {
-			return 0, fmt.Errorf("got overflow")
+			return 0, errOverflow
 		}
 	} else {
 		if a < -math.MaxFloat64-b {
-			return 0, fmt.Errorf("got overflow")
+			return 0, errOverflow
 		}
 	}
 	return a + b, nil
@@ -24,39 +41,56 @@ func Sub(a, b float64) (float64, error) {
 
 func Mul(a, b float64) (float64, error) {
 	if math.Abs(a) > math.MaxFloat64/math.Abs(b) {
-		return 0, fmt.Errorf("got overflow")
+		return 0, errOverflow
 	}
 	return a * b, nil
 }
 
 func Div(a, b float64) (float64, error) {
 	if b == 0 {
-		return 0, fmt.Errorf("division by zero")
+		return 0, errDivisionByZero
 	}
 	return Mul(a, 1/b)
 }
 
 func Pow(a, b float64) (float64, error) {
 	if b > 1 && a > 1 && b > Log(a, math.MaxFloat64) {
-		return 0, fmt.Errorf("got overflow")
+		return 0, errOverflow
 	}
 	return math.Pow(a, b), nil
 }
 
 func Log(base, x float64) float64 {
+	// у Log2 и Log10 степени основания точные: log(10, 1000) = 3
+	switch base {
+	case 2:
+		return math.Log2(x)
+	case 10:
+		return math.Log10(x)
+	}
 	return math.Log(x) / math.Log(base)
 }
 
+func LogBase(base, x float64) (float64, error) {
+	if base <= 0 || base == 1 {
+		return 0, errLogBase
+	}
+	if x <= 0 {
+		return 0, errLogDomain
+	}
+	return Log(base, x), nil
+}
+
 func Sqrt(x float64) (float64, error) {
 	if x < 0 {
-		return 0, fmt.Errorf("square root of negative number")
+		return 0, errSqrtDomain
 	}
 	return math.Sqrt(x), nil
 }
 
 func Ln(x float64) (float64, error) {
 	if x <= 0 {
-		return 0, fmt.Errorf("natural logarithm of non-positive number")
+		return 0, errLnDomain
 	}
 	return math.Log(x), nil
 }
@@ -71,22 +105,303 @@ func Cos(x float64) float64 {
 
 func Tg(x float64) (float64, error) {
 	if _, frac := math.Modf(x / (math.Pi/2)); frac == 0 {
-		return 0, fmt.Errorf("tangent of pi/2 * k")
+		return 0, newError("tangent of pi/2 * k")
 	}
 	return math.Tan(x), nil
 }
 
 func Cot(x float64) (float64, error) {
 	if _, frac := math.Modf(x / math.Pi); frac == 0  {
-		return 0, fmt.Errorf("cotangent of pi * k")
+		return 0, newError("cotangent of pi * k")
 	}
 	return 1 / math.Tan(x), nil
 }
 
 func Exp(x float64) (float64, error) {
 	if x > math.Log(math.MaxFloat64) {
-		return 0, fmt.Errorf("got overflow")
+		return 0, errOverflow
 	}
 	return math.Exp(x), nil
 }
 
+func Atan2(y, x float64) float64 {
+	return math.Atan2(y, x)
+}
+
+func Max(args ...float64) float64 {
+	result := args[0]
+	for _, arg := range args[1:] {
+		result = math.Max(result, arg)
+	}
+	return result
+}
+
+func Min(args ...float64) float64 {
+	result := args[0]
+	for _, arg := range args[1:] {
+		result = math.Min(result, arg)
+	}
+	return result
+}
+
+func Asin(x float64) (float64, error) {
+	if x < -1 || x > 1 {
+		return 0, outsideUnitError("arcsin")
+	}
+	return math.Asin(x), nil
+}
+
+func Acos(x float64) (float64, error) {
+	if x < -1 || x > 1 {
+		return 0, outsideUnitError("arccos")
+	}
+	return math.Acos(x), nil
+}
+
+func Atan(x float64) float64 {
+	return math.Atan(x)
+}
+
+// Acot - арккотангенс со значениями в (0, pi)
+func Acot(x float64) float64 {
+	return math.Pi/2 - math.Atan(x)
+}
+
+func Sinh(x float64) (float64, error) {
+	result := math.Sinh(x)
+	if math.IsInf(result, 0) {
+		return 0, errOverflow
+	}
+	return result, nil
+}
+
+func Cosh(x float64) (float64, error) {
+	result := math.Cosh(x)
+	if math.IsInf(result, 0) {
+		return 0, errOverflow
+	}
+	return result, nil
+}
+
+func Tanh(x float64) float64 {
+	return math.Tanh(x)
+}
+
+func Coth(x float64) (float64, error) {
+	if x == 0 {
+		return 0, newError("hyperbolic cotangent of zero")
+	}
+	return 1 / math.Tanh(x), nil
+}
+
+func Asinh(x float64) float64 {
+	return math.Asinh(x)
+}
+
+func Acosh(x float64) (float64, error) {
+	if x < 1 {
+		return 0, errAcoshDomain
+	}
+	return math.Acosh(x), nil
+}
+
+func Atanh(x float64) (float64, error) {
+	if x <= -1 || x >= 1 {
+		return 0, errAtanhDomain
+	}
+	return math.Atanh(x), nil
+}
+
+func Acoth(x float64) (float64, error) {
+	if x >= -1 && x <= 1 {
+		return 0, errAcothDomain
+	}
+	return math.Atanh(1 / x), nil
+}
+
+// Deg переводит угол из радиан в градусы
+func Deg(x float64) float64 {
+	return convertAngle(x, Radian, Degree)
+}
+
+// Rad переводит угол из градусов в радианы
+func Rad(x float64) float64 {
+	return convertAngle(x, Degree, Radian)
+}
+
+func Log10(x float64) (float64, error) {
+	if x <= 0 {
+		return 0, errLogDomain
+	}
+	return math.Log10(x), nil
+}
+
+func Log2(x float64) (float64, error) {
+	if x <= 0 {
+		return 0, errLogDomain
+	}
+	return math.Log2(x), nil
+}
+
+func Abs(x float64) float64 {
+	return math.Abs(x)
+}
+
+func Floor(x float64) float64 {
+	return math.Floor(x)
+}
+
+func Ceil(x float64) float64 {
+	return math.Ceil(x)
+}
+
+func Trunc(x float64) float64 {
+	return math.Trunc(x)
+}
+
+// Sign возвращает -1, 0 или 1
+func Sign(x float64) float64 {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
+
+// Round округляет до digits знаков после запятой, половины - от нуля;
+// при отрицательном digits - до десятков, сотен и т.д. Округляется точное
+// двоичное значение x: round(2.675, 2) = 2.67, потому что 2.675 в float64
+// чуть меньше 2.675.
+func Round(x, digits float64) (float64, error) {
+	if digits != math.Trunc(digits) {
+		return 0, newError("number of digits must be an integer")
+	}
+	// у float64 не больше 1074 знаков после запятой и 309 до неё
+	if digits > 1074 || math.IsInf(x, 0) {
+		return x, nil
+	}
+	if digits < -309 {
+		return 0, nil
+	}
+	rounded, err := ratRound(new(big.Rat).SetFloat64(x), big.NewRat(int64(digits), 1))
+	if err != nil {
+		return 0, err
+	}
+	result, _ := rounded.Float64()
+	if math.IsInf(result, 0) {
+		return 0, errOverflow
+	}
+	return result, nil
+}
+
+// Mod - остаток от деления со знаком делителя: mod(-7, 3) = 2
+func Mod(a, b float64) (float64, error) {
+	if b == 0 {
+		return 0, newError("modulo by zero")
+	}
+	r := math.Mod(a, b)
+	if r != 0 && (r < 0) != (b < 0) {
+		r += b
+	}
+	return r, nil
+}
+
+func Gcd(args ...float64) (float64, error) {
+	var result float64
+	for _, arg := range args {
+		if arg != math.Trunc(arg) || math.IsInf(arg, 0) {
+			return 0, newError("gcd of non-integer number")
+		}
+		result = gcd(result, math.Abs(arg))
+	}
+	return result, nil
+}
+
+func Lcm(args ...float64) (float64, error) {
+	result := 1.0
+	for _, arg := range args {
+		if arg != math.Trunc(arg) || math.IsInf(arg, 0) {
+			return 0, newError("lcm of non-integer number")
+		}
+		if arg == 0 {
+			return 0, nil
+		}
+		var err error
+		if result, err = Mul(result/gcd(result, math.Abs(arg)), math.Abs(arg)); err != nil {
+			return 0, err
+		}
+	}
+	return result, nil
+}
+
+// gcd - алгоритм Евклида для неотрицательных целых float64
+func gcd(a, b float64) float64 {
+	for b != 0 {
+		a, b = b, math.Mod(a, b)
+	}
+	return a
+}
+
+// Factorial считается точно в big.Int и округляется до float64
+func Factorial(n float64) (float64, error) {
+	if n < 0 {
+		return 0, newError("factorial of negative number")
+	}
+	if n != math.Trunc(n) {
+		return 0, newError("factorial of non-integer number")
+	}
+	if n > 170 {
+		return 0, errOverflow
+	}
+	return intToFloat(new(big.Int).MulRange(1, int64(n)))
+}
+
+// NCr - число сочетаний из n по k
+func NCr(n, k float64) (float64, error) {
+	if err := checkCombination("nCr", n, k); err != nil {
+		return 0, err
+	}
+	if k > n {
+		return 0, nil
+	}
+	// C(n, k) >= 2^min(k, n-k), при min больше 1024 это переполнение
+	if k = math.Min(k, n-k); k > 1024 || n >= 1<<63 {
+		return 0, errOverflow
+	}
+	return intToFloat(new(big.Int).Binomial(int64(n), int64(k)))
+}
+
+// NPr - число размещений из n по k
+func NPr(n, k float64) (float64, error) {
+	if err := checkCombination("nPr", n, k); err != nil {
+		return 0, err
+	}
+	if k > n {
+		return 0, nil
+	}
+	// P(n, k) >= k!, а 171! не помещается в float64
+	if k > 170 || n >= 1<<63 {
+		return 0, errOverflow
+	}
+	return intToFloat(new(big.Int).MulRange(int64(n-k)+1, int64(n)))
+}
+
+func checkCombination(name string, n, k float64) error {
+	if n < 0 || k < 0 {
+		return newError("%s of negative number", name)
+	}
+	if n != math.Trunc(n) || k != math.Trunc(k) || math.IsInf(n, 0) {
+		return newError("%s of non-integer number", name)
+	}
+	return nil
+}
+
+func intToFloat(x *big.Int) (float64, error) {
+	result, _ := new(big.Float).SetInt(x).Float64()
+	if math.IsInf(result, 0) {
+		return 0, errOverflow
+	}
+	return result, nil
+}
@@ -0,0 +1,624 @@
+package calculator
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// Грамматика выражений (EBNF):
+//
+//	program    = statement { ";" statement } [ ";" ] .
+//	statement  = funcdef | assignment | expr .
+//	funcdef    = ident "(" [ ident { "," ident } ] ")" "=" expr .
+//	assignment = ident "=" expr .
+//	expr       = unary { binop unary } { ( "to" | "in" ) target } .
+//	unary      = ( "-" | "~" ) expr<7> | postfix .
+//	postfix    = primary { "!" } .
+//	primary    = number | quantity | constant | unit | variable | call | list | "(" expr ")" .
+//	number     = digits [ "." digits ] [ "e" [ "+" | "-" ] digits [ "." zeros ] ] [ "i" | angle ]
+//	           | ( "0x" | "0b" | "0o" ) digits .
+//	angle      = "°" | "deg" | "rad" | "grad" | "turn" .
+//	quantity   = number unit { unit } .
+//	unit       = ident [ "^" [ "-" ] digits ] .
+//	target     = units { ( "*" | "/" ) units } .
+//	units      = unit { unit } | "(" target ")" [ "^" [ "-" ] digits ] .
+//	call       = ident "(" [ expr { "," expr } ] ")" .
+//	list       = "[" [ expr { "," expr } ] "]" .
+//
+// Бинарные операторы разбираются по приоритету (больше - сильнее):
+//
+//	приоритет  операторы  ассоциативность
+//	1          |          левая
+//	2          xor        левая
+//	3          &          левая
+//	4          << >>      левая:  1 << 2 + 1 = 1 << (2+1)
+//	5          + -        левая:  1-2-3 = (1-2)-3
+//	6          * /        левая:  8/4/2 = (8/4)/2
+//	7          унарные    -       -2*3 = (-2)*3, ~a & b = (~a) & b
+//	           - и ~
+//	8          ^          правая: 2^3^2 = 2^(3^2)
+//	9          ±          левая:  2±0.1^2 = (2±0.1)^2
+//
+// Единицы измерения и перевод to/in есть только в режиме Units. Единица
+// после числа связывается с ним сильнее любого оператора: 5 km / 2 h =
+// (5 km) / (2 h), 2 m^2 - два квадратных метра. Перевод to слабее всех
+// операторов (приоритет 0): 3 ft + 2 in to cm = (3 ft + 2 in) to cm. Слово in после числа -
+// дюйм, если дальше не идёт операнд: 5 in - дюймы, 5 in cm - перевод.
+//
+// Побитовые операторы и унарный ~ есть только в режиме Integer; как в C и
+// Python, они слабее арифметических. Операторы из Calculator.RegisterOperator
+// встают в эту же таблицу с любым положительным приоритетом.
+//
+// Факториал ! связывается с операндом сильнее всех операторов: -3! = -(3!),
+// 2^3! = 2^(3!). В дереве он становится вызовом factorial.
+//
+// Операнд унарного минуса - выражение из операторов с приоритетом не ниже 7,
+// поэтому -2^2 = -(2^2) = -4, а 2^-2 = 2^(-2).
+const unaryPrecedence = 7
+
+// приоритет перевода единиц to, он слабее всех бинарных операторов
+const conversionPrecedence = 0
+
+// выражение целиком разбирается с самым слабым приоритетом
+const lowestPrecedence = conversionPrecedence
+
+// единицы углов, которые пишутся вплотную к числу: 30°, 0.5rad
+var angleSuffixes = map[string]AngleUnit{
+	"°":    Degree,
+	"deg":  Degree,
+	"rad":  Radian,
+	"grad": Gradian,
+	"turn": Turn,
+}
+
+// angleSuffix - запись единицы угла после числа
+func angleSuffix(unit AngleUnit) string {
+	switch unit {
+	case Degree:
+		return "°"
+	case Gradian:
+		return "grad"
+	case Turn:
+		return "turn"
+	}
+	return "rad"
+}
+
+var constants = map[string]bool{
+	"pi": true,
+	"e":  true,
+}
+
+// isConstant проверяет, что имя - константа; мнимая единица i есть только
+// в режиме Complex
+func (c *Calculator) isConstant(name string) bool {
+	return constants[name] || name == "i" && c.config.Mode == Complex
+}
+
+type parser struct {
+	calc   *Calculator
+	tokens []token
+	pos    int
+}
+
+// Parse разбирает выражение со встроенными функциями и операторами в
+// абстрактное синтаксическое дерево. Ошибки возвращаются как *Error
+// с позицией в выражении.
+func Parse(expression string) (Node, error) {
+	return defaultCalculator(CalculatorConfig{}).Parse(expression)
+}
+
+func (c *Calculator) parse(expression string) (Node, error) {
+	if err := c.config.Locale.validate(); err != nil {
+		return nil, err
+	}
+	tokens, err := tokenize(expression, c.symbols(), c.config.Locale)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{calc: c, tokens: tokens}
+	var stmts []Node
+	for {
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+
+		if p.peek().kind != tokSemicolon {
+			break
+		}
+		p.next()
+		if p.peek().kind == tokEOF {
+			break
+		}
+	}
+
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, errorAt(tok.span, "unexpected token: %s", tok.text)
+	}
+
+	if len(stmts) == 1 {
+		return stmts[0], nil
+	}
+	return &Block{
+		Span:  Span{stmts[0].Position().Start, stmts[len(stmts)-1].Position().End},
+		Stmts: stmts,
+	}, nil
+}
+
+// parseStatement разбирает присваивание "name = expr",
+// определение функции "name(params) = expr" или просто выражение
+func (p *parser) parseStatement() (Node, error) {
+	name := p.peek()
+	if name.kind == tokIdent && p.isFuncDef() {
+		return p.parseFuncDef()
+	}
+	if name.kind != tokIdent || p.tokens[p.pos+1].kind != tokAssign {
+		return p.parseExpr(lowestPrecedence)
+	}
+
+	if p.calc.isConstant(name.text) {
+		return nil, errorAt(name.span, "cannot assign to constant %s", name.text)
+	}
+	if _, ok := p.calc.functions[name.text]; ok {
+		return nil, errorAt(name.span, "cannot assign to function %s", name.text)
+	}
+	if _, ok := p.calc.operator(name.text); ok {
+		return nil, errorAt(name.span, "cannot assign to operator %s", name.text)
+	}
+	if p.calc.isUnit(name.text) {
+		return nil, errorAt(name.span, "cannot assign to unit %s", name.text)
+	}
+	p.next()
+	p.next()
+
+	value, err := p.parseExpr(lowestPrecedence)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Assign{Span: Span{name.span.Start, value.Position().End}, Name: name.text, Value: value}, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// isFuncDef проверяет, что с текущей позиции начинается "name(...) ="
+func (p *parser) isFuncDef() bool {
+	if p.tokens[p.pos+1].kind != tokLParen {
+		return false
+	}
+
+	depth := 0
+	for i := p.pos + 1; i < len(p.tokens); i++ {
+		switch p.tokens[i].kind {
+		case tokLParen:
+			depth++
+		case tokRParen:
+			depth--
+			if depth == 0 {
+				return p.tokens[i+1].kind == tokAssign
+			}
+		case tokEOF, tokSemicolon:
+			return false
+		}
+	}
+	return false
+}
+
+func (p *parser) parseFuncDef() (Node, error) {
+	name := p.next()
+	if p.calc.isConstant(name.text) {
+		return nil, errorAt(name.span, "cannot redefine constant %s", name.text)
+	}
+	if _, ok := p.calc.functions[name.text]; ok {
+		return nil, errorAt(name.span, "cannot redefine built-in function %s", name.text)
+	}
+	if _, ok := p.calc.operator(name.text); ok {
+		return nil, errorAt(name.span, "cannot redefine operator %s", name.text)
+	}
+	p.next()
+
+	var params []string
+	for len(params) > 0 || p.peek().kind != tokRParen {
+		param := p.next()
+		if param.kind != tokIdent {
+			return nil, errorAt(param.span, "expected parameter name, got %s", describe(param))
+		}
+		if p.calc.isConstant(param.text) {
+			return nil, errorAt(param.span, "cannot use constant %s as parameter", param.text)
+		}
+		if p.calc.isUnit(param.text) {
+			return nil, errorAt(param.span, "cannot use unit %s as parameter", param.text)
+		}
+		if slices.Contains(params, param.text) {
+			return nil, errorAt(param.span, "duplicate parameter %s", param.text)
+		}
+		params = append(params, param.text)
+
+		if p.peek().kind != tokComma {
+			break
+		}
+		p.next()
+	}
+
+	if _, err := p.expect(tokRParen); err != nil {
+		return nil, err
+	}
+	// "="
+	p.next()
+
+	body, err := p.parseExpr(lowestPrecedence)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FuncDef{
+		Span:   Span{name.span.Start, body.Position().End},
+		Name:   name.text,
+		Params: params,
+		Body:   body,
+	}, nil
+}
+
+// parseExpr разбирает выражение из операторов с приоритетом не ниже minPrec
+func (p *parser) parseExpr(minPrec int) (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		if minPrec <= conversionPrecedence && p.isConversion(tok) {
+			p.next()
+			target, err := p.parseTarget()
+			if err != nil {
+				return nil, err
+			}
+			left = &Convert{Span: Span{left.Position().Start, target.Position().End}, X: left, Target: target}
+			continue
+		}
+
+		op, ok := p.operator(tok)
+		if !ok || op.Precedence < minPrec {
+			return left, nil
+		}
+		p.next()
+
+		nextPrec := op.Precedence + 1
+		if op.Associativity == RightAssoc {
+			nextPrec = op.Precedence
+		}
+		right, err := p.parseExpr(nextPrec)
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryOp{
+			Span: Span{left.Position().Start, right.Position().End},
+			Op:   tok.text,
+			X:    left,
+			Y:    right,
+		}
+	}
+}
+
+// isConversion проверяет, что токен - перевод единиц to или in
+func (p *parser) isConversion(tok token) bool {
+	return p.calc.config.Mode == Units && tok.kind == tokIdent && (tok.text == "to" || tok.text == "in")
+}
+
+// operator возвращает бинарный оператор, если токен им является:
+// это либо знак, либо слово вроде "mod"
+func (p *parser) operator(tok token) (*Operator, bool) {
+	if tok.kind != tokOperator && tok.kind != tokIdent {
+		return nil, false
+	}
+	return p.calc.operator(tok.text)
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if tok := p.peek(); tok.kind == tokOperator && (tok.text == "-" || tok.text == "~") {
+		p.next()
+		operand, err := p.parseExpr(unaryPrecedence)
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryOp{Span: Span{tok.span.Start, operand.Position().End}, Op: tok.text, X: operand}, nil
+	}
+
+	return p.parsePostfix()
+}
+
+// parsePostfix разбирает факториал после операнда: 5!, (n-1)!
+func (p *parser) parsePostfix() (Node, error) {
+	// у (n-1)! позиция начинается со скобки
+	start := p.peek().span.Start
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for tok := p.peek(); tok.kind == tokOperator && tok.text == "!"; tok = p.peek() {
+		p.next()
+		node = &FuncCall{Span: Span{start, tok.span.End}, Name: "factorial", Args: []Node{node}}
+	}
+	return node, nil
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	tok := p.next()
+
+	switch tok.kind {
+	case tokNumber:
+		value, err := parseNumber(tok.text)
+		if err != nil {
+			return nil, errorAt(tok.span, "invalid number: %s", tok.text)
+		}
+		// мнимый литерал 2i: буква i вплотную к числу
+		if next := p.peek(); p.calc.config.Mode == Complex && next.kind == tokIdent && next.text == "i" && next.span.Start == tok.span.End {
+			p.next()
+			return &NumberLit{Span: Span{tok.span.Start, next.span.End}, Value: value, Text: tok.text + "i", Imaginary: true}, nil
+		}
+		// угол с единицей вплотную к числу: 30°, 0.5rad
+		if next := p.peek(); next.span.Start == tok.span.End && next.kind != tokNumber {
+			if unit, ok := angleSuffixes[next.text]; ok {
+				p.next()
+				number := &NumberLit{Span: tok.span, Value: value, Text: tok.text}
+				return &AngleLit{Span: Span{tok.span.Start, next.span.End}, Value: number, Unit: unit}, nil
+			}
+		}
+		return p.parseUnits(&NumberLit{Span: tok.span, Value: value, Text: tok.text}), nil
+	case tokLParen:
+		node, err := p.parseExpr(lowestPrecedence)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokRParen); err != nil {
+			return nil, err
+		}
+		return node, nil
+	case tokIdent:
+		if p.calc.isConstant(tok.text) {
+			return &Constant{Span: tok.span, Name: tok.text}, nil
+		}
+		if p.peek().kind == tokLParen {
+			return p.parseCall(tok)
+		}
+		if p.calc.isUnit(tok.text) {
+			return &Unit{Span: tok.span, Name: tok.text, Power: 1}, nil
+		}
+		if _, ok := p.calc.functions[tok.text]; ok {
+			return nil, errorAt(p.peek().span, "expected (, got %s", describe(p.peek()))
+		}
+		if _, ok := p.calc.operator(tok.text); ok {
+			return nil, errorAt(tok.span, "unexpected operator %s", tok.text)
+		}
+		return &Variable{Span: tok.span, Name: tok.text}, nil
+	case tokLBracket:
+		elems, end, err := p.parseList(tokRBracket)
+		if err != nil {
+			return nil, err
+		}
+		return &List{Span: Span{tok.span.Start, end.span.End}, Elems: elems}, nil
+	case tokEOF:
+		return nil, errorAt(tok.span, "unexpected end of expression")
+	}
+
+	return nil, errorAt(tok.span, "unexpected token: %s", tok.text)
+}
+
+// parseNumber разбирает десятичное число или целое с префиксом 0x, 0b, 0o.
+// Число вне диапазона float64 не ошибка разбора: оно становится ±Inf,
+// а режимы на math/big читают его из исходной записи.
+func parseNumber(text string) (float64, error) {
+	if isPrefixedNumber(text) {
+		n, err := strconv.ParseUint(text, 0, 64)
+		if errors.Is(err, strconv.ErrRange) {
+			return math.Inf(1), nil
+		}
+		return float64(n), err
+	}
+	x, err := strconv.ParseFloat(text, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return x, nil
+	}
+	return x, err
+}
+
+// float возвращает значение литерала для режимов на float64
+func (n *NumberLit) float() (float64, error) {
+	if math.IsInf(n.Value, 0) {
+		return 0, kindErrorAt(KindOverflow, n.Span, "number out of range: %s", n)
+	}
+	return n.Value, nil
+}
+
+// parseUnits разбирает единицы измерения после числа: 5 km, 9.81 m s^-2
+func (p *parser) parseUnits(number *NumberLit) Node {
+	units := p.unitProduct()
+	if len(units) == 0 {
+		return number
+	}
+	return &QuantityLit{Span: Span{number.Start, units[len(units)-1].End}, Value: number, Units: units}
+}
+
+// unitProduct разбирает идущие подряд единицы со степенями: m s^-2
+func (p *parser) unitProduct() []*Unit {
+	var units []*Unit
+	for {
+		tok := p.peek()
+		if tok.kind != tokIdent || !p.calc.isUnit(tok.text) {
+			break
+		}
+		// 5 in cm - перевод в сантиметры, а не пять дюймов на сантиметр
+		if tok.text == "in" && p.startsOperand(p.tokens[p.pos+1]) {
+			break
+		}
+		p.next()
+
+		unit := &Unit{Span: tok.span, Name: tok.text, Power: 1}
+		if power, end, ok := p.unitPower(); ok {
+			unit.Power, unit.End = power, end
+		}
+		units = append(units, unit)
+	}
+	return units
+}
+
+// parseTarget разбирает единицы, в которые переводится значение:
+// km/h, kg m/s^2, (m/s)^2. Чисел в них нет, идущие подряд единицы
+// перемножаются, как после числа.
+func (p *parser) parseTarget() (Node, error) {
+	left, err := p.parseTargetUnits()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if tok.kind != tokOperator || tok.text != "*" && tok.text != "/" {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseTargetUnits()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryOp{Span: Span{left.Position().Start, right.Position().End}, Op: tok.text, X: left, Y: right}
+	}
+}
+
+func (p *parser) parseTargetUnits() (Node, error) {
+	tok := p.peek()
+	if tok.kind == tokLParen {
+		p.next()
+		target, err := p.parseTarget()
+		if err != nil {
+			return nil, err
+		}
+		rparen, err := p.expect(tokRParen)
+		if err != nil {
+			return nil, err
+		}
+		power, end, ok := p.unitPower()
+		if !ok {
+			return target, nil
+		}
+		exponent := &NumberLit{Span: Span{rparen.span.End + 1, end}, Value: float64(power), Text: strconv.Itoa(power)}
+		return &BinaryOp{Span: Span{tok.span.Start, end}, Op: "^", X: target, Y: exponent}, nil
+	}
+
+	units := p.unitProduct()
+	if len(units) == 0 {
+		if tok.kind == tokEOF {
+			return nil, errorAt(tok.span, "unexpected end of expression")
+		}
+		return nil, errorAt(tok.span, "expected unit, got %s", describe(tok))
+	}
+	var result Node = units[0]
+	for _, unit := range units[1:] {
+		result = &BinaryOp{Span: Span{result.Position().Start, unit.End}, Op: "*", X: result, Y: unit}
+	}
+	return result, nil
+}
+
+// unitPower разбирает целую степень единицы "^2" или "^-1"
+// и возвращает её вместе с концом записи
+func (p *parser) unitPower() (int, int, bool) {
+	if tok := p.peek(); tok.kind != tokOperator || tok.text != "^" {
+		return 0, 0, false
+	}
+	i := p.pos + 1
+	sign := 1
+	if tok := p.tokens[i]; tok.kind == tokOperator && tok.text == "-" {
+		sign = -1
+		i++
+	}
+	tok := p.tokens[i]
+	if tok.kind != tokNumber {
+		return 0, 0, false
+	}
+	power, err := strconv.Atoi(tok.text)
+	if err != nil {
+		return 0, 0, false
+	}
+	p.pos = i + 1
+	return sign * power, tok.span.End, true
+}
+
+// startsOperand проверяет, что с токена может начинаться операнд
+func (p *parser) startsOperand(tok token) bool {
+	switch tok.kind {
+	case tokNumber, tokLParen, tokLBracket:
+		return true
+	case tokIdent:
+		return !p.isConversion(tok)
+	}
+	return false
+}
+
+// parseCall разбирает список аргументов name(arg1, arg2, ...)
+func (p *parser) parseCall(name token) (Node, error) {
+	p.next()
+
+	args, rparen, err := p.parseList(tokRParen)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FuncCall{Span: Span{name.span.Start, rparen.span.End}, Name: name.text, Args: args}, nil
+}
+
+// parseList разбирает выражения через запятую до токена end включительно
+func (p *parser) parseList(end tokenKind) ([]Node, token, error) {
+	var elems []Node
+	if p.peek().kind != end {
+		for {
+			elem, err := p.parseExpr(lowestPrecedence)
+			if err != nil {
+				return nil, token{}, err
+			}
+			elems = append(elems, elem)
+
+			if p.peek().kind != tokComma {
+				break
+			}
+			p.next()
+		}
+	}
+
+	tok, err := p.expect(end)
+	if err != nil {
+		return nil, token{}, err
+	}
+	return elems, tok, nil
+}
+
+func (p *parser) expect(kind tokenKind) (token, error) {
+	tok := p.next()
+	if tok.kind == kind {
+		return tok, nil
+	}
+
+	want := map[tokenKind]string{tokLParen: "(", tokRParen: ")", tokComma: string(p.calc.config.Locale.ArgumentSeparator()), tokRBracket: "]"}[kind]
+	return tok, errorAt(tok.span, "expected %s, got %s", want, describe(tok))
+}
+
+func describe(tok token) string {
+	if tok.kind == tokEOF {
+		return "end of expression"
+	}
+	return tok.text
+}
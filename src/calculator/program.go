@@ -0,0 +1,611 @@
+package calculator
+
+import (
+	"math"
+	"slices"
+	"strings"
+)
+
+// ограничение на глубину вложенных вызовов пользовательских функций
+const maxCallDepth = 64
+
+type opcode uint8
+
+const (
+	opPush opcode = iota
+	opLoad
+	opStore
+	opLoadParam
+	opPop
+	opNeg
+	opBinary
+	opCall
+	opCallUser
+)
+
+// instruction - команда программы. arg - номер глобальной переменной для
+// opLoad/opStore, параметра для opLoadParam, иначе номер операнда в
+// таблицах operands: consts для opPush, ops для opBinary, calls для opCall,
+// callees для opCallUser.
+type instruction struct {
+	op  opcode
+	arg int32
+}
+
+// operands - таблицы операндов команд, общие для программы и тел её функций
+type operands struct {
+	consts  []float64
+	ops     []binaryOp
+	calls   []call
+	callees []*chunk
+}
+
+// binaryOp - операнд opBinary
+type binaryOp struct {
+	fn func(a, b float64) (float64, error)
+	// название операции для сообщений об ошибках
+	name message
+	span Span
+}
+
+// call - операнд opCall
+type call struct {
+	fn   func(args ...float64) (float64, error)
+	argc int
+	name message
+	// перевод аргументов из этих единиц углов в радианы
+	argAngle AngleUnit
+	// перевод результата из радиан в эти единицы углов
+	resultAngle AngleUnit
+	span        Span
+}
+
+// chunk - скомпилированное тело пользовательской функции
+type chunk struct {
+	def  *FuncDef
+	code []instruction
+	// глобальные переменные, которые читает функция, включая вызываемые ею функции
+	free []string
+	// сколько места в стеке нужно функции вместе с параметрами
+	stackSize int
+}
+
+// Program - скомпилированное выражение. Program неизменяема после Compile,
+// поэтому её можно вычислять одновременно из нескольких горутин.
+type Program struct {
+	source string
+	code   []instruction
+	operands
+	// все глобальные переменные, они лежат в начале стека
+	globals []string
+	// номера глобальных переменных, значения которых задаются снаружи
+	inputs     []int
+	inputSpans []Span
+	// переменные и функции, которые определяет само выражение
+	assigned  []int
+	defs      []*FuncDef
+	stackSize int
+	hasValue  bool
+}
+
+func (calc *Calculator) compileTree(tree Node, source string, funcs map[string]*FuncDef) (*Program, error) {
+	c := newCompiler(calc, funcs)
+	hasValue, err := c.compileStatement(tree)
+	if err != nil {
+		return nil, newError("%w: %w", errParsing, locateError(err, source))
+	}
+
+	p := &Program{
+		source:     source,
+		code:       c.code,
+		operands:   c.operands,
+		globals:    c.globalNames,
+		inputs:     c.inputs,
+		inputSpans: c.inputSpans,
+		defs:       c.defs,
+		stackSize:  len(c.globalNames) + c.maxDepth,
+		hasValue:   hasValue,
+	}
+	for name := range c.assigned {
+		p.assigned = append(p.assigned, c.globals[name])
+	}
+	slices.Sort(p.assigned)
+
+	return p, nil
+}
+
+// Variables возвращает имена переменных в том порядке, в котором EvalInto ждёт их значения
+func (p *Program) Variables() []string {
+	names := make([]string, 0, len(p.inputs))
+	for _, slot := range p.inputs {
+		names = append(names, p.globals[slot])
+	}
+	return names
+}
+
+// StackSize возвращает минимальный размер стека для EvalInto
+func (p *Program) StackSize() int {
+	return p.stackSize
+}
+
+// HasValue сообщает, вычисляет ли программа значение:
+// у программы из одних определений функций значения нет
+func (p *Program) HasValue() bool {
+	return p.hasValue
+}
+
+func (p *Program) String() string {
+	return p.source
+}
+
+// Eval вычисляет программу, беря значения переменных из vars
+func (p *Program) Eval(vars map[string]float64) (float64, error) {
+	stack := make([]float64, p.stackSize)
+	if err := p.runInto(stack, vars); err != nil {
+		return 0, err
+	}
+	if !p.hasValue {
+		return 0, newError("%w: expression has no value", errCalculating)
+	}
+
+	return p.result(stack), nil
+}
+
+// EvalInto вычисляет программу без выделения памяти: stack - рабочий буфер
+// размером не меньше StackSize(), args - значения переменных в порядке Variables()
+func (p *Program) EvalInto(stack []float64, args []float64) (float64, error) {
+	if len(stack) < p.stackSize {
+		return 0, newError("stack too small: need %d, got %d", p.stackSize, len(stack))
+	}
+	if len(args) != len(p.inputs) {
+		return 0, newError("expected %d variables, got %d", len(p.inputs), len(args))
+	}
+	if !p.hasValue {
+		return 0, newError("%w: expression has no value", errCalculating)
+	}
+
+	for i, slot := range p.inputs {
+		stack[slot] = args[i]
+	}
+	if _, err := p.exec(p.code, stack, 0, len(p.globals)); err != nil {
+		return 0, err
+	}
+
+	return p.result(stack), nil
+}
+
+// runInto вычисляет программу в переданном стеке, после чего
+// в начале стека остаются значения глобальных переменных
+func (p *Program) runInto(stack []float64, vars map[string]float64) error {
+	for i, slot := range p.inputs {
+		value, ok := vars[p.globals[slot]]
+		if !ok {
+			e := kindErrorAt(KindUndefined, p.inputSpans[i], "undefined variable %s", p.globals[slot])
+			return newError("%w: %w", errCalculating, e.locate(p.source))
+		}
+		stack[slot] = value
+	}
+
+	_, err := p.exec(p.code, stack, 0, len(p.globals))
+	return err
+}
+
+func (p *Program) result(stack []float64) float64 {
+	return stack[len(p.globals)]
+}
+
+// exec выполняет код программы или тела функции. fp указывает на первый
+// параметр функции, sp - на вершину стека. Возвращает новую вершину стека.
+func (p *Program) exec(code []instruction, stack []float64, fp, sp int) (int, error) {
+	for _, ins := range code {
+		switch ins.op {
+		case opPush:
+			stack[sp] = p.consts[ins.arg]
+			sp++
+		case opLoad:
+			stack[sp] = stack[ins.arg]
+			sp++
+		case opStore:
+			stack[ins.arg] = stack[sp-1]
+		case opLoadParam:
+			stack[sp] = stack[fp+int(ins.arg)]
+			sp++
+		case opPop:
+			sp--
+		case opNeg:
+			stack[sp-1] = -stack[sp-1]
+		case opBinary:
+			op := &p.ops[ins.arg]
+			sp--
+			result, err := op.fn(stack[sp-1], stack[sp])
+			if err != nil {
+				return 0, p.fail(op.span, err, "calculating %s", op.name)
+			}
+			stack[sp-1] = result
+		case opCall:
+			call := &p.calls[ins.arg]
+			args := stack[sp-call.argc : sp]
+			if !call.argAngle.isRadian() {
+				for i := range args {
+					args[i] = call.argAngle.toRadians(args[i])
+				}
+			}
+			result, err := call.fn(args...)
+			if err != nil {
+				return 0, p.fail(call.span, err, "calculating %s", call.name)
+			}
+			result = call.resultAngle.fromRadians(result)
+			sp -= call.argc
+			stack[sp] = result
+			sp++
+		case opCallUser:
+			user := p.callees[ins.arg]
+			frame := sp - len(user.def.Params)
+			top, err := p.exec(user.code, stack, frame, sp)
+			if err != nil {
+				return 0, err
+			}
+			stack[frame] = stack[top-1]
+			sp = frame + 1
+		}
+	}
+
+	return sp, nil
+}
+
+func (p *Program) fail(span Span, err error, format string, args ...any) error {
+	e := errorAt(span, format, args...)
+	if err != nil {
+		e = wrapErrorAt(span, err, format, args...)
+	}
+	return newError("%w: %w", errCalculating, e.locate(p.source))
+}
+
+type compiler struct {
+	calc *Calculator
+	code []instruction
+	operands
+	depth    int
+	maxDepth int
+
+	globals     map[string]int
+	globalNames []string
+	inputs      []int
+	inputSpans  []Span
+	// переменные, которым на верхнем уровне уже присвоено значение
+	assigned map[string]bool
+
+	funcs  map[string]*FuncDef
+	defs   []*FuncDef
+	chunks map[string]*chunk
+
+	// компилируемая функция, nil на верхнем уровне
+	current *chunk
+	calling []string
+}
+
+func newCompiler(calc *Calculator, funcs map[string]*FuncDef) *compiler {
+	c := &compiler{
+		calc:     calc,
+		globals:  map[string]int{},
+		assigned: map[string]bool{},
+		funcs:    map[string]*FuncDef{},
+		chunks:   map[string]*chunk{},
+	}
+	for name, def := range funcs {
+		c.funcs[name] = def
+	}
+	return c
+}
+
+func (c *compiler) emit(op opcode, arg int, stackDelta int) {
+	c.code = append(c.code, instruction{op: op, arg: int32(arg)})
+	c.depth += stackDelta
+	c.maxDepth = max(c.maxDepth, c.depth)
+}
+
+func (c *compiler) emitPush(value float64) {
+	c.emit(opPush, len(c.consts), 1)
+	c.consts = append(c.consts, value)
+}
+
+func (c *compiler) emitBinary(op binaryOp) {
+	c.emit(opBinary, len(c.ops), -1)
+	c.ops = append(c.ops, op)
+}
+
+func (c *compiler) emitCall(call call) {
+	c.emit(opCall, len(c.calls), 1-call.argc)
+	c.calls = append(c.calls, call)
+}
+
+func (c *compiler) global(name string) int {
+	slot, ok := c.globals[name]
+	if !ok {
+		slot = len(c.globalNames)
+		c.globals[name] = slot
+		c.globalNames = append(c.globalNames, name)
+	}
+	return slot
+}
+
+// readGlobal отмечает чтение глобальной переменной: если на верхнем уровне
+// ей ещё ничего не присвоено, значение должно прийти снаружи
+func (c *compiler) readGlobal(name string, span Span) int {
+	slot := c.global(name)
+	if c.current != nil {
+		if !slices.Contains(c.current.free, name) {
+			c.current.free = append(c.current.free, name)
+		}
+		return slot
+	}
+
+	if !c.assigned[name] && !slices.Contains(c.inputs, slot) {
+		c.inputs = append(c.inputs, slot)
+		c.inputSpans = append(c.inputSpans, span)
+	}
+	return slot
+}
+
+// compileStatement компилирует оператор и сообщает, оставил ли он значение в стеке
+func (c *compiler) compileStatement(node Node) (bool, error) {
+	switch n := node.(type) {
+	case *Block:
+		hasValue := false
+		for _, stmt := range n.Stmts {
+			if hasValue {
+				c.emit(opPop, 0, -1)
+			}
+
+			var err error
+			hasValue, err = c.compileStatement(stmt)
+			if err != nil {
+				return false, err
+			}
+		}
+		return hasValue, nil
+	case *Assign:
+		if err := c.compile(n.Value); err != nil {
+			return false, err
+		}
+		c.assigned[n.Name] = true
+		c.emit(opStore, c.global(n.Name), 0)
+		return true, nil
+	case *FuncDef:
+		return false, c.define(n)
+	}
+
+	return true, c.compile(node)
+}
+
+func (c *compiler) define(def *FuncDef) error {
+	if path := c.findCycle(def.Name, def.Body, []string{def.Name}); path != nil {
+		return errorAt(def.Span, "recursive definition of %s: %s", def.Name, strings.Join(path, " -> "))
+	}
+
+	c.funcs[def.Name] = def
+	c.defs = append(c.defs, def)
+	return nil
+}
+
+// findCycle ищет цепочку вызовов, ведущую из body обратно в функцию name
+func (c *compiler) findCycle(name string, body Node, path []string) []string {
+	var cycle []string
+	Inspect(body, func(n Node) bool {
+		if cycle != nil {
+			return false
+		}
+		call, ok := n.(*FuncCall)
+		if !ok {
+			return true
+		}
+
+		if call.Name == name {
+			cycle = append(slices.Clone(path), name)
+		} else if def, ok := c.funcs[call.Name]; ok && !slices.Contains(path, call.Name) {
+			cycle = c.findCycle(name, def.Body, append(slices.Clone(path), call.Name))
+		}
+		return cycle == nil
+	})
+	return cycle
+}
+
+// literal возвращает значение литерала для программы. Программа выполняется
+// только в режиме float64; в остальных режимах она лишь проверяет выражение,
+// а числа вне диапазона float64 разбирает домен режима.
+func (c *compiler) literal(n *NumberLit) (float64, error) {
+	if !c.calc.usesProgram() {
+		return n.Value, nil
+	}
+	return n.float()
+}
+
+func (c *compiler) compile(node Node) error {
+	switch n := node.(type) {
+	case *NumberLit:
+		value, err := c.literal(n)
+		if err != nil {
+			return err
+		}
+		c.emitPush(value)
+	case *AngleLit:
+		value, err := c.literal(n.Value)
+		if err != nil {
+			return err
+		}
+		value = convertAngle(value, n.Unit, c.calc.config.AngleUnits)
+		c.emitPush(value)
+	case *Constant:
+		value := math.Pi
+		if n.Name == "e" {
+			value = math.E
+		}
+		c.emitPush(value)
+	case *Variable:
+		if c.current != nil {
+			if i := slices.Index(c.current.def.Params, n.Name); i >= 0 {
+				c.emit(opLoadParam, i, 1)
+				return nil
+			}
+		}
+		c.emit(opLoad, c.readGlobal(n.Name, n.Span), 1)
+	case *UnaryOp:
+		if err := c.compile(n.X); err != nil {
+			return err
+		}
+		if n.Op == "~" {
+			c.emitCall(call{fn: func(args ...float64) (float64, error) {
+				return BitNot(args[0])
+			}, argc: 1, name: message{format: "bitwise not"}, span: n.Span})
+			return nil
+		}
+		c.emit(opNeg, 0, 0)
+	case *BinaryOp:
+		if err := c.compile(n.X); err != nil {
+			return err
+		}
+		if err := c.compile(n.Y); err != nil {
+			return err
+		}
+		op, ok := c.calc.operator(n.Op)
+		if !ok {
+			return kindErrorAt(KindUndefined, n.Span, "unknown operator %s", n.Op)
+		}
+		c.emitBinary(binaryOp{fn: op.Fn, name: op.name, span: n.Span})
+	case *FuncCall:
+		return c.compileCall(n)
+	case *List:
+		// списки вычисляются только в других режимах, здесь проверяются элементы
+		if c.calc.usesProgram() {
+			return kindErrorAt(KindUnsupported, n.Span, "list literals are not supported in %s mode", c.calc.config.Mode)
+		}
+		c.emitPush(0)
+		for _, elem := range n.Elems {
+			if err := c.compile(elem); err != nil {
+				return err
+			}
+			c.emit(opPop, 0, -1)
+		}
+	case *Unit:
+		// единицы вычисляются в режиме Units, здесь они переводятся в основные
+		def, ok := c.calc.units().lookup(n.Name)
+		if !ok || c.calc.config.Mode != Units {
+			return kindErrorAt(KindUnsupported, n.Span, "units are not supported in %s mode", c.calc.config.Mode)
+		}
+		c.emitPush(math.Pow(def.factor, float64(n.Power)))
+	case *QuantityLit:
+		if err := c.compile(n.Value); err != nil {
+			return err
+		}
+		for _, unit := range n.Units {
+			if err := c.compile(unit); err != nil {
+				return err
+			}
+			c.emitBinary(binaryOp{fn: Mul, name: message{format: "multiplication"}, span: n.Span})
+		}
+	case *Convert:
+		if err := c.compile(n.X); err != nil {
+			return err
+		}
+		if err := c.compile(n.Target); err != nil {
+			return err
+		}
+		c.emitBinary(binaryOp{fn: Div, name: message{format: "conversion"}, span: n.Span})
+	default:
+		panic("unknown node")
+	}
+
+	return nil
+}
+
+func (c *compiler) compileCall(n *FuncCall) error {
+	if f, ok := c.calc.functions[n.Name]; ok {
+		args := c.calc.callArgs(f, n.Args)
+		if msg, ok := f.arityError(n.Name, len(args)); ok {
+			return errorAt(n.Span, msg.format, msg.args...)
+		}
+		for _, arg := range args {
+			if err := c.compile(arg); err != nil {
+				return err
+			}
+		}
+		call := call{fn: f.Fn, argc: len(args), name: message{"%s", []any{n.Name}}, span: n.Span}
+		if f.isTrig {
+			call.argAngle = c.calc.config.AngleUnits
+		}
+		if f.returnsAngle {
+			call.resultAngle = c.calc.config.AngleUnits
+		}
+		c.emitCall(call)
+		return nil
+	}
+
+	def, ok := c.funcs[n.Name]
+	if !ok {
+		return kindErrorAt(KindUndefined, n.Span, "unknown function %s", n.Name)
+	}
+	if len(n.Args) != len(def.Params) {
+		msg := expectsArgs(n.Name, len(def.Params), len(n.Args))
+		return errorAt(n.Span, msg.format, msg.args...)
+	}
+
+	for _, arg := range n.Args {
+		if err := c.compile(arg); err != nil {
+			return err
+		}
+	}
+
+	callee, err := c.chunk(def)
+	if err != nil {
+		return err
+	}
+
+	// свободные переменные функции читаются в момент вызова
+	for _, name := range callee.free {
+		c.readGlobal(name, n.Span)
+	}
+
+	c.emit(opCallUser, len(c.callees), 1-len(n.Args))
+	c.callees = append(c.callees, callee)
+	c.maxDepth = max(c.maxDepth, c.depth-1+callee.stackSize)
+	return nil
+}
+
+// chunk компилирует тело функции при первом вызове,
+// после переопределения функции - заново
+func (c *compiler) chunk(def *FuncDef) (*chunk, error) {
+	if compiled, ok := c.chunks[def.Name]; ok && compiled.def == def {
+		return compiled, nil
+	}
+	if len(c.calling) >= maxCallDepth {
+		return nil, errorAt(def.Span, "call depth limit exceeded in %s", def.Name)
+	}
+
+	code, depth, maxDepth, current := c.code, c.depth, c.maxDepth, c.current
+	c.code, c.depth, c.maxDepth = nil, len(def.Params), len(def.Params)
+	c.current = &chunk{def: def}
+	c.calling = append(c.calling, def.Name)
+
+	err := c.compile(def.Body)
+	compiled := c.current
+	compiled.code, compiled.stackSize = c.code, c.maxDepth
+
+	c.code, c.depth, c.maxDepth, c.current = code, depth, maxDepth, current
+	c.calling = c.calling[:len(c.calling)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	c.chunks[def.Name] = compiled
+	return compiled, nil
+}
+
+// expectsArgs - сообщение о том, что функция name ждёт want аргументов,
+// а получила got. Для одного аргумента свой шаблон, чтобы перевод мог
+// согласовать число.
+func expectsArgs(name string, want, got int) message {
+	if want == 1 {
+		return message{"function %s expects 1 argument, got %d", []any{name, got}}
+	}
+	return message{"function %s expects %d arguments, got %d", []any{name, want, got}}
+}
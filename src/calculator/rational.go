@@ -0,0 +1,435 @@
+package calculator
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ограничение на размер числителя и знаменателя при возведении в степень
+const maxRationalBits = 1 << 20
+
+// наибольшее число знаков в round при точных вычислениях
+const maxRoundDigits = 10000
+
+var (
+	errInexact    = errors.New("result is not exact in rational mode")
+	errNotANumber = errors.New("result is not a number")
+)
+
+// Fraction - результат вычисления в режиме Rational
+type Fraction struct {
+	r *big.Rat
+	// значение получено через float64 (AllowInexact) и может быть неточным
+	inexact bool
+	mixed   bool
+}
+
+// Rat возвращает копию значения
+func (f Fraction) Rat() *big.Rat {
+	return new(big.Rat).Set(f.r)
+}
+
+// Exact сообщает, что значение вычислено точно
+func (f Fraction) Exact() bool {
+	return !f.inexact
+}
+
+// String печатает дробь вида 3/10, целые числа - без знаменателя,
+// смешанные дроби - как 3 1/2. Неточные значения печатаются десятичной
+// записью со знаком ≈.
+func (f Fraction) String() string {
+	if f.inexact {
+		x, _ := f.r.Float64()
+		return "≈" + strconv.FormatFloat(x, 'g', -1, 64)
+	}
+	if f.r.IsInt() || !f.mixed {
+		return f.r.RatString()
+	}
+
+	num := new(big.Int).Abs(f.r.Num())
+	whole, rest := new(big.Int).QuoRem(num, f.r.Denom(), new(big.Int))
+	if whole.Sign() == 0 {
+		return f.r.RatString()
+	}
+
+	var b strings.Builder
+	if f.r.Sign() < 0 {
+		b.WriteByte('-')
+	}
+	b.WriteString(whole.String() + " " + rest.String() + "/" + f.r.Denom().String())
+	return b.String()
+}
+
+func (f Fraction) Float64() (float64, bool) {
+	x, _ := f.r.Float64()
+	return x, !math.IsInf(x, 0)
+}
+
+// ratValue - значение в домене рациональных чисел
+type ratValue struct {
+	r       *big.Rat
+	inexact bool
+}
+
+// rationalDomain - точные вычисления над big.Rat
+type rationalDomain struct {
+	mixed        bool
+	allowInexact bool
+}
+
+func newRationalDomain(config CalculatorConfig) *rationalDomain {
+	return &rationalDomain{
+		mixed:        config.MixedFractions,
+		allowInexact: config.AllowInexact,
+	}
+}
+
+// number разбирает исходную запись литерала, поэтому числа вне диапазона
+// float64 и длинные десятичные дроби остаются точными
+func (d *rationalDomain) number(n *NumberLit) (ratValue, error) {
+	text := n.Text
+	if text == "" {
+		text = strconv.FormatFloat(n.Value, 'g', -1, 64)
+	}
+	r, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return ratValue{}, errorAt(n.Span, "invalid number: %s", n.Text)
+	}
+	return ratValue{r: r}, nil
+}
+
+func (d *rationalDomain) constant(name string) (ratValue, error) {
+	if name == "e" {
+		return d.approx(math.E)
+	}
+	return d.approx(math.Pi)
+}
+
+// fromFloat переводит значение переменной в дробь по кратчайшей десятичной
+// записи, чтобы 0.1 стало 1/10
+func (d *rationalDomain) fromFloat(x float64) (ratValue, bool) {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return ratValue{}, false
+	}
+	r, _ := new(big.Rat).SetString(strconv.FormatFloat(x, 'g', -1, 64))
+	return ratValue{r: r}, true
+}
+
+func (d *rationalDomain) approx(x float64) (ratValue, error) {
+	if !d.allowInexact {
+		return ratValue{}, errInexact
+	}
+	if math.IsNaN(x) {
+		return ratValue{}, errNotANumber
+	}
+	if math.IsInf(x, 0) {
+		return ratValue{}, errOverflow
+	}
+	return ratValue{r: new(big.Rat).SetFloat64(x), inexact: true}, nil
+}
+
+func (d *rationalDomain) toFloat(x ratValue) (float64, bool) {
+	f, _ := x.r.Float64()
+	return f, !math.IsInf(f, 0)
+}
+
+func (d *rationalDomain) neg(x ratValue) (ratValue, error) {
+	return ratValue{r: new(big.Rat).Neg(x.r), inexact: x.inexact}, nil
+}
+
+func (d *rationalDomain) binary(op string, x, y ratValue) (ratValue, error) {
+	z := ratValue{r: new(big.Rat), inexact: x.inexact || y.inexact}
+	switch op {
+	case "+":
+		z.r.Add(x.r, y.r)
+	case "-":
+		z.r.Sub(x.r, y.r)
+	case "*":
+		z.r.Mul(x.r, y.r)
+	case "/":
+		if y.r.Sign() == 0 {
+			return ratValue{}, errDivisionByZero
+		}
+		z.r.Quo(x.r, y.r)
+	case "^":
+		r, err := ratPow(x.r, y.r)
+		if err != nil {
+			return ratValue{}, err
+		}
+		z.r = r
+	default:
+		return ratValue{}, errUnsupported
+	}
+	return z, nil
+}
+
+func (d *rationalDomain) call(name string, args []ratValue) (ratValue, error) {
+	inexact := false
+	for _, arg := range args {
+		inexact = inexact || arg.inexact
+	}
+
+	switch name {
+	case "sqrt":
+		if root, ok := ratRoot(args[0].r, 2); ok {
+			return ratValue{r: root, inexact: inexact}, nil
+		}
+	case "max", "min":
+		result := args[0].r
+		for _, arg := range args[1:] {
+			if c := arg.r.Cmp(result); c > 0 && name == "max" || c < 0 && name == "min" {
+				result = arg.r
+			}
+		}
+		return ratValue{r: new(big.Rat).Set(result), inexact: inexact}, nil
+	}
+
+	rats := make([]*big.Rat, 0, len(args))
+	for _, arg := range args {
+		rats = append(rats, arg.r)
+	}
+	result, err := ratFunction(name, rats)
+	if err != nil {
+		return ratValue{}, err
+	}
+	return ratValue{r: result, inexact: inexact}, nil
+}
+
+func (d *rationalDomain) list(elems []ratValue) (ratValue, error) {
+	return ratValue{}, errUnsupported
+}
+
+func (d *rationalDomain) value(x ratValue) Value {
+	return Fraction{r: x.r, inexact: x.inexact, mixed: d.mixed}
+}
+
+// ratPow возводит x в рациональную степень y = p/q. Результат точный, если
+// из x извлекается корень степени q, иначе возвращается errUnsupported.
+func ratPow(x, y *big.Rat) (*big.Rat, error) {
+	if !y.Num().IsInt64() || !y.Denom().IsInt64() {
+		if y.IsInt() {
+			return nil, errOverflow
+		}
+		return nil, errUnsupported
+	}
+	p, q := y.Num().Int64(), y.Denom().Int64()
+
+	if x.Sign() == 0 {
+		if p < 0 {
+			return nil, errDivisionByZero
+		}
+		if p == 0 {
+			return big.NewRat(1, 1), nil
+		}
+		return new(big.Rat), nil
+	}
+
+	base := x
+	if q != 1 {
+		root, ok := ratRoot(x, q)
+		if !ok {
+			return nil, errUnsupported
+		}
+		base = root
+	}
+
+	n := p
+	if n < 0 {
+		n = -n
+	}
+	bits := max(base.Num().BitLen(), base.Denom().BitLen())
+	if int64(bits)*n > maxRationalBits {
+		return nil, errOverflow
+	}
+
+	num := new(big.Int).Exp(base.Num(), big.NewInt(n), nil)
+	den := new(big.Int).Exp(base.Denom(), big.NewInt(n), nil)
+	if p < 0 {
+		num, den = den, num
+	}
+	return new(big.Rat).SetFrac(num, den), nil
+}
+
+// ratRoot извлекает корень степени q, если он рациональный
+func ratRoot(x *big.Rat, q int64) (*big.Rat, bool) {
+	if q > maxRationalBits || x.Sign() < 0 && q%2 == 0 {
+		return nil, false
+	}
+	num, ok := intRoot(new(big.Int).Abs(x.Num()), q)
+	if !ok {
+		return nil, false
+	}
+	den, ok := intRoot(x.Denom(), q)
+	if !ok {
+		return nil, false
+	}
+	if x.Sign() < 0 {
+		num.Neg(num)
+	}
+	return new(big.Rat).SetFrac(num, den), true
+}
+
+// intRoot извлекает целый корень степени q из x >= 0 двоичным поиском
+func intRoot(x *big.Int, q int64) (*big.Int, bool) {
+	if q == 2 {
+		root := new(big.Int).Sqrt(x)
+		return root, new(big.Int).Mul(root, root).Cmp(x) == 0
+	}
+
+	power := big.NewInt(q)
+	lo := big.NewInt(0)
+	hi := new(big.Int).Lsh(big.NewInt(1), uint(x.BitLen()/int(q)+1))
+	for lo.Cmp(hi) <= 0 {
+		mid := new(big.Int).Add(lo, hi)
+		mid.Rsh(mid, 1)
+		switch new(big.Int).Exp(mid, power, nil).Cmp(x) {
+		case 0:
+			return mid, true
+		case -1:
+			lo.Add(mid, big.NewInt(1))
+		default:
+			hi.Sub(mid, big.NewInt(1))
+		}
+	}
+	return nil, false
+}
+
+// наибольший аргумент факториала и число множителей в nCr и nPr при точных
+// вычислениях: 10000! - это 35660 десятичных знаков
+const maxFactorial = 10000
+
+// ratFunction вычисляет точно функции, результат которых рационален при
+// рациональных аргументах. Её используют режимы Rational, Integer и big.Float.
+func ratFunction(name string, args []*big.Rat) (*big.Rat, error) {
+	x := args[0]
+	switch name {
+	case "abs":
+		return new(big.Rat).Abs(x), nil
+	case "sign":
+		return new(big.Rat).SetInt64(int64(x.Sign())), nil
+	case "floor", "ceil", "trunc":
+		return new(big.Rat).SetInt(ratInt(name, x)), nil
+	case "round":
+		digits := big.NewRat(0, 1)
+		if len(args) == 2 {
+			digits = args[1]
+		}
+		return ratRound(x, digits)
+	case "mod":
+		if args[1].Sign() == 0 {
+			return nil, errors.New("modulo by zero")
+		}
+		// a - b*floor(a/b), знак как у делителя
+		q := new(big.Rat).SetInt(ratInt("floor", new(big.Rat).Quo(x, args[1])))
+		return q.Sub(x, q.Mul(q, args[1])), nil
+	case "gcd", "lcm":
+		ints, err := ratIntegers(name, args)
+		if err != nil {
+			return nil, err
+		}
+		result := new(big.Int).Abs(ints[0])
+		for _, n := range ints[1:] {
+			n = new(big.Int).Abs(n)
+			if name == "gcd" {
+				result.GCD(nil, nil, result, n)
+				continue
+			}
+			if result.Sign() == 0 || n.Sign() == 0 {
+				result.SetInt64(0)
+				continue
+			}
+			g := new(big.Int).GCD(nil, nil, result, n)
+			result.Mul(result.Quo(result, g), n)
+		}
+		return new(big.Rat).SetInt(result), nil
+	case "factorial":
+		if x.Sign() < 0 {
+			return nil, errors.New("factorial of negative number")
+		}
+		n, err := ratIntegers(name, args)
+		if err != nil {
+			return nil, err
+		}
+		if !n[0].IsInt64() || n[0].Int64() > maxFactorial {
+			return nil, newError("factorial argument is greater than %d", maxFactorial)
+		}
+		return new(big.Rat).SetInt(new(big.Int).MulRange(1, n[0].Int64())), nil
+	case "nCr", "nPr":
+		if x.Sign() < 0 || args[1].Sign() < 0 {
+			return nil, newError("%s of negative number", name)
+		}
+		ints, err := ratIntegers(name, args)
+		if err != nil {
+			return nil, err
+		}
+		n, k := ints[0], ints[1]
+		if k.Cmp(n) > 0 {
+			return new(big.Rat), nil
+		}
+		if name == "nCr" && new(big.Int).Sub(n, k).Cmp(k) < 0 {
+			k = new(big.Int).Sub(n, k)
+		}
+		if !n.IsInt64() || k.Int64() > maxFactorial {
+			return nil, newError("%s needs more than %d factors", name, maxFactorial)
+		}
+		if name == "nCr" {
+			return new(big.Rat).SetInt(new(big.Int).Binomial(n.Int64(), k.Int64())), nil
+		}
+		return new(big.Rat).SetInt(new(big.Int).MulRange(n.Int64()-k.Int64()+1, n.Int64())), nil
+	}
+	return ratStats(name, args)
+}
+
+// ratInt округляет дробь до целого вниз (floor), вверх (ceil) или к нулю (trunc)
+func ratInt(mode string, x *big.Rat) *big.Int {
+	// Quo округляет к нулю
+	q := new(big.Int).Quo(x.Num(), x.Denom())
+	if x.IsInt() {
+		return q
+	}
+	if mode == "floor" && x.Sign() < 0 {
+		q.Sub(q, big.NewInt(1))
+	}
+	if mode == "ceil" && x.Sign() > 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	return q
+}
+
+// ratRound округляет до digits десятичных знаков, половины - от нуля
+func ratRound(x, digits *big.Rat) (*big.Rat, error) {
+	if !digits.IsInt() {
+		return nil, errors.New("number of digits must be an integer")
+	}
+	if !digits.Num().IsInt64() || digits.Num().CmpAbs(big.NewInt(maxRoundDigits)) > 0 {
+		return nil, errors.New("number of digits is too large")
+	}
+	d := digits.Num().Int64()
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(max(d, -d)), nil))
+	if d < 0 {
+		scale.Inv(scale)
+	}
+
+	scaled := new(big.Rat).Mul(x, scale)
+	half := big.NewRat(1, 2)
+	if scaled.Sign() < 0 {
+		half.Neg(half)
+	}
+	rounded := new(big.Rat).SetInt(ratInt("trunc", scaled.Add(scaled, half)))
+	return rounded.Quo(rounded, scale), nil
+}
+
+// ratIntegers проверяет, что аргументы функции name целые
+func ratIntegers(name string, args []*big.Rat) ([]*big.Int, error) {
+	ints := make([]*big.Int, 0, len(args))
+	for _, arg := range args {
+		if !arg.IsInt() {
+			return nil, newError("%s of non-integer number", name)
+		}
+		ints = append(ints, arg.Num())
+	}
+	return ints, nil
+}
@@ -0,0 +1,88 @@
+package calculator
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRational(t *testing.T) {
+	type CaseRational struct {
+		expression string
+		config     CalculatorConfig
+		result     string
+		err        string
+	}
+	mixed := CalculatorConfig{Mode: Rational, MixedFractions: true}
+	inexact := CalculatorConfig{Mode: Rational, AllowInexact: true}
+	cases := []CaseRational{
+		{expression: "0.1 + 0.2", result: "3/10"},
+		{expression: "1/3 + 1/6", result: "1/2"},
+		{expression: "1/3 * 3", result: "1"},
+		{expression: "2.5e-3", result: "1/400"},
+		{expression: "1e400 / 1e399", result: "10"},
+		{expression: "1e-400 * 1e400", result: "1"},
+		{expression: "0.100000000000000000000000000001 * 10", result: "100000000000000000000000000001/100000000000000000000000000000"},
+		{expression: "(2/3)^3", result: "8/27"},
+		{expression: "2^-3", result: "1/8"},
+		{expression: "0^0", result: "1"},
+		{expression: "8^(2/3)", result: "4"},
+		{expression: "(-8)^(1/3)", result: "-2"},
+		{expression: "(9/4)^(-1/2)", result: "2/3"},
+		{expression: "sqrt(16/9)", result: "4/3"},
+		{expression: "max(1/3, 0.3, 2/7)", result: "1/3"},
+		{expression: "2^200", result: "1606938044258990275541962092341162602522202993782792835301376"},
+		{expression: "f(x) = x / 3; a = 1/2; f(a) + a", result: "2/3"},
+		{expression: "7/2", config: mixed, result: "3 1/2"},
+		{expression: "-7/2", config: mixed, result: "-3 1/2"},
+		{expression: "2/7", config: mixed, result: "2/7"},
+		{expression: "14/7", config: mixed, result: "2"},
+		{expression: "sqrt(2)", config: inexact, result: "≈1.4142135623730951"},
+		{expression: "1/2 + sin(0)", config: inexact, result: "≈0.5"},
+		{expression: "1/2 + 1/4", config: inexact, result: "3/4"},
+
+		{expression: "1 / (1/2 - 0.5)", err: "1:1: calculating division: division by zero"},
+		{expression: "0^-1", err: "1:1: calculating power: division by zero"},
+		{expression: "sqrt(2)", err: "1:1: calculating sqrt: result is not exact in rational mode"},
+		{expression: "1 + sin(1)", err: "1:5: calculating sin: result is not exact in rational mode"},
+		{expression: "2 * pi", err: "1:5: constant pi: result is not exact in rational mode"},
+		{expression: "2^0.5", err: "1:1: calculating power: result is not exact in rational mode"},
+		{expression: "2^(2^40)", err: "calculating power: got overflow"},
+		{expression: "sqrt(-4)", config: inexact, err: "calculating sqrt: square root of negative number"},
+		{expression: "(-4)^(1/2)", config: inexact, err: "calculating power: result is not a number"},
+	}
+
+	for _, c := range cases {
+		if c.config.Mode == Float64 {
+			c.config.Mode = Rational
+		}
+		result, err := Evaluate(c.expression, c.config)
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.result, result.String(), c.expression)
+	}
+}
+
+func TestRationalValue(t *testing.T) {
+	result, err := Evaluate("0.1 + 0.2", CalculatorConfig{Mode: Rational})
+	require.NoError(t, err)
+
+	fraction := result.(Fraction)
+	require.True(t, fraction.Exact())
+	require.Equal(t, 0, fraction.Rat().Cmp(big.NewRat(3, 10)))
+
+	value, err := CalculateWithVars("x * 3", CalculatorConfig{Mode: Rational}, map[string]float64{"x": 0.1})
+	require.NoError(t, err)
+	require.Equal(t, 0.3, value)
+
+	mode, err := ParseMode("rational")
+	require.NoError(t, err)
+	require.Equal(t, Rational, mode)
+	_, err = ParseMode("decimal")
+	require.EqualError(t, err, `unknown mode "decimal"`)
+}
@@ -0,0 +1,502 @@
+package calculator
+
+import (
+	"maps"
+	"strings"
+	"unicode"
+)
+
+type Associativity int
+
+const (
+	LeftAssoc Associativity = iota
+	RightAssoc
+)
+
+// Unlimited - значение MaxArgs для функций с любым числом аргументов
+const Unlimited = -1
+
+// Function - функция, доступная в выражениях
+type Function struct {
+	Name    string
+	MinArgs int
+	MaxArgs int
+	Fn      func(args ...float64) (float64, error)
+	// аргументы - углы, зависят от AngleUnits
+	isTrig bool
+	// результат - угол в радианах, переводится в AngleUnits
+	returnsAngle bool
+	// списки в аргументах раскрываются: sum([1, 2], 3) = sum(1, 2, 3)
+	aggregate bool
+}
+
+// Operator - бинарный оператор. Чем больше Precedence, тем сильнее связывание:
+// у встроенных + и - приоритет 5, у * и / - 6, у унарного минуса - 7, у ^ - 8,
+// у ± - 9. Побитовые операторы режима Integer слабее: << и >> - 4, & - 3,
+// xor - 2, | - 1. Приоритет всегда положительный.
+type Operator struct {
+	Symbol        string
+	Precedence    int
+	Associativity Associativity
+	Fn            func(a, b float64) (float64, error)
+	// название для сообщений об ошибках
+	name message
+}
+
+// Calculator - набор функций и операторов, по которому разбираются
+// и вычисляются выражения. Разные экземпляры не влияют друг на друга.
+type Calculator struct {
+	config    CalculatorConfig
+	functions map[string]*Function
+	operators map[string]*Operator
+}
+
+var builtinFunctions = map[string]*Function{}
+var builtinOperators = map[string]*Operator{}
+
+// побитовые операторы есть только в режиме Integer, в остальных режимах
+// их имена свободны
+var bitwiseOperators = map[string]*Operator{}
+
+func init() {
+	registerBuiltinOperator(builtinOperators, "+", 5, LeftAssoc, Add, "addition")
+	registerBuiltinOperator(builtinOperators, "-", 5, LeftAssoc, Sub, "substraction")
+	registerBuiltinOperator(builtinOperators, "*", 6, LeftAssoc, Mul, "multiplication")
+	registerBuiltinOperator(builtinOperators, "/", 6, LeftAssoc, Div, "division")
+	registerBuiltinOperator(builtinOperators, "^", 8, RightAssoc, Pow, "power")
+	registerBuiltinOperator(builtinOperators, "±", 9, LeftAssoc, func(a, b float64) (float64, error) {
+		return 0, errTolerance
+	}, "tolerance")
+
+	// побитовые операторы слабее арифметических
+	registerBuiltinOperator(bitwiseOperators, "|", 1, LeftAssoc, bitwise(func(a, b int64) int64 { return a | b }), "bitwise or")
+	registerBuiltinOperator(bitwiseOperators, "xor", 2, LeftAssoc, bitwise(func(a, b int64) int64 { return a ^ b }), "bitwise xor")
+	registerBuiltinOperator(bitwiseOperators, "&", 3, LeftAssoc, bitwise(func(a, b int64) int64 { return a & b }), "bitwise and")
+	registerBuiltinOperator(bitwiseOperators, "<<", 4, LeftAssoc, shift(true), "left shift")
+	registerBuiltinOperator(bitwiseOperators, ">>", 4, LeftAssoc, shift(false), "right shift")
+
+	registerBuiltinFunction("sqrt", Sqrt, false)
+	registerBuiltinFunction("ln", Ln, false)
+	registerBuiltinFunction("exp", Exp, false)
+	registerBuiltinFunction("sin", func(x float64) (float64, error) { return Sin(x), nil }, true)
+	registerBuiltinFunction("cos", func(x float64) (float64, error) { return Cos(x), nil }, true)
+	registerBuiltinFunction("tg", Tg, true)
+	registerBuiltinFunction("ctg", Cot, true)
+	// названия из разных школ: tg и tan, ctg и cot - одна и та же функция
+	registerBuiltinAlias("tan", "tg")
+	registerBuiltinAlias("cot", "ctg")
+
+	// обратные тригонометрические функции возвращают угол в AngleUnits
+	registerBuiltinFunction("arcsin", Asin, false).returnsAngle = true
+	registerBuiltinFunction("arccos", Acos, false).returnsAngle = true
+	registerBuiltinFunction("arctg", func(x float64) (float64, error) { return Atan(x), nil }, false).returnsAngle = true
+	registerBuiltinFunction("arcctg", func(x float64) (float64, error) { return Acot(x), nil }, false).returnsAngle = true
+	registerBuiltinAlias("asin", "arcsin")
+	registerBuiltinAlias("acos", "arccos")
+	registerBuiltinAlias("atan", "arctg")
+	registerBuiltinAlias("acot", "arcctg")
+	registerBuiltinAlias("arctan", "arctg")
+	registerBuiltinAlias("arccot", "arcctg")
+
+	registerBuiltinFunction("sinh", Sinh, false)
+	registerBuiltinFunction("cosh", Cosh, false)
+	registerBuiltinFunction("tanh", func(x float64) (float64, error) { return Tanh(x), nil }, false)
+	registerBuiltinFunction("coth", Coth, false)
+	registerBuiltinFunction("arsinh", func(x float64) (float64, error) { return Asinh(x), nil }, false)
+	registerBuiltinFunction("arcosh", Acosh, false)
+	registerBuiltinFunction("artanh", Atanh, false)
+	registerBuiltinFunction("arcoth", Acoth, false)
+	registerBuiltinAlias("asinh", "arsinh")
+	registerBuiltinAlias("acosh", "arcosh")
+	registerBuiltinAlias("atanh", "artanh")
+	registerBuiltinAlias("acoth", "arcoth")
+
+	// deg и rad переводят углы независимо от AngleUnits: deg(pi) = 180, rad(180) = pi
+	registerBuiltinFunction("deg", func(x float64) (float64, error) { return Deg(x), nil }, false)
+	registerBuiltinFunction("rad", func(x float64) (float64, error) { return Rad(x), nil }, false)
+
+	// re, im и conj нужны в режиме Complex, для действительных чисел они тривиальны
+	registerBuiltinFunction("re", func(x float64) (float64, error) { return x, nil }, false)
+	registerBuiltinFunction("im", func(x float64) (float64, error) { return 0, nil }, false)
+	registerBuiltinFunction("conj", func(x float64) (float64, error) { return x, nil }, false)
+
+	registerBuiltinFunction("log10", Log10, false)
+	registerBuiltinFunction("log2", Log2, false)
+	registerBuiltinFunction("abs", func(x float64) (float64, error) { return Abs(x), nil }, false)
+	registerBuiltinFunction("floor", func(x float64) (float64, error) { return Floor(x), nil }, false)
+	registerBuiltinFunction("ceil", func(x float64) (float64, error) { return Ceil(x), nil }, false)
+	registerBuiltinFunction("trunc", func(x float64) (float64, error) { return Trunc(x), nil }, false)
+	registerBuiltinFunction("sign", func(x float64) (float64, error) { return Sign(x), nil }, false)
+	registerBuiltinFunction("factorial", Factorial, false)
+
+	// log(x) - десятичный логарифм, log(base, x) - по основанию base
+	registerBuiltinVariadic("log", 1, 2, func(args ...float64) (float64, error) {
+		if len(args) == 1 {
+			return Log10(args[0])
+		}
+		return LogBase(args[0], args[1])
+	})
+	registerBuiltinVariadic("round", 1, 2, func(args ...float64) (float64, error) {
+		if len(args) == 1 {
+			return Round(args[0], 0)
+		}
+		return Round(args[0], args[1])
+	})
+	registerBuiltinVariadic("mod", 2, 2, func(args ...float64) (float64, error) {
+		return Mod(args[0], args[1])
+	})
+	registerBuiltinVariadic("gcd", 2, Unlimited, Gcd)
+	registerBuiltinVariadic("lcm", 2, Unlimited, Lcm)
+	registerBuiltinVariadic("nCr", 2, 2, func(args ...float64) (float64, error) {
+		return NCr(args[0], args[1])
+	})
+	registerBuiltinVariadic("nPr", 2, 2, func(args ...float64) (float64, error) {
+		return NPr(args[0], args[1])
+	})
+	registerBuiltinVariadic("atan2", 2, 2, func(args ...float64) (float64, error) {
+		return Atan2(args[0], args[1]), nil
+	}).returnsAngle = true
+	registerBuiltinVariadic("max", 1, Unlimited, func(args ...float64) (float64, error) {
+		return Max(args...), nil
+	}).aggregate = true
+	registerBuiltinVariadic("min", 1, Unlimited, func(args ...float64) (float64, error) {
+		return Min(args...), nil
+	}).aggregate = true
+
+	// статистика: var и stdev - выборочные, varp и stdevp - по генеральной совокупности
+	registerBuiltinVariadic("sum", 1, Unlimited, Sum).aggregate = true
+	registerBuiltinVariadic("mean", 1, Unlimited, Mean).aggregate = true
+	registerBuiltinVariadic("median", 1, Unlimited, func(args ...float64) (float64, error) {
+		return Median(args...), nil
+	}).aggregate = true
+	registerBuiltinVariadic("mode", 1, Unlimited, func(args ...float64) (float64, error) {
+		return Modal(args...), nil
+	}).aggregate = true
+	registerBuiltinVariadic("var", 2, Unlimited, func(args ...float64) (float64, error) {
+		return Variance(false, args...)
+	}).aggregate = true
+	registerBuiltinVariadic("varp", 1, Unlimited, func(args ...float64) (float64, error) {
+		return Variance(true, args...)
+	}).aggregate = true
+	registerBuiltinVariadic("stdev", 2, Unlimited, func(args ...float64) (float64, error) {
+		return Stdev(false, args...)
+	}).aggregate = true
+	registerBuiltinVariadic("stdevp", 1, Unlimited, func(args ...float64) (float64, error) {
+		return Stdev(true, args...)
+	}).aggregate = true
+	registerBuiltinVariadic("percentile", 2, Unlimited, func(args ...float64) (float64, error) {
+		return Percentile(args[0], args[1:]...)
+	}).aggregate = true
+	registerBuiltinVariadic("geomean", 1, Unlimited, Geomean).aggregate = true
+	registerBuiltinVariadic("harmean", 1, Unlimited, Harmean).aggregate = true
+}
+
+func registerBuiltinOperator(table map[string]*Operator, symbol string, precedence int, assoc Associativity, fn func(a, b float64) (float64, error), name string) {
+	table[symbol] = &Operator{Symbol: symbol, Precedence: precedence, Associativity: assoc, Fn: fn, name: message{format: name}}
+}
+
+func registerBuiltinFunction(name string, fn func(float64) (float64, error), isTrig bool) *Function {
+	f := &Function{
+		Name:    name,
+		MinArgs: 1,
+		MaxArgs: 1,
+		Fn:      func(args ...float64) (float64, error) { return fn(args[0]) },
+		isTrig:  isTrig,
+	}
+	builtinFunctions[name] = f
+	return f
+}
+
+// registerBuiltinAlias добавляет другое имя встроенной функции. Домены
+// получают имя из Function.Name, поэтому псевдоним вычисляется так же.
+func registerBuiltinAlias(alias, name string) {
+	builtinFunctions[alias] = builtinFunctions[name]
+}
+
+func registerBuiltinVariadic(name string, minArgs, maxArgs int, fn func(args ...float64) (float64, error)) *Function {
+	f := &Function{Name: name, MinArgs: minArgs, MaxArgs: maxArgs, Fn: fn}
+	builtinFunctions[name] = f
+	return f
+}
+
+// defaultCalculator используется функциями уровня пакета; встроенные
+// таблицы не копируются, поэтому регистрировать в нём ничего нельзя
+func defaultCalculator(config CalculatorConfig) *Calculator {
+	return &Calculator{config: config, functions: builtinFunctions, operators: builtinOperators}
+}
+
+// arityError возвращает сообщение об ошибке, если функция не принимает
+// n аргументов. name - имя из вызова: у синонима оно отличается от f.Name.
+func (f *Function) arityError(name string, n int) (message, bool) {
+	switch {
+	case f.MinArgs == f.MaxArgs && n != f.MinArgs:
+		return expectsArgs(name, f.MinArgs, n), true
+	case f.MaxArgs == Unlimited && n < f.MinArgs:
+		if f.MinArgs == 1 {
+			return message{"function %s expects at least 1 argument, got %d", []any{name, n}}, true
+		}
+		return message{"function %s expects at least %d arguments, got %d", []any{name, f.MinArgs, n}}, true
+	case n < f.MinArgs || (f.MaxArgs != Unlimited && n > f.MaxArgs):
+		return message{"function %s expects %d to %d arguments, got %d", []any{name, f.MinArgs, f.MaxArgs, n}}, true
+	}
+	return message{}, false
+}
+
+// callArgs раскрывает списки в аргументах агрегатной функции f. В режиме
+// Interval список [a, b] - это интервал, там аргументы не меняются.
+func (c *Calculator) callArgs(f *Function, args []Node) []Node {
+	if !f.aggregate || c.config.Mode == Interval {
+		return args
+	}
+	flat := make([]Node, 0, len(args))
+	for _, arg := range args {
+		if list, ok := arg.(*List); ok {
+			flat = append(flat, list.Elems...)
+			continue
+		}
+		flat = append(flat, arg)
+	}
+	return flat
+}
+
+// NewCalculator создаёт калькулятор со встроенными функциями и операторами
+func NewCalculator(config CalculatorConfig) *Calculator {
+	return &Calculator{
+		config:    config,
+		functions: maps.Clone(builtinFunctions),
+		operators: maps.Clone(builtinOperators),
+	}
+}
+
+// RegisterFunction добавляет функцию name с фиксированным числом аргументов
+// или заменяет уже существующую
+func (c *Calculator) RegisterFunction(name string, arity int, fn func(args ...float64) (float64, error)) error {
+	if arity < 0 {
+		return newError("invalid arity %d for function %s", arity, name)
+	}
+	return c.RegisterVariadicFunction(name, arity, arity, fn)
+}
+
+// RegisterVariadicFunction добавляет функцию, принимающую от minArgs до maxArgs
+// аргументов; maxArgs = Unlimited снимает ограничение сверху
+func (c *Calculator) RegisterVariadicFunction(name string, minArgs, maxArgs int, fn func(args ...float64) (float64, error)) error {
+	if !isIdentifier(name) {
+		return newError("invalid function name %q", name)
+	}
+	if c.isConstant(name) {
+		return newError("cannot redefine constant %s", name)
+	}
+	if _, ok := c.operator(name); ok {
+		return newError("%s is already an operator", name)
+	}
+	if minArgs < 0 || (maxArgs != Unlimited && maxArgs < minArgs) {
+		return newError("invalid arity %d..%d for function %s", minArgs, maxArgs, name)
+	}
+	if fn == nil {
+		return newError("function %s has no implementation", name)
+	}
+
+	c.functions[name] = &Function{Name: name, MinArgs: minArgs, MaxArgs: maxArgs, Fn: fn}
+	return nil
+}
+
+// RegisterOperator добавляет бинарный оператор или заменяет уже существующий.
+// Символ оператора - либо последовательность знаков вроде "%" или "**",
+// либо слово вроде "mod".
+func (c *Calculator) RegisterOperator(symbol string, precedence int, assoc Associativity, fn func(a, b float64) (float64, error)) error {
+	if !isIdentifier(symbol) && !isOperatorSymbol(symbol) {
+		return newError("invalid operator symbol %q", symbol)
+	}
+	if c.isConstant(symbol) {
+		return newError("cannot redefine constant %s", symbol)
+	}
+	if _, ok := c.functions[symbol]; ok {
+		return newError("%s is already a function", symbol)
+	}
+	if precedence <= 0 {
+		return newError("operator precedence must be positive, got %d", precedence)
+	}
+	if assoc != LeftAssoc && assoc != RightAssoc {
+		return newError("invalid associativity for operator %s", symbol)
+	}
+	if fn == nil {
+		return newError("operator %s has no implementation", symbol)
+	}
+
+	c.operators[symbol] = &Operator{
+		Symbol:        symbol,
+		Precedence:    precedence,
+		Associativity: assoc,
+		Fn:            fn,
+		name:          message{"operator %s", []any{symbol}},
+	}
+	return nil
+}
+
+// Functions возвращает зарегистрированные функции
+func (c *Calculator) Functions() map[string]*Function {
+	return maps.Clone(c.functions)
+}
+
+// Operators возвращает бинарные операторы, доступные в режиме калькулятора
+func (c *Calculator) Operators() map[string]*Operator {
+	operators := maps.Clone(c.operators)
+	if c.config.Mode == Integer {
+		for symbol, op := range bitwiseOperators {
+			if _, ok := operators[symbol]; !ok {
+				operators[symbol] = op
+			}
+		}
+	}
+	return operators
+}
+
+// operator ищет бинарный оператор. Побитовые операторы есть только в режиме
+// Integer; зарегистрированный с тем же символом оператор их заменяет.
+func (c *Calculator) operator(symbol string) (*Operator, bool) {
+	if op, ok := c.operators[symbol]; ok {
+		return op, true
+	}
+	if c.config.Mode == Integer {
+		op, ok := bitwiseOperators[symbol]
+		return op, ok
+	}
+	return nil, false
+}
+
+// isBuiltin сообщает, что оператор встроенный, а не заменён через RegisterOperator
+func (op *Operator) isBuiltin() bool {
+	return op == builtinOperators[op.Symbol] || op == bitwiseOperators[op.Symbol]
+}
+
+func (c *Calculator) Parse(expression string) (Node, error) {
+	node, err := c.parse(expression)
+	if err != nil {
+		return nil, locateError(err, expression)
+	}
+	return node, nil
+}
+
+func (c *Calculator) Compile(expression string) (*Program, error) {
+	tree, err := c.Parse(expression)
+	if err != nil {
+		return nil, newError("%w: %w", errParsing, err)
+	}
+
+	return c.compileTree(tree, expression, nil)
+}
+
+func (c *Calculator) Calculate(expression string) (float64, error) {
+	return c.CalculateWithVars(expression, nil)
+}
+
+func (c *Calculator) CalculateWithVars(expression string, vars map[string]float64) (float64, error) {
+	if c.usesProgram() {
+		program, err := c.Compile(expression)
+		if err != nil {
+			return 0, err
+		}
+		return program.Eval(vars)
+	}
+
+	value, err := c.evaluate(expression, vars)
+	if err != nil {
+		return 0, err
+	}
+	result, ok := value.Float64()
+	if !ok {
+		return 0, newError("%w: result %s does not fit in float64", errCalculating, value)
+	}
+	return result, nil
+}
+
+// Evaluate вычисляет выражение в режиме, заданном конфигурацией калькулятора
+func (c *Calculator) Evaluate(expression string) (Value, error) {
+	return c.evaluate(expression, nil)
+}
+
+func (c *Calculator) evaluate(expression string, vars map[string]float64) (Value, error) {
+	tree, err := c.Parse(expression)
+	if err != nil {
+		return nil, newError("%w: %w", errParsing, err)
+	}
+
+	if !c.usesProgram() {
+		s := &Session{calc: c, vars: vars, funcs: map[string]*FuncDef{}}
+		result, hasValue, err := s.evaluateTree(tree, expression, false)
+		if err != nil {
+			return nil, err
+		}
+		if !hasValue {
+			return nil, newError("%w: expression has no value", errCalculating)
+		}
+		return result, nil
+	}
+
+	program, err := c.compileTree(tree, expression, nil)
+	if err != nil {
+		return nil, err
+	}
+	result, err := program.Eval(vars)
+	if err != nil {
+		return nil, err
+	}
+	return Float(result), nil
+}
+
+// usesProgram сообщает, вычисляются ли выражения в float64 через Program
+func (c *Calculator) usesProgram() bool {
+	return c.config.Mode == Float64 && c.config.Precision == 0
+}
+
+// NewSession создаёт сессию, в которой доступны функции и операторы калькулятора
+func (c *Calculator) NewSession() *Session {
+	return &Session{
+		calc:   c,
+		vars:   map[string]float64{},
+		funcs:  map[string]*FuncDef{},
+		values: map[string]sessionValue{},
+	}
+}
+
+// symbols возвращает операторы-знаки для лексера, в режиме Integer -
+// включая унарный ~
+func (c *Calculator) symbols() []string {
+	symbols := []string{"°", "!"}
+	if c.config.Mode == Integer {
+		symbols = append(symbols, "~")
+	}
+	for symbol := range c.operators {
+		if isOperatorSymbol(symbol) {
+			symbols = append(symbols, symbol)
+		}
+	}
+	if c.config.Mode == Integer {
+		for symbol := range bitwiseOperators {
+			if isOperatorSymbol(symbol) {
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	return symbols
+}
+
+func isIdentifier(name string) bool {
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// isOperatorSymbol проверяет, что символ состоит из знаков,
+// не занятых синтаксисом выражений
+func isOperatorSymbol(symbol string) bool {
+	for _, r := range symbol {
+		if !unicode.IsPunct(r) && !unicode.IsSymbol(r) || strings.ContainsRune("().,;=_", r) {
+			return false
+		}
+	}
+	return symbol != ""
+}
@@ -0,0 +1,154 @@
+package calculator
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Session хранит переменные и пользовательские функции между вычислениями
+type Session struct {
+	calc  *Calculator
+	vars  map[string]float64
+	funcs map[string]*FuncDef
+	// значения переменных в режимах без Program: дроби, интервалы и т.д.
+	values map[string]sessionValue
+}
+
+// sessionValue - значение домена и его вид для вывода
+type sessionValue struct {
+	raw   any
+	value Value
+}
+
+// Eval вычисляет ввод в контексте сессии. Присвоенные переменные и
+// определённые функции сохраняются, только если вычисление прошло без ошибок.
+// hasValue равен false, если ввод состоял из одних определений функций.
+func (s *Session) Eval(input string) (result float64, hasValue bool, err error) {
+	program, err := s.compile(input)
+	if err != nil {
+		return 0, false, err
+	}
+
+	stack := make([]float64, program.stackSize)
+	if err := program.runInto(stack, s.vars); err != nil {
+		return 0, false, err
+	}
+
+	for _, slot := range program.assigned {
+		s.vars[program.globals[slot]] = stack[slot]
+	}
+	for _, def := range program.defs {
+		s.funcs[def.Name] = def
+	}
+
+	if !program.hasValue {
+		return 0, false, nil
+	}
+	return program.result(stack), true, nil
+}
+
+// Evaluate вычисляет ввод в режиме сессии, как Calculator.Evaluate.
+// Результат запоминается в переменных ans и _.
+func (s *Session) Evaluate(input string) (result Value, hasValue bool, err error) {
+	if s.calc.usesProgram() {
+		x, hasValue, err := s.Eval(input)
+		if err != nil || !hasValue {
+			return nil, false, err
+		}
+		s.vars["ans"], s.vars["_"] = x, x
+		return Float(x), true, nil
+	}
+
+	tree, err := s.calc.Parse(input)
+	if err != nil {
+		return nil, false, newError("%w: %w", errParsing, err)
+	}
+	return s.evaluateTree(tree, input, true)
+}
+
+// evaluateTree вычисляет дерево в домене режима калькулятора сессии
+func (s *Session) evaluateTree(tree Node, source string, persist bool) (Value, bool, error) {
+	config := s.calc.config
+	switch config.Mode {
+	case Integer:
+		dom, err := newIntegerDomain(config)
+		if err != nil {
+			return nil, false, err
+		}
+		return evaluateTree(s, dom, tree, source, persist)
+	case Units:
+		return evaluateTree(s, newUnitsDomain(config), tree, source, persist)
+	case Uncertainty:
+		return evaluateTree(s, newUncertaintyDomain(config), tree, source, persist)
+	case Interval:
+		return evaluateTree(s, newIntervalDomain(config), tree, source, persist)
+	case Complex:
+		return evaluateTree(s, newComplexDomain(config), tree, source, persist)
+	case Rational:
+		return evaluateTree(s, newRationalDomain(config), tree, source, persist)
+	}
+	return evaluateTree(s, newBigDomain(config), tree, source, persist)
+}
+
+// setValue сохраняет значение домена; в vars остаётся его float64-приближение
+func (s *Session) setValue(name string, raw any, value Value) {
+	s.values[name] = sessionValue{raw: raw, value: value}
+	if x, ok := value.Float64(); ok {
+		s.vars[name] = x
+	} else {
+		delete(s.vars, name)
+	}
+}
+
+// Config возвращает настройки, в которых сессия вычисляет выражения
+func (s *Session) Config() CalculatorConfig {
+	return s.calc.config
+}
+
+// SetConfig меняет настройки сессии, функции и операторы калькулятора
+// сохраняются. Значения переменных из другого режима не имеют смысла,
+// поэтому остаются только их float64-приближения.
+func (s *Session) SetConfig(config CalculatorConfig) {
+	s.calc = &Calculator{config: config, functions: s.calc.functions, operators: s.calc.operators}
+	clear(s.values)
+}
+
+func (s *Session) compile(input string) (*Program, error) {
+	tree, err := s.calc.Parse(input)
+	if err != nil {
+		return nil, newError("%w: %w", errParsing, err)
+	}
+	return s.calc.compileTree(tree, input, s.funcs)
+}
+
+// Set задаёт значение переменной сессии
+func (s *Session) Set(name string, value float64) {
+	s.vars[name] = value
+}
+
+// Variables возвращает копию переменных сессии
+func (s *Session) Variables() map[string]float64 {
+	return maps.Clone(s.vars)
+}
+
+// Values возвращает значения переменных сессии в её режиме
+func (s *Session) Values() map[string]Value {
+	values := make(map[string]Value, len(s.vars)+len(s.values))
+	for name, x := range s.vars {
+		values[name] = Float(x)
+	}
+	for name, v := range s.values {
+		values[name] = v.value
+	}
+	return values
+}
+
+// Functions возвращает определённые в сессии функции, отсортированные по имени
+func (s *Session) Functions() []*FuncDef {
+	defs := slices.Collect(maps.Values(s.funcs))
+	slices.SortFunc(defs, func(a, b *FuncDef) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return defs
+}
@@ -0,0 +1,125 @@
+package calculator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserFunctions(t *testing.T) {
+	type CaseUserFunctions struct {
+		expression string
+		result     float64
+		err        string
+	}
+	cases := []CaseUserFunctions{
+		{expression: "f(x, y) = x^2 + y; f(3, 4)", result: 13},
+		{expression: "f(x) = x * 2; g(x) = f(x) + f(1); g(5)", result: 12},
+		{expression: "sq(x) = x*x; sq(sq(sq(2)))", result: 256},
+		{expression: "c() = 42; c() + 1", result: 43},
+		{expression: "a = 2; f(x) = x + a; a = 5; f(1)", result: 6},
+		{expression: "x = 10; f(x) = x + 1; f(1) + x", result: 12},
+		{expression: "f(x) = x + 1; g(x) = f(x) * 2; f(x) = x + 100; g(1)", result: 202},
+		{expression: "f(x) = sin(x) + 1; f(90)", result: 2},
+
+		{expression: "f(x, y) = x + y; f(1)", err: "1:18: function f expects 2 arguments, got 1"},
+		{expression: "sin(1, 2)", err: "1:1: function sin expects 1 argument, got 2"},
+		{expression: "g(2)", err: "1:1: unknown function g"},
+		{expression: "f(x) = f(x) + 1", err: "recursive definition of f: f -> f"},
+		{expression: "g(x) = 1; f(x) = g(x); g(x) = f(x)", err: "recursive definition of g: g -> f -> g"},
+		{expression: "f(x) = x + y; f(1)", err: "1:15: undefined variable y"},
+		{expression: "f(x) = 1 / x; f(0)", err: "1:8: calculating division: division by zero"},
+		{expression: "f(x, x) = x", err: "duplicate parameter x"},
+		{expression: "sin(x) = x", err: "cannot redefine built-in function sin"},
+		{expression: "f(pi) = 1", err: "cannot use constant pi as parameter"},
+		{expression: "f(x) = 1", err: "expression has no value"},
+	}
+
+	for _, c := range cases {
+		result, err := Calculate(c.expression, CalculatorConfig{AngleUnits: "degree"})
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.result, result, c.expression)
+	}
+}
+
+func TestSession(t *testing.T) {
+	s := NewSession(CalculatorConfig{AngleUnits: "radian"})
+
+	_, hasValue, err := s.Eval("f(x, y) = x^2 + y")
+	require.NoError(t, err)
+	require.False(t, hasValue)
+
+	result, hasValue, err := s.Eval("a = f(3, 4)")
+	require.NoError(t, err)
+	require.True(t, hasValue)
+	require.Equal(t, 13.0, result)
+
+	_, _, err = s.Eval("g(x) = f(x, a) / k")
+	require.NoError(t, err)
+
+	_, _, err = s.Eval("g(1)")
+	require.ErrorContains(t, err, "undefined variable k")
+
+	s.Set("k", 2)
+	result, _, err = s.Eval("g(1)")
+	require.NoError(t, err)
+	require.Equal(t, 7.0, result)
+
+	// неудачное вычисление не меняет состояние сессии
+	_, _, err = s.Eval("a = 100; h(x) = x; 1/0")
+	require.Error(t, err)
+
+	require.Equal(t, map[string]float64{"a": 13, "k": 2}, s.Variables())
+
+	var defs []string
+	for _, def := range s.Functions() {
+		defs = append(defs, def.String())
+	}
+	require.Equal(t, []string{"f(x, y) = x ^ 2 + y", "g(x) = f(x, a) / k"}, defs)
+
+	_, _, err = s.Eval("f(x, y) = g(x)")
+	require.ErrorContains(t, err, "recursive definition of f: f -> g -> f")
+}
+
+func TestSessionModes(t *testing.T) {
+	s := NewSession(CalculatorConfig{Mode: Rational})
+
+	result, _, err := s.Evaluate("f(y) = y + x; x = 1/3")
+	require.NoError(t, err)
+	require.Equal(t, "1/3", result.String())
+
+	result, _, err = s.Evaluate("f(1/6) * ans")
+	require.NoError(t, err)
+	require.Equal(t, "1/6", result.String())
+
+	result, _, err = s.Evaluate("_ + x")
+	require.NoError(t, err)
+	require.Equal(t, "1/2", result.String())
+
+	_, _, err = s.Evaluate("x = 5; 1/0")
+	require.Error(t, err)
+	require.Equal(t, "1/3", s.Values()["x"].String())
+
+	// при смене режима остаются float64-приближения переменных
+	s.SetConfig(CalculatorConfig{AngleUnits: Degree})
+	result, _, err = s.Evaluate("x * 3 + sin(30) + f(0)")
+	require.NoError(t, err)
+	require.InDelta(t, 1.8333333333333333, float64(result.(Float)), 1e-15)
+	require.Equal(t, Degree, s.Config().AngleUnits)
+
+	s.SetConfig(CalculatorConfig{Mode: Complex})
+	_, _, err = s.Evaluate("z = 1 + i")
+	require.NoError(t, err)
+	s.SetConfig(CalculatorConfig{})
+	_, _, err = s.Evaluate("z")
+	require.ErrorContains(t, err, "undefined variable z")
+
+	_, hasValue, err := s.Evaluate("g(y) = y")
+	require.NoError(t, err)
+	require.False(t, hasValue)
+}
@@ -0,0 +1,257 @@
+package calculator
+
+import (
+	"math"
+	"math/big"
+	"slices"
+)
+
+// Sum складывает с компенсацией ошибок округления (алгоритм Ноймайера):
+// sum(1e100, 1, -1e100) = 1, а не 0
+func Sum(args ...float64) (float64, error) {
+	sum, c := 0.0, 0.0
+	for _, x := range args {
+		t := sum + x
+		if math.Abs(sum) >= math.Abs(x) {
+			c += (sum - t) + x
+		} else {
+			c += (x - t) + sum
+		}
+		sum = t
+	}
+	result := sum + c
+	if math.IsInf(sum, 0) || math.IsInf(result, 0) || math.IsNaN(result) {
+		return 0, errOverflow
+	}
+	return result, nil
+}
+
+func Mean(args ...float64) (float64, error) {
+	sum, err := Sum(args...)
+	if err != nil {
+		// сумма переполнилась, а среднее может и поместиться
+		mean, _ := welford(args)
+		if math.IsInf(mean, 0) || math.IsNaN(mean) {
+			return 0, err
+		}
+		return mean, nil
+	}
+	return sum / float64(len(args)), nil
+}
+
+// welford возвращает среднее и сумму квадратов отклонений от него за один
+// проход без вычитания близких больших чисел (алгоритм Уэлфорда)
+func welford(args []float64) (mean, m2 float64) {
+	for i, x := range args {
+		delta := x - mean
+		mean += delta / float64(i+1)
+		m2 += delta * (x - mean)
+	}
+	return mean, m2
+}
+
+// Variance - выборочная дисперсия (делитель n-1), при population -
+// дисперсия генеральной совокупности (делитель n)
+func Variance(population bool, args ...float64) (float64, error) {
+	_, m2 := welford(args)
+	if math.IsInf(m2, 0) || math.IsNaN(m2) {
+		return 0, errOverflow
+	}
+	if population {
+		return m2 / float64(len(args)), nil
+	}
+	return m2 / float64(len(args)-1), nil
+}
+
+func Stdev(population bool, args ...float64) (float64, error) {
+	v, err := Variance(population, args...)
+	if err != nil {
+		return 0, err
+	}
+	return math.Sqrt(v), nil
+}
+
+func Median(args ...float64) float64 {
+	return percentile(0.5, sorted(args))
+}
+
+// Modal возвращает моду - самое частое значение, из нескольких таких - наименьшее.
+// Имя Mode занято режимом вычисления.
+func Modal(args ...float64) float64 {
+	xs := sorted(args)
+	result, count := xs[0], 0
+	for i := 0; i < len(xs); {
+		j := i
+		for j < len(xs) && xs[j] == xs[i] {
+			j++
+		}
+		if j-i > count {
+			result, count = xs[i], j-i
+		}
+		i = j
+	}
+	return result
+}
+
+// Percentile - p-й процентиль с линейной интерполяцией между соседними
+// значениями, как PERCENTILE в электронных таблицах: percentile(50, ...) - медиана
+func Percentile(p float64, args ...float64) (float64, error) {
+	if p < 0 || p > 100 {
+		return 0, newError("percentile must be between 0 and 100")
+	}
+	return percentile(p/100, sorted(args)), nil
+}
+
+// percentile интерполирует между соседними значениями упорядоченного xs,
+// q - доля от 0 до 1
+func percentile(q float64, xs []float64) float64 {
+	h := q * float64(len(xs)-1)
+	i := int(h)
+	if i == len(xs)-1 {
+		return xs[i]
+	}
+	// a + t*(b-a) не переполняется, в отличие от (1-t)*a + t*b
+	return xs[i] + (h-float64(i))*(xs[i+1]-xs[i])
+}
+
+func sorted(args []float64) []float64 {
+	xs := slices.Clone(args)
+	slices.Sort(xs)
+	return xs
+}
+
+// Geomean - среднее геометрическое через среднее логарифмов, так
+// произведение большого числа значений не переполняется
+func Geomean(args ...float64) (float64, error) {
+	logs := make([]float64, 0, len(args))
+	for _, x := range args {
+		if x <= 0 {
+			return 0, newError("geometric mean of non-positive number")
+		}
+		logs = append(logs, math.Log(x))
+	}
+	mean, err := Mean(logs...)
+	if err != nil {
+		return 0, err
+	}
+	return Exp(mean)
+}
+
+// Harmean - среднее гармоническое; если среди значений есть ноль, оно равно нулю
+func Harmean(args ...float64) (float64, error) {
+	inverses := make([]float64, 0, len(args))
+	for _, x := range args {
+		if x < 0 {
+			return 0, newError("harmonic mean of negative number")
+		}
+		if x == 0 {
+			return 0, nil
+		}
+		inverses = append(inverses, 1/x)
+	}
+	sum, err := Sum(inverses...)
+	if err != nil {
+		return 0, err
+	}
+	return float64(len(args)) / sum, nil
+}
+
+// ratStats вычисляет точно статистические функции с рациональным
+// результатом. stdev - только если дисперсия - точный квадрат.
+func ratStats(name string, args []*big.Rat) (*big.Rat, error) {
+	n := big.NewRat(int64(len(args)), 1)
+	switch name {
+	case "sum":
+		return ratSum(args), nil
+	case "mean":
+		sum := ratSum(args)
+		return sum.Quo(sum, n), nil
+	case "var", "varp":
+		return ratVariance(name == "varp", args), nil
+	case "stdev", "stdevp":
+		if root, ok := ratRoot(ratVariance(name == "stdevp", args), 2); ok {
+			return root, nil
+		}
+	case "median":
+		return ratPercentile(big.NewRat(1, 2), ratSorted(args)), nil
+	case "percentile":
+		p := args[0]
+		if p.Sign() < 0 || p.Cmp(big.NewRat(100, 1)) > 0 {
+			return nil, newError("percentile must be between 0 and 100")
+		}
+		return ratPercentile(new(big.Rat).Quo(p, big.NewRat(100, 1)), ratSorted(args[1:])), nil
+	case "mode":
+		xs := ratSorted(args)
+		result, count := xs[0], 0
+		for i := 0; i < len(xs); {
+			j := i
+			for j < len(xs) && xs[j].Cmp(xs[i]) == 0 {
+				j++
+			}
+			if j-i > count {
+				result, count = xs[i], j-i
+			}
+			i = j
+		}
+		return new(big.Rat).Set(result), nil
+	case "harmean":
+		inverses := make([]*big.Rat, 0, len(args))
+		for _, x := range args {
+			if x.Sign() < 0 {
+				return nil, newError("harmonic mean of negative number")
+			}
+			if x.Sign() == 0 {
+				return new(big.Rat), nil
+			}
+			inverses = append(inverses, new(big.Rat).Inv(x))
+		}
+		return n.Quo(n, ratSum(inverses)), nil
+	case "geomean":
+		for _, x := range args {
+			if x.Sign() <= 0 {
+				return nil, newError("geometric mean of non-positive number")
+			}
+		}
+	}
+	return nil, errUnsupported
+}
+
+func ratSum(args []*big.Rat) *big.Rat {
+	sum := new(big.Rat)
+	for _, x := range args {
+		sum.Add(sum, x)
+	}
+	return sum
+}
+
+func ratVariance(population bool, args []*big.Rat) *big.Rat {
+	mean := ratSum(args)
+	mean.Quo(mean, big.NewRat(int64(len(args)), 1))
+	m2 := new(big.Rat)
+	for _, x := range args {
+		d := new(big.Rat).Sub(x, mean)
+		m2.Add(m2, d.Mul(d, d))
+	}
+	if population {
+		return m2.Quo(m2, big.NewRat(int64(len(args)), 1))
+	}
+	return m2.Quo(m2, big.NewRat(int64(len(args)-1), 1))
+}
+
+func ratSorted(args []*big.Rat) []*big.Rat {
+	xs := slices.Clone(args)
+	slices.SortFunc(xs, func(a, b *big.Rat) int { return a.Cmp(b) })
+	return xs
+}
+
+// ratPercentile - percentile для дробей, q - доля от 0 до 1
+func ratPercentile(q *big.Rat, xs []*big.Rat) *big.Rat {
+	h := new(big.Rat).Mul(q, big.NewRat(int64(len(xs)-1), 1))
+	i := int(ratInt("floor", h).Int64())
+	if i == len(xs)-1 {
+		return new(big.Rat).Set(xs[i])
+	}
+	t := h.Sub(h, big.NewRat(int64(i), 1))
+	result := new(big.Rat).Sub(xs[i+1], xs[i])
+	return result.Add(xs[i], result.Mul(result, t))
+}
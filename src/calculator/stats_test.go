@@ -0,0 +1,135 @@
+package calculator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatistics(t *testing.T) {
+	type CaseStats struct {
+		expression string
+		result     float64
+		err        string
+	}
+	cases := []CaseStats{
+		{expression: "sum(1, 2, 3.5)", result: 6.5},
+		{expression: "sum([1, 2, 3])", result: 6},
+		{expression: "sum([1, 2], 3, [4])", result: 10},
+		{expression: "mean(1, 2, 3, 4)", result: 2.5},
+		{expression: "median(3, 1, 2)", result: 2},
+		{expression: "median([4, 1, 3, 2])", result: 2.5},
+		{expression: "mode(1, 3, 3, 2, 2)", result: 2},
+		{expression: "mode(5)", result: 5},
+		{expression: "var(2, 4, 4, 4, 5, 5, 7, 9)", result: 32.0 / 7},
+		{expression: "varp(2, 4, 4, 4, 5, 5, 7, 9)", result: 4},
+		{expression: "stdev([1, 2, 3, 4])", result: math.Sqrt(5.0 / 3)},
+		{expression: "stdevp([2, 4, 4, 4, 5, 5, 7, 9])", result: 2},
+		{expression: "varp(7)", result: 0},
+		{expression: "percentile(0, 5, 1, 3)", result: 1},
+		{expression: "percentile(100, [5, 1, 3])", result: 5},
+		{expression: "percentile(90, [1, 2, 3, 4, 5])", result: 4.6},
+		{expression: "percentile(50, 1, 2, 3, 4)", result: 2.5},
+		{expression: "geomean(2, 8)", result: 4},
+		{expression: "geomean(1e300, 1e300)", result: 1e300},
+		{expression: "harmean(1, 2, 4)", result: 12.0 / 7},
+		{expression: "harmean(1, 0)", result: 0},
+		{expression: "min([3, 1], 2) + max([3, 1], 2)", result: 4},
+		{expression: "mean([1, 2, 3]) + sum(1)", result: 3},
+		// компенсированное суммирование и алгоритм Уэлфорда
+		{expression: "sum(1e100, 1, -1e100)", result: 1},
+		{expression: "sum(0.1, 0.2, 0.3) - 0.6", result: 0},
+		{expression: "mean(1e308, 1e308)", result: 1e308},
+		{expression: "var(1e9 + 4, 1e9 + 7, 1e9 + 13, 1e9 + 16)", result: 30},
+
+		{expression: "sum()", err: "error while parsing: 1:1: function sum expects at least 1 argument, got 0"},
+		{expression: "mean([])", err: "function mean expects at least 1 argument, got 0"},
+		{expression: "var(1)", err: "function var expects at least 2 arguments, got 1"},
+		{expression: "stdev([1])", err: "function stdev expects at least 2 arguments, got 1"},
+		{expression: "percentile(50)", err: "function percentile expects at least 2 arguments, got 1"},
+		{expression: "percentile(101, 1)", err: "1:1: calculating percentile: percentile must be between 0 and 100"},
+		{expression: "geomean(1, -2)", err: "calculating geomean: geometric mean of non-positive number"},
+		{expression: "harmean(1, -2)", err: "calculating harmean: harmonic mean of negative number"},
+		{expression: "sum(1e308, 1e308)", err: "calculating sum: got overflow"},
+		{expression: "sum([[1, 2]])", err: "list literals are not supported in float mode"},
+		{expression: "sin([1, 2])", err: "list literals are not supported in float mode"},
+	}
+
+	for _, c := range cases {
+		result, err := Calculate(c.expression, CalculatorConfig{})
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+		require.NoError(t, err, c.expression)
+		require.InDelta(t, c.result, result, 1e-12*max(1, c.result), c.expression)
+	}
+}
+
+func TestStatisticsModes(t *testing.T) {
+	type CaseModes struct {
+		expression string
+		config     CalculatorConfig
+		result     string
+		err        string
+	}
+	rational := CalculatorConfig{Mode: Rational}
+	integer := CalculatorConfig{Mode: Integer}
+	interval := CalculatorConfig{Mode: Interval}
+	uncertainty := CalculatorConfig{Mode: Uncertainty}
+	units := CalculatorConfig{Mode: Units}
+	cases := []CaseModes{
+		{expression: "mean([1, 2, 4])", config: rational, result: "7/3"},
+		{expression: "var(1, 2, 3, 4)", config: rational, result: "5/3"},
+		{expression: "stdevp(1, 5)", config: rational, result: "2"},
+		{expression: "median(1/3, 1/2)", config: rational, result: "5/12"},
+		{expression: "percentile(25, 1, 2, 3, 4)", config: rational, result: "7/4"},
+		{expression: "mode(1/2, 1/3, 2/4)", config: rational, result: "1/2"},
+		{expression: "harmean(1, 2, 4)", config: rational, result: "12/7"},
+		{expression: "stdev(1, 2)", config: rational, err: "calculating stdev: result is not exact in rational mode"},
+		{expression: "geomean(1, -2)", config: rational, err: "geometric mean of non-positive number"},
+
+		{expression: "sum([1, 2, 3])", config: integer, result: "6"},
+		{expression: "median(1, 5, 2)", config: integer, result: "2"},
+		{expression: "mean(1, 2)", config: integer, err: "calculating mean: result is not an integer"},
+
+		{expression: "sum(0.1, 0.2)", config: CalculatorConfig{Precision: 200}, result: "0.3"},
+		{expression: "stdev(1, 2, 3, 4) ^ 2", config: CalculatorConfig{Precision: 200}, result: "1.66666666666666666666666666666666666666666666666666666666667"},
+		{expression: "geomean(2, 8, 4)", config: CalculatorConfig{Precision: 200}, result: "4"},
+
+		{expression: "mean(1 + i, 3 - i)", config: CalculatorConfig{Mode: Complex}, result: "2"},
+		{expression: "sum([i, 2])", config: CalculatorConfig{Mode: Complex}, result: "2+i"},
+
+		// в режиме Interval список - это интервал
+		{expression: "sum([1, 2], 3)", config: interval, result: "[4, 5]"},
+		{expression: "mean([1, 2], [3, 4])", config: interval, result: "[2, 3]"},
+		{expression: "median(1, [2, 3], 5)", config: interval, result: "[2, 3]"},
+		{expression: "percentile([0, 100], 1, 2, 3)", config: interval, result: "[1, 3]"},
+		{expression: "percentile([0, 101], 1, 2)", config: interval, err: "percentile must be between 0 and 100"},
+
+		{expression: "mean(1±0.1, 3±0.1)", config: uncertainty, result: "2.000±0.071"},
+		{expression: "sum([1±0.1, 2])", config: uncertainty, result: "3.00±0.10"},
+		{expression: "stdev(1±0.1, 2, 3)", config: uncertainty, result: "1.000±0.050"},
+		{expression: "median(1±0.1, 5, 3±0.2)", config: uncertainty, result: "3.00±0.20"},
+		{expression: "geomean(2±0.1, 8)", config: uncertainty, result: "4.00±0.10"},
+		// одно и то же измерение складывается с самим собой
+		{expression: "x = 1±0.1; sum(x, x)", config: uncertainty, result: "2.00±0.20"},
+
+		{expression: "mean(1 m, 50 cm)", config: units, result: "0.75 m"},
+		{expression: "var(1 m, 3 m)", config: units, result: "2 m^2"},
+		{expression: "percentile(50, [1 s, 2 s])", config: units, result: "1.5 s"},
+		{expression: "sum(1 m, 1 s)", config: units, err: "calculating sum: incompatible units: m and s"},
+		{expression: "percentile(50 m, 1 m)", config: units, err: "percentile must be dimensionless, got m"},
+	}
+
+	for _, c := range cases {
+		result, err := Evaluate(c.expression, c.config)
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.result, result.String(), c.expression)
+	}
+}
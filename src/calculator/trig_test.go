@@ -0,0 +1,215 @@
+package calculator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInverseTrigHyperbolic(t *testing.T) {
+	type CaseTrig struct {
+		expression string
+		angleUnits AngleUnit
+		result     float64
+		err        string
+	}
+	cases := []CaseTrig{
+		{expression: "arcsin(0.5)", result: math.Pi / 6},
+		{expression: "asin(0.5)", result: math.Pi / 6},
+		{expression: "asin(0.5)", angleUnits: "degree", result: 30},
+		{expression: "arccos(0)", result: math.Pi / 2},
+		{expression: "acos(-1)", angleUnits: "degree", result: 180},
+		{expression: "arctg(1)", result: math.Pi / 4},
+		{expression: "atan(1)", angleUnits: "degree", result: 45},
+		{expression: "arcctg(1)", result: math.Pi / 4},
+		{expression: "acot(-1)", result: 3 * math.Pi / 4},
+		// названия разных школ
+		{expression: "arctan(1)", angleUnits: "degree", result: 45},
+		{expression: "arccot(-1)", result: 3 * math.Pi / 4},
+		{expression: "tan(45)", angleUnits: "degree", result: 1},
+		{expression: "cot(pi / 4)", result: 1},
+		{expression: "tan(0.3) - tg(0.3) + cot(0.3) - ctg(0.3)", result: 0},
+		{expression: "arcctg(0)", angleUnits: "degree", result: 90},
+		{expression: "sin(asin(0.3))", angleUnits: "degree", result: 0.3},
+		{expression: "sinh(1)", result: math.Sinh(1)},
+		{expression: "cosh(-1)", result: math.Cosh(1)},
+		{expression: "tanh(0.5)", result: math.Tanh(0.5)},
+		{expression: "coth(2)", result: 1 / math.Tanh(2)},
+		// гиперболические функции не зависят от единиц углов
+		{expression: "sinh(1)", angleUnits: "degree", result: math.Sinh(1)},
+		{expression: "arsinh(1)", result: math.Asinh(1)},
+		{expression: "asinh(-2)", result: math.Asinh(-2)},
+		{expression: "arcosh(1)", result: 0},
+		{expression: "acosh(2)", result: math.Acosh(2)},
+		{expression: "artanh(0.5)", result: math.Atanh(0.5)},
+		{expression: "atanh(-0.5)", result: math.Atanh(-0.5)},
+		{expression: "arcoth(2)", result: math.Atanh(0.5)},
+		{expression: "acoth(-2)", result: math.Atanh(-0.5)},
+
+		{expression: "arcsin(2)", err: "1:1: calculating arcsin: arcsin of value outside [-1,1]"},
+		// ошибка числа аргументов называет функцию так, как она записана
+		{expression: "tan(1, 2)", err: "1:1: function tan expects 1 argument, got 2"},
+		{expression: "asin(-1.5)", err: "1:1: calculating asin: arcsin of value outside [-1,1]"},
+		{expression: "acos(2)", err: "arccos of value outside [-1,1]"},
+		{expression: "coth(0)", err: "calculating coth: hyperbolic cotangent of zero"},
+		{expression: "acosh(0.5)", err: "arcosh of value less than 1"},
+		{expression: "atanh(1)", err: "artanh of value outside (-1,1)"},
+		{expression: "acoth(0.5)", err: "arcoth of value inside [-1,1]"},
+		{expression: "sinh(1000)", err: "calculating sinh: got overflow"},
+		{expression: "cosh(-1000)", err: "calculating cosh: got overflow"},
+	}
+
+	for _, c := range cases {
+		config := CalculatorConfig{AngleUnits: c.angleUnits}
+		result, err := Calculate(c.expression, config)
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+		require.NoError(t, err, c.expression)
+		require.InDelta(t, c.result, result, 1e-12, c.expression)
+
+		// повышенная точность совпадает с float64
+		config.Precision = 100
+		result, err = Calculate(c.expression, config)
+		require.NoError(t, err, c.expression)
+		require.InDelta(t, c.result, result, 1e-12, c.expression)
+	}
+}
+
+func TestInverseTrigHyperbolicModes(t *testing.T) {
+	type CaseModes struct {
+		expression string
+		mode       Mode
+		angleUnits AngleUnit
+		result     string
+		err        string
+	}
+	cases := []CaseModes{
+		{expression: "asin(2)", mode: Complex, result: "1.5707963267948966+1.3169578969248164i"},
+		{expression: "asin(0.5)", mode: Complex, angleUnits: "degree", result: "30.000000000000004"},
+		{expression: "acosh(0)", mode: Complex, result: "1.5707963267948966i"},
+		{expression: "atanh(2)", mode: Complex, result: "0.5493061443340549+1.5707963267948966i"},
+		{expression: "sinh(i * pi / 2)", mode: Complex, result: "i"},
+		{expression: "coth(0)", mode: Complex, err: "hyperbolic cotangent of zero"},
+		{expression: "atanh(1)", mode: Complex, err: "artanh of ±1"},
+		{expression: "atan(i)", mode: Complex, err: "arctg of ±i"},
+
+		{expression: "acos([0.5, 1])", mode: Interval, result: "[0, 1.047197551196598]"},
+		{expression: "cosh([-1, 2])", mode: Interval, result: "[1, 3.7621956910836323]"},
+		{expression: "acosh(1)", mode: Interval, result: "[0, 0]"},
+		{expression: "atanh(0.5±0.1)", mode: Interval, result: "[0.4236489301936016, 0.6931471805599457]"},
+		{expression: "asin([0.5, 1])", mode: Interval, angleUnits: "degree", result: "[29.999999999999982, 90.00000000000004]"},
+		{expression: "asin([0.5, 2])", mode: Interval, err: "arcsin of value outside [-1,1]"},
+		{expression: "coth([-1, 1])", mode: Interval, err: "interval contains zero, a pole of hyperbolic cotangent"},
+		{expression: "acoth([0.5, 2])", mode: Interval, err: "arcoth of value inside [-1,1]"},
+
+		{expression: "sinh(1±0.1)", mode: Uncertainty, result: "1.18±0.15"},
+		{expression: "atanh(0.5±0.1)", mode: Uncertainty, result: "0.55±0.13"},
+		{expression: "asin(0.5±0.01)", mode: Uncertainty, angleUnits: "degree", result: "30.00±0.66"},
+		{expression: "acosh(1±0.1)", mode: Uncertainty, err: "uncertainty is undefined at this point"},
+	}
+
+	for _, c := range cases {
+		result, err := Evaluate(c.expression, CalculatorConfig{Mode: c.mode, AngleUnits: c.angleUnits})
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.result, result.String(), c.expression)
+	}
+}
+
+func TestAngleUnits(t *testing.T) {
+	type CaseAngle struct {
+		expression string
+		angleUnits AngleUnit
+		result     float64
+	}
+	cases := []CaseAngle{
+		{expression: "sin(30)", angleUnits: Degree, result: 0.5},
+		{expression: "sin(100)", angleUnits: Gradian, result: 1},
+		{expression: "cos(0.5)", angleUnits: Turn, result: -1},
+		{expression: "asin(1)", angleUnits: Gradian, result: 100},
+		{expression: "atan2(1, 1)", angleUnits: Turn, result: 0.125},
+		{expression: "sin(30°)", result: 0.5},
+		{expression: "sin(30deg)", angleUnits: Gradian, result: 0.5},
+		{expression: "cos(0.5rad)", angleUnits: Degree, result: math.Cos(0.5)},
+		{expression: "tg(50grad)", result: 1},
+		{expression: "sin(0.25turn)", angleUnits: Degree, result: 1},
+		// угол с единицей переводится в AngleUnits
+		{expression: "90°", result: math.Pi / 2},
+		{expression: "90°", angleUnits: Gradian, result: 100},
+		{expression: "2 * 45° + 1turn", angleUnits: Degree, result: 450},
+		{expression: "deg(pi)", result: 180},
+		{expression: "deg(pi / 2)", angleUnits: Gradian, result: 90},
+		{expression: "rad(180)", result: math.Pi},
+		{expression: "sin(rad(30))", result: 0.5},
+	}
+
+	for _, c := range cases {
+		config := CalculatorConfig{AngleUnits: c.angleUnits}
+		result, err := Calculate(c.expression, config)
+		require.NoError(t, err, c.expression)
+		require.InDelta(t, c.result, result, 1e-12, c.expression)
+
+		config.Precision = 100
+		result, err = Calculate(c.expression, config)
+		require.NoError(t, err, c.expression)
+		require.InDelta(t, c.result, result, 1e-12, c.expression)
+	}
+}
+
+func TestAngleLiterals(t *testing.T) {
+	type CaseAngleLit struct {
+		expression string
+		mode       Mode
+		angleUnits AngleUnit
+		tree       string
+		result     string
+		err        string
+	}
+	cases := []CaseAngleLit{
+		{expression: "sin(30deg)", tree: "sin(30°)"},
+		{expression: "1.5rad + 50grad", tree: "1.5rad + 50grad"},
+		{expression: "-0.5turn", tree: "-0.5turn"},
+		{expression: "100grad", mode: Rational, angleUnits: Degree, result: "90"},
+		{expression: "0.5turn - 90°", mode: Rational, angleUnits: Gradian, result: "100"},
+		{expression: "sin(30°)", mode: Interval, result: "[0.4999999999999997, 0.5000000000000003]"},
+		{expression: "sin(50±1)", mode: Uncertainty, angleUnits: Gradian, result: "0.707±0.011"},
+		{expression: "deg(pi/2 + i)", mode: Complex, result: "90+57.29577951308232i"},
+		{expression: "sin(30deg)", mode: Units, result: "0.49999999999999994"},
+
+		{expression: "30°", mode: Rational, err: "1:1: angle 30°: result is not exact in rational mode"},
+		{expression: "30 °", err: "error while parsing: 1:4: unexpected token: °"},
+		{expression: "x°", err: "error while parsing: 1:2: unexpected token: °"},
+	}
+
+	for _, c := range cases {
+		config := CalculatorConfig{Mode: c.mode, AngleUnits: c.angleUnits}
+		if c.tree != "" {
+			tree, err := NewCalculator(config).Parse(c.expression)
+			require.NoError(t, err, c.expression)
+			require.Equal(t, c.tree, tree.String(), c.expression)
+			continue
+		}
+		result, err := Evaluate(c.expression, config)
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.result, result.String(), c.expression)
+	}
+}
+
+func TestParseAngleUnit(t *testing.T) {
+	unit, err := ParseAngleUnit("gradian")
+	require.NoError(t, err)
+	require.Equal(t, Gradian, unit)
+
+	_, err = ParseAngleUnit("radians")
+	require.EqualError(t, err, `unknown angle unit "radians", expected radian, degree, gradian or turn`)
+}
@@ -0,0 +1,465 @@
+package calculator
+
+import (
+	"cmp"
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"sync/atomic"
+)
+
+// Measurement - результат вычисления в режиме Uncertainty:
+// значение и его стандартная неопределённость
+type Measurement struct {
+	Value float64
+	Sigma float64
+}
+
+// String округляет неопределённость до двух значащих цифр,
+// а значение - до того же разряда: 19.62±0.11
+func (m Measurement) String() string {
+	if m.Sigma == 0 || math.IsInf(m.Sigma, 0) || math.IsNaN(m.Sigma) {
+		return formatFloat(m.Value)
+	}
+
+	exp := int(math.Floor(math.Log10(m.Sigma))) - 1
+	if exp < -12 || exp > 12 {
+		return formatFloat(m.Value) + "±" + strconv.FormatFloat(m.Sigma, 'g', 2, 64)
+	}
+	scale := math.Pow(10, float64(exp))
+	decimals := max(0, -exp)
+	value := math.Round(m.Value/scale) * scale
+	sigma := math.Round(m.Sigma/scale) * scale
+	return strconv.FormatFloat(value, 'f', decimals, 64) + "±" + strconv.FormatFloat(sigma, 'f', decimals, 64)
+}
+
+// Text печатает значение и неопределённость, форматируя их функцией number
+func (m Measurement) Text(number func(float64) string) string {
+	if m.Sigma == 0 || math.IsInf(m.Sigma, 0) || math.IsNaN(m.Sigma) {
+		return number(m.Value)
+	}
+	return number(m.Value) + "±" + number(m.Sigma)
+}
+
+func (m Measurement) Float64() (float64, bool) {
+	return m.Value, m.Sigma == 0
+}
+
+// sourceID нумерует независимые источники неопределённости. Счётчик общий,
+// чтобы значения из разных вычислений не оказались ложно коррелированы.
+var sourceID atomic.Int64
+
+// measured - значение и его производные по независимым источникам
+// неопределённости, каждый источник нормирован на своё стандартное отклонение
+type measured struct {
+	v    float64
+	grad map[int64]float64
+}
+
+func certain(v float64) measured {
+	return measured{v: v}
+}
+
+func (m measured) sigma() float64 {
+	var sum float64
+	for _, g := range m.grad {
+		sum += g * g
+	}
+	return math.Sqrt(sum)
+}
+
+// linear возвращает значение v с производной a*m.grad + b*n.grad
+func linear(v float64, a float64, m measured, b float64, n measured) measured {
+	if len(m.grad) == 0 && len(n.grad) == 0 {
+		return certain(v)
+	}
+	grad := make(map[int64]float64, len(m.grad)+len(n.grad))
+	for id, g := range m.grad {
+		grad[id] += a * g
+	}
+	for id, g := range n.grad {
+		grad[id] += b * g
+	}
+	return measured{v: v, grad: grad}
+}
+
+// chain возвращает f(m) со значением v и производной f'(m) = d
+func (m measured) chain(v, d float64) (measured, error) {
+	if len(m.grad) == 0 {
+		return certain(v), nil
+	}
+	if math.IsInf(d, 0) || math.IsNaN(d) {
+		return measured{}, errors.New("uncertainty is undefined at this point")
+	}
+	return linear(v, d, m, 0, measured{}), nil
+}
+
+// uncertaintyDomain - линейное (первого порядка) распространение
+// неопределённостей по формуле Гаусса
+type uncertaintyDomain struct {
+	angle AngleUnit
+}
+
+func newUncertaintyDomain(config CalculatorConfig) *uncertaintyDomain {
+	return &uncertaintyDomain{angle: config.AngleUnits}
+}
+
+func (d *uncertaintyDomain) number(n *NumberLit) (measured, error) {
+	x, err := n.float()
+	return certain(x), err
+}
+
+func (d *uncertaintyDomain) constant(name string) (measured, error) {
+	if name == "e" {
+		return certain(math.E), nil
+	}
+	return certain(math.Pi), nil
+}
+
+func (d *uncertaintyDomain) fromFloat(x float64) (measured, bool) {
+	return certain(x), !math.IsNaN(x)
+}
+
+func (d *uncertaintyDomain) approx(x float64) (measured, error) {
+	if math.IsNaN(x) {
+		return measured{}, errNotANumber
+	}
+	return certain(x), nil
+}
+
+func (d *uncertaintyDomain) toFloat(x measured) (float64, bool) {
+	return x.v, len(x.grad) == 0
+}
+
+func (d *uncertaintyDomain) neg(x measured) (measured, error) {
+	return linear(-x.v, -1, x, 0, measured{}), nil
+}
+
+func (d *uncertaintyDomain) binary(op string, x, y measured) (measured, error) {
+	var z measured
+	switch op {
+	case "+":
+		v, err := Add(x.v, y.v)
+		if err != nil {
+			return measured{}, err
+		}
+		z = linear(v, 1, x, 1, y)
+	case "-":
+		v, err := Sub(x.v, y.v)
+		if err != nil {
+			return measured{}, err
+		}
+		z = linear(v, 1, x, -1, y)
+	case "*":
+		v, err := Mul(x.v, y.v)
+		if err != nil {
+			return measured{}, err
+		}
+		z = linear(v, y.v, x, x.v, y)
+	case "/":
+		v, err := Div(x.v, y.v)
+		if err != nil {
+			return measured{}, err
+		}
+		z = linear(v, 1/y.v, x, -v/y.v, y)
+	case "^":
+		v, err := Pow(x.v, y.v)
+		if err != nil {
+			return measured{}, err
+		}
+		dx := y.v * math.Pow(x.v, y.v-1)
+		dy := 0.0
+		if len(y.grad) > 0 {
+			if x.v <= 0 {
+				return measured{}, errors.New("uncertain exponent needs a positive base")
+			}
+			dy = v * math.Log(x.v)
+		}
+		if len(x.grad) == 0 {
+			dx = 0
+		}
+		z = linear(v, dx, x, dy, y)
+	case "±":
+		if len(y.grad) > 0 {
+			return measured{}, errors.New("tolerance must be an exact number")
+		}
+		if y.v < 0 {
+			return measured{}, errNegativeTolerance
+		}
+		z = linear(x.v, 1, x, 0, measured{})
+		if y.v > 0 {
+			if z.grad == nil {
+				z.grad = map[int64]float64{}
+			}
+			z.grad[sourceID.Add(1)] = y.v
+		}
+	default:
+		return measured{}, errUnsupported
+	}
+	return checkMeasured(z)
+}
+
+func (d *uncertaintyDomain) call(name string, args []measured) (measured, error) {
+	x := args[0]
+	var z measured
+	var err error
+	switch name {
+	case "sqrt":
+		var v float64
+		if v, err = Sqrt(x.v); err == nil {
+			z, err = x.chain(v, 1/(2*v))
+		}
+	case "ln":
+		var v float64
+		if v, err = Ln(x.v); err == nil {
+			z, err = x.chain(v, 1/x.v)
+		}
+	case "exp":
+		var v float64
+		if v, err = Exp(x.v); err == nil {
+			z, err = x.chain(v, v)
+		}
+	case "sin", "cos", "tg", "ctg":
+		z, err = d.trig(name, x)
+	case "arcsin", "arccos", "arctg", "arcctg":
+		z, err = d.inverseTrig(name, x)
+	case "sinh", "cosh", "tanh", "coth", "arsinh", "arcosh", "artanh", "arcoth":
+		z, err = hyperbolic(name, x)
+	case "log", "log10", "log2":
+		base, x := logArgs(name, args, certain)
+		var v float64
+		if v, err = LogBase(base.v, x.v); err == nil {
+			lnBase := math.Log(base.v)
+			z = linear(v, -v/(base.v*lnBase), base, 1/(x.v*lnBase), x)
+		}
+	case "atan2":
+		y, x := args[0], args[1]
+		r2 := x.v*x.v + y.v*y.v
+		scale := d.angle.fromRadians(1)
+		z = linear(Atan2(y.v, x.v)*scale, scale*x.v/r2, y, -scale*y.v/r2, x)
+	case "max", "min":
+		z = x
+		for _, arg := range args[1:] {
+			if arg.v > z.v && name == "max" || arg.v < z.v && name == "min" {
+				z = arg
+			}
+		}
+		z = measured{v: z.v, grad: maps.Clone(z.grad)}
+	case "abs":
+		z, err = x.chain(Abs(x.v), Sign(x.v))
+	case "re", "conj":
+		z = x
+	case "im":
+		z = certain(0)
+	case "sum", "mean", "var", "varp", "stdev", "stdevp", "median", "percentile", "geomean", "harmean":
+		z, err = statistic(name, args)
+	default:
+		return measured{}, errUnsupported
+	}
+	if err != nil {
+		return measured{}, err
+	}
+	return checkMeasured(z)
+}
+
+// statistic считает значение через float64 и складывает производные
+// аргументов с весами - частными производными функции по ним
+func statistic(name string, args []measured) (measured, error) {
+	var p measured
+	if name == "percentile" {
+		p, args = args[0], args[1:]
+	}
+	xs := make([]float64, 0, len(args))
+	for _, arg := range args {
+		xs = append(xs, arg.v)
+	}
+	n := float64(len(xs))
+	mean, _ := welford(xs)
+	weights := make([]float64, len(xs))
+	pWeight := 0.0
+
+	var v float64
+	var err error
+	switch name {
+	case "sum", "mean":
+		fn, w := Sum, 1.0
+		if name == "mean" {
+			fn, w = Mean, 1/n
+		}
+		v, err = fn(xs...)
+		for i := range weights {
+			weights[i] = w
+		}
+	case "var", "varp", "stdev", "stdevp":
+		population := name == "varp" || name == "stdevp"
+		v, err = Variance(population, xs...)
+		denom := n - 1
+		if population {
+			denom = n
+		}
+		// d var / d x_i = 2(x_i - mean) / denom, d stdev = d var / (2 stdev)
+		scale := 2 / denom
+		if name == "stdev" || name == "stdevp" {
+			v = math.Sqrt(v)
+			scale = 1 / (denom * v)
+		}
+		for i, x := range xs {
+			weights[i] = scale * (x - mean)
+		}
+	case "median", "percentile":
+		q := 0.5
+		if name == "percentile" {
+			if p.v < 0 || p.v > 100 {
+				return measured{}, errors.New("percentile must be between 0 and 100")
+			}
+			q = p.v / 100
+		}
+		// процентиль - интерполяция между двумя соседними по величине значениями
+		order := make([]int, len(xs))
+		for i := range order {
+			order[i] = i
+		}
+		slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(xs[a], xs[b]) })
+		h := q * (n - 1)
+		i := int(h)
+		if i == len(xs)-1 {
+			v, weights[order[i]] = xs[order[i]], 1
+			break
+		}
+		a, b := xs[order[i]], xs[order[i+1]]
+		t := h - float64(i)
+		v = a + t*(b-a)
+		weights[order[i]] += 1 - t
+		weights[order[i+1]] += t
+		// процентиль растёт с p со скоростью (b - a) * (n - 1) / 100
+		pWeight = (b - a) * (n - 1) / 100
+	case "geomean":
+		v, err = Geomean(xs...)
+		for i, x := range xs {
+			weights[i] = v / (n * x)
+		}
+	case "harmean":
+		v, err = Harmean(xs...)
+		for i, x := range xs {
+			weights[i] = v * v / (n * x * x)
+		}
+	}
+	if err != nil {
+		return measured{}, err
+	}
+
+	z := linear(v, pWeight, p, 0, measured{})
+	for i, arg := range args {
+		if len(arg.grad) > 0 {
+			z = linear(v, 1, z, weights[i], arg)
+		}
+	}
+	return z, nil
+}
+
+func (d *uncertaintyDomain) trig(name string, x measured) (measured, error) {
+	// производная по углу в градусах умножается на pi/180
+	r, scale := d.angle.toRadians(x.v), d.angle.toRadians(1)
+
+	switch name {
+	case "sin":
+		return x.chain(Sin(r), scale*Cos(r))
+	case "cos":
+		return x.chain(Cos(r), -scale*Sin(r))
+	case "tg":
+		v, err := Tg(r)
+		if err != nil {
+			return measured{}, err
+		}
+		return x.chain(v, scale*(1+v*v))
+	default:
+		v, err := Cot(r)
+		if err != nil {
+			return measured{}, err
+		}
+		return x.chain(v, -scale*(1+v*v))
+	}
+}
+
+func (d *uncertaintyDomain) inverseTrig(name string, x measured) (measured, error) {
+	// результат в градусах: производная умножается на 180/pi
+	scale := d.angle.fromRadians(1)
+
+	var v, deriv float64
+	var err error
+	switch name {
+	case "arcsin":
+		v, err = Asin(x.v)
+		deriv = 1 / math.Sqrt((1-x.v)*(1+x.v))
+	case "arccos":
+		v, err = Acos(x.v)
+		deriv = -1 / math.Sqrt((1-x.v)*(1+x.v))
+	case "arctg":
+		v, deriv = Atan(x.v), 1/(1+x.v*x.v)
+	default:
+		v, deriv = Acot(x.v), -1/(1+x.v*x.v)
+	}
+	if err != nil {
+		return measured{}, err
+	}
+	return x.chain(scale*v, scale*deriv)
+}
+
+func hyperbolic(name string, x measured) (measured, error) {
+	var v, deriv float64
+	var err error
+	switch name {
+	case "sinh":
+		v, err = Sinh(x.v)
+		deriv = math.Cosh(x.v)
+	case "cosh":
+		v, err = Cosh(x.v)
+		deriv = math.Sinh(x.v)
+	case "tanh":
+		v = Tanh(x.v)
+		deriv = 1 - v*v
+	case "coth":
+		v, err = Coth(x.v)
+		deriv = 1 - v*v
+	case "arsinh":
+		v, deriv = Asinh(x.v), 1/math.Sqrt(x.v*x.v+1)
+	case "arcosh":
+		v, err = Acosh(x.v)
+		deriv = 1 / math.Sqrt((x.v-1)*(x.v+1))
+	case "artanh":
+		v, err = Atanh(x.v)
+		deriv = 1 / ((1 - x.v) * (1 + x.v))
+	default:
+		v, err = Acoth(x.v)
+		deriv = 1 / ((1 - x.v) * (1 + x.v))
+	}
+	if err != nil {
+		return measured{}, err
+	}
+	return x.chain(v, deriv)
+}
+
+func (d *uncertaintyDomain) list(elems []measured) (measured, error) {
+	return measured{}, errUnsupported
+}
+
+func (d *uncertaintyDomain) value(x measured) Value {
+	return Measurement{Value: x.v, Sigma: x.sigma()}
+}
+
+func checkMeasured(x measured) (measured, error) {
+	if math.IsNaN(x.v) {
+		return measured{}, errNotANumber
+	}
+	if math.IsInf(x.v, 0) {
+		return measured{}, errOverflow
+	}
+	for _, g := range x.grad {
+		if math.IsInf(g, 0) || math.IsNaN(g) {
+			return measured{}, errors.New("uncertainty is undefined at this point")
+		}
+	}
+	return x, nil
+}
@@ -0,0 +1,72 @@
+package calculator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUncertainty(t *testing.T) {
+	type CaseUncertainty struct {
+		expression string
+		angleUnits AngleUnit
+		value      float64
+		sigma      float64
+		result     string
+		err        string
+	}
+	cases := []CaseUncertainty{
+		{expression: "9.81±0.02 * 2.0±0.01", value: 19.62, sigma: math.Hypot(2*0.02, 9.81*0.01), result: "19.62±0.11"},
+		{expression: "1±0.3 + 2±0.4", value: 3, sigma: 0.5, result: "3.00±0.50"},
+		{expression: "x = 2±0.1; x - x", value: 0, sigma: 0, result: "0"},
+		{expression: "x = 2±0.1; x * x", value: 4, sigma: 0.4, result: "4.00±0.40"},
+		{expression: "x = 2±0.1; y = 2±0.1; x * y", value: 4, sigma: 0.2 * math.Sqrt2, result: "4.00±0.28"},
+		{expression: "f(a) = a^2; f(3±0.1)", value: 9, sigma: 0.6, result: "9.00±0.60"},
+		{expression: "sqrt(4±0.4)", value: 2, sigma: 0.1, result: "2.00±0.10"},
+		{expression: "ln(10±1)", value: math.Log(10), sigma: 0.1, result: "2.30±0.10"},
+		{expression: "sin(0±0.01)", value: 0, sigma: 0.01, result: "0.000±0.010"},
+		{expression: "sin(30±1)", angleUnits: "degree", value: 0.5, sigma: math.Sqrt(3) / 2 * math.Pi / 180, result: "0.500±0.015"},
+		{expression: "cos(90±1)", angleUnits: "degree", value: 6.123233995736766e-17, sigma: math.Pi / 180, result: "0.000±0.017"},
+		{expression: "2^(3±0.1)", value: 8, sigma: 0.8 * math.Ln2, result: "8.00±0.55"},
+		{expression: "1234.5±12", value: 1234.5, sigma: 12, result: "1235±12"},
+		{expression: "atan2(1±0.1, 1)", value: math.Pi / 4, sigma: 0.05, result: "0.785±0.050"},
+		{expression: "5±0", value: 5, sigma: 0, result: "5"},
+
+		{expression: "1 / (0±1)", err: "1:1: calculating division: division by zero"},
+		{expression: "sqrt(0±0.1)", err: "1:1: calculating sqrt: uncertainty is undefined at this point"},
+		{expression: "2±(1±0.1)", err: "1:1: calculating tolerance: tolerance must be an exact number"},
+		{expression: "(-2)^(1±0.1)", err: "calculating power: uncertain exponent needs a positive base"},
+		{expression: "[1, 2]", err: "1:1: list literals are not supported in uncertainty mode"},
+	}
+
+	for _, c := range cases {
+		result, err := Evaluate(c.expression, CalculatorConfig{Mode: Uncertainty, AngleUnits: c.angleUnits})
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+		require.NoError(t, err, c.expression)
+
+		m := result.(Measurement)
+		require.InDelta(t, c.value, m.Value, 1e-12, c.expression)
+		require.InDelta(t, c.sigma, m.Sigma, 1e-12, c.expression)
+		require.Equal(t, c.result, m.String(), c.expression)
+	}
+}
+
+func TestUncertaintyIndependentEvaluations(t *testing.T) {
+	calc := NewCalculator(CalculatorConfig{Mode: Uncertainty})
+	a, err := calc.Evaluate("2±0.1")
+	require.NoError(t, err)
+	b, err := calc.Evaluate("2±0.1")
+	require.NoError(t, err)
+	require.Equal(t, a, b)
+
+	value, err := calc.Calculate("(2±0.1) * 0 + 1")
+	require.NoError(t, err)
+	require.Equal(t, 1.0, value)
+
+	_, err = calc.Calculate("2±0.1")
+	require.ErrorContains(t, err, "does not fit in float64")
+}
@@ -0,0 +1,593 @@
+package calculator
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+)
+
+//go:embed units.txt
+var builtinUnits string
+
+// ограничение на число основных единиц в базе
+const maxDimensions = 10
+
+// dimension - степени основных единиц, номера - в порядке их объявления в базе
+type dimension [maxDimensions]int8
+
+// unitDef - единица измерения: её значение в основных единицах
+type unitDef struct {
+	factor float64
+	dim    dimension
+	// к имени можно приписывать приставки
+	prefixable bool
+}
+
+// UnitDatabase - единицы измерения и приставки для режима Units.
+// После загрузки база только читается, поэтому её можно использовать
+// из нескольких калькуляторов одновременно.
+type UnitDatabase struct {
+	// имена основных единиц по номерам измерений
+	bases    []string
+	units    map[string]unitDef
+	prefixes map[string]float64
+}
+
+// errNoUnit - перевод в единицы значения без единиц: 5 to cm
+var errNoUnit = errors.New("value has no unit")
+
+var (
+	builtinOnce sync.Once
+	builtinDB   *UnitDatabase
+)
+
+// defaultUnits возвращает встроенную базу, она загружается при первом обращении
+func defaultUnits() *UnitDatabase {
+	builtinOnce.Do(func() {
+		builtinDB = &UnitDatabase{}
+		if err := builtinDB.Load(strings.NewReader(builtinUnits)); err != nil {
+			panic("built-in units: " + err.Error())
+		}
+	})
+	return builtinDB
+}
+
+// DefaultUnits возвращает копию встроенной базы единиц, которую можно
+// дополнить через Load и LoadFile
+func DefaultUnits() *UnitDatabase {
+	db := defaultUnits()
+	return &UnitDatabase{
+		bases:    slices.Clone(db.bases),
+		units:    maps.Clone(db.units),
+		prefixes: maps.Clone(db.prefixes),
+	}
+}
+
+// LoadFile добавляет в базу единицы из файла в формате units.txt
+func (db *UnitDatabase) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := db.Load(f); err != nil {
+		return newError("%s:%w", path, err)
+	}
+	return nil
+}
+
+// Load добавляет в базу единицы в формате units.txt. Единица с уже
+// существующим именем переопределяется. Ошибка содержит номер строки.
+func (db *UnitDatabase) Load(r io.Reader) error {
+	if db.units == nil {
+		db.units, db.prefixes = map[string]unitDef{}, map[string]float64{}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if err := db.define(text); err != nil {
+			return newError("%d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// define разбирает одну строку файла единиц
+func (db *UnitDatabase) define(line string) error {
+	name, definition, ok := strings.Cut(line, "=")
+	if !ok {
+		return errors.New("expected name = definition")
+	}
+	name, definition = strings.TrimSpace(name), strings.TrimSpace(definition)
+
+	if prefix, ok := strings.CutSuffix(name, "-"); ok {
+		if !isIdentifier(prefix) {
+			return newError("invalid prefix name %q", prefix)
+		}
+		value, err := db.evaluate(definition)
+		if err != nil {
+			return err
+		}
+		if value.dim != (dimension{}) {
+			return newError("prefix %s must be a dimensionless number", prefix)
+		}
+		db.prefixes[prefix] = value.v
+		return nil
+	}
+
+	name, prefixable := strings.CutSuffix(name, "*")
+	if !isIdentifier(name) {
+		return newError("invalid unit name %q", name)
+	}
+	if name == "to" {
+		return errors.New("to is reserved for unit conversion")
+	}
+
+	if strings.HasPrefix(definition, "!") {
+		if !isIdentifier(definition[1:]) {
+			return newError("invalid dimension name %q", definition[1:])
+		}
+		i := slices.Index(db.bases, name)
+		if i < 0 {
+			if len(db.bases) == maxDimensions {
+				return newError("too many base units, at most %d are supported", maxDimensions)
+			}
+			i = len(db.bases)
+			db.bases = append(db.bases, name)
+		}
+		var dim dimension
+		dim[i] = 1
+		db.units[name] = unitDef{factor: 1, dim: dim, prefixable: prefixable}
+		return nil
+	}
+
+	value, err := db.evaluate(definition)
+	if err != nil {
+		return err
+	}
+	if value.v == 0 {
+		return newError("unit %s is zero", name)
+	}
+	db.units[name] = unitDef{factor: value.v, dim: value.dim, prefixable: prefixable}
+	return nil
+}
+
+// evaluate вычисляет определение единицы через уже загруженные единицы
+func (db *UnitDatabase) evaluate(definition string) (quantity, error) {
+	calc := defaultCalculator(CalculatorConfig{Mode: Units, UnitDatabase: db})
+	tree, err := calc.Parse(definition)
+	if err != nil {
+		return quantity{}, err
+	}
+	if _, err := calc.compileTree(tree, definition, nil); err != nil {
+		return quantity{}, err
+	}
+	// переменных в определении нет, незнакомое имя - неизвестная единица
+	var unknown *Variable
+	Inspect(tree, func(n Node) bool {
+		if v, ok := n.(*Variable); ok && unknown == nil {
+			unknown = v
+		}
+		return unknown == nil
+	})
+	if unknown != nil {
+		return quantity{}, errorAt(unknown.Span, "unknown unit %s", unknown.Name).locate(definition)
+	}
+
+	e := &evaluator[quantity]{calc: calc, dom: newUnitsDomain(calc.config), vars: map[string]quantity{}, funcs: map[string]*FuncDef{}}
+	value, hasValue, err := e.statement(tree)
+	if err != nil {
+		return quantity{}, locateError(err, definition)
+	}
+	if !hasValue {
+		return quantity{}, errors.New("definition has no value")
+	}
+	return value, nil
+}
+
+// lookup ищет единицу по имени, в том числе с приставкой: km, µs.
+// Точное совпадение важнее приставки, поэтому min - минута, а не милли-дюйм.
+func (db *UnitDatabase) lookup(name string) (unitDef, bool) {
+	if def, ok := db.units[name]; ok {
+		return def, true
+	}
+
+	best := ""
+	for prefix := range db.prefixes {
+		if len(prefix) <= len(best) || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if def, ok := db.units[name[len(prefix):]]; ok && def.prefixable {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return unitDef{}, false
+	}
+
+	def := db.units[name[len(best):]]
+	def.factor *= db.prefixes[best]
+	def.prefixable = false
+	return def, true
+}
+
+// format печатает размерность через основные единицы: kg m/s^2
+func (db *UnitDatabase) format(dim dimension) string {
+	var num, den []string
+	for i, name := range db.bases {
+		switch p := dim[i]; {
+		case p == 1 || p == -1:
+			if p > 0 {
+				num = append(num, name)
+			} else {
+				den = append(den, name)
+			}
+		case p > 0:
+			num = append(num, fmt.Sprintf("%s^%d", name, p))
+		case p < 0:
+			den = append(den, fmt.Sprintf("%s^%d", name, -p))
+		}
+	}
+
+	text := strings.Join(num, " ")
+	switch {
+	case len(den) == 0:
+		return text
+	case text == "":
+		text = "1"
+	}
+	if len(den) > 1 {
+		return text + "/(" + strings.Join(den, " ") + ")"
+	}
+	return text + "/" + den[0]
+}
+
+// describe - размерность для сообщений об ошибках
+func (db *UnitDatabase) describe(dim dimension) string {
+	if dim == (dimension{}) {
+		return "dimensionless"
+	}
+	return db.format(dim)
+}
+
+// units возвращает базу единиц калькулятора
+func (c *Calculator) units() *UnitDatabase {
+	if c.config.UnitDatabase != nil {
+		return c.config.UnitDatabase
+	}
+	return defaultUnits()
+}
+
+// isUnit проверяет, что имя - единица измерения; единицы есть только в режиме Units
+func (c *Calculator) isUnit(name string) bool {
+	if c.config.Mode != Units {
+		return false
+	}
+	_, ok := c.units().lookup(name)
+	return ok
+}
+
+// Quantity - результат вычисления в режиме Units: значение в единицах Unit.
+// Без перевода через to результат печатается в основных единицах СИ.
+type Quantity struct {
+	Value float64
+	// пустая строка - безразмерная величина
+	Unit string
+}
+
+func (q Quantity) String() string {
+	return q.Text(formatFloat)
+}
+
+// Text печатает значение, форматируя его функцией number
+func (q Quantity) Text(number func(float64) string) string {
+	if q.Unit == "" {
+		return number(q.Value)
+	}
+	return number(q.Value) + " " + q.Unit
+}
+
+func (q Quantity) Float64() (float64, bool) {
+	return q.Value, q.Unit == ""
+}
+
+// quantity - значение в основных единицах и его размерность
+type quantity struct {
+	v   float64
+	dim dimension
+	// единица, в которую значение переведено через to, и её величина
+	unit  string
+	scale float64
+}
+
+// unitDomain - домен, в котором есть единицы измерения
+type unitDomain[T any] interface {
+	unit(u *Unit) (T, error)
+	convert(x, target T, unit string) (T, error)
+}
+
+// unitsDomain - вычисления в float64 с проверкой размерностей
+type unitsDomain struct {
+	db    *UnitDatabase
+	angle AngleUnit
+}
+
+func newUnitsDomain(config CalculatorConfig) *unitsDomain {
+	db := config.UnitDatabase
+	if db == nil {
+		db = defaultUnits()
+	}
+	return &unitsDomain{db: db, angle: config.AngleUnits}
+}
+
+func (d *unitsDomain) number(n *NumberLit) (quantity, error) {
+	x, err := n.float()
+	return quantity{v: x}, err
+}
+
+func (d *unitsDomain) constant(name string) (quantity, error) {
+	if name == "e" {
+		return quantity{v: math.E}, nil
+	}
+	return quantity{v: math.Pi}, nil
+}
+
+func (d *unitsDomain) fromFloat(x float64) (quantity, bool) {
+	return quantity{v: x}, !math.IsNaN(x)
+}
+
+func (d *unitsDomain) approx(x float64) (quantity, error) {
+	return checkQuantity(quantity{v: x})
+}
+
+func (d *unitsDomain) toFloat(x quantity) (float64, bool) {
+	return x.v, x.dim == dimension{}
+}
+
+func (d *unitsDomain) neg(x quantity) (quantity, error) {
+	x.v = -x.v
+	return x, nil
+}
+
+func (d *unitsDomain) binary(op string, x, y quantity) (quantity, error) {
+	z := quantity{dim: x.dim}
+	var err error
+	switch op {
+	case "+", "-":
+		if x.dim != y.dim {
+			return quantity{}, newError("incompatible units: %s and %s", d.db.describe(x.dim), d.db.describe(y.dim))
+		}
+		if op == "+" {
+			z.v, err = Add(x.v, y.v)
+		} else {
+			z.v, err = Sub(x.v, y.v)
+		}
+	case "*":
+		if z.dim, err = combine(x.dim, y.dim, 1); err == nil {
+			z.v, err = Mul(x.v, y.v)
+		}
+	case "/":
+		if z.dim, err = combine(x.dim, y.dim, -1); err == nil {
+			z.v, err = Div(x.v, y.v)
+		}
+	case "^":
+		if y.dim != (dimension{}) {
+			return quantity{}, newError("exponent must be dimensionless, got %s", d.db.describe(y.dim))
+		}
+		if z.dim, err = d.power(x.dim, y.v); err == nil {
+			z.v, err = Pow(x.v, y.v)
+		}
+	default:
+		return quantity{}, errUnsupported
+	}
+	if err != nil {
+		return quantity{}, err
+	}
+	return checkQuantity(z)
+}
+
+// combine складывает степени размерностей: sign = 1 для умножения, -1 для деления
+func combine(x, y dimension, sign int) (dimension, error) {
+	var z dimension
+	for i := range z {
+		p := int(x[i]) + sign*int(y[i])
+		if p < math.MinInt8 || p > math.MaxInt8 {
+			return dimension{}, errors.New("unit power is too large")
+		}
+		z[i] = int8(p)
+	}
+	return z, nil
+}
+
+// power возводит размерность в степень y; степени всех основных единиц
+// должны остаться целыми: (m^2)^0.5 = m, а m^0.5 - ошибка
+func (d *unitsDomain) power(dim dimension, y float64) (dimension, error) {
+	var z dimension
+	for i, p := range dim {
+		q := float64(p) * y
+		if math.Abs(q-math.Round(q)) > 1e-9 {
+			return dimension{}, newError("%s to a fractional power", d.db.describe(dim))
+		}
+		if q < math.MinInt8 || q > math.MaxInt8 {
+			return dimension{}, errors.New("unit power is too large")
+		}
+		z[i] = int8(math.Round(q))
+	}
+	return z, nil
+}
+
+func (d *unitsDomain) call(name string, args []quantity) (quantity, error) {
+	x := args[0]
+	switch name {
+	case "sqrt":
+		dim, err := d.power(x.dim, 0.5)
+		if err != nil {
+			return quantity{}, err
+		}
+		v, err := Sqrt(x.v)
+		if err != nil {
+			return quantity{}, err
+		}
+		return quantity{v: v, dim: dim}, nil
+	case "max", "min", "atan2":
+		for _, arg := range args[1:] {
+			if arg.dim != x.dim {
+				return quantity{}, newError("incompatible units: %s and %s", d.db.describe(x.dim), d.db.describe(arg.dim))
+			}
+		}
+		if name == "atan2" {
+			return quantity{v: d.angle.fromRadians(Atan2(args[0].v, args[1].v))}, nil
+		}
+		z := x
+		for _, arg := range args[1:] {
+			if arg.v > z.v && name == "max" || arg.v < z.v && name == "min" {
+				z = arg
+			}
+		}
+		return quantity{v: z.v, dim: z.dim}, nil
+	case "abs":
+		return quantity{v: Abs(x.v), dim: x.dim}, nil
+	case "sign":
+		return quantity{v: Sign(x.v)}, nil
+	case "re", "conj":
+		return x, nil
+	case "im":
+		return quantity{dim: x.dim}, nil
+	case "sum", "mean", "median", "mode", "percentile", "var", "varp", "stdev", "stdevp", "geomean", "harmean":
+		return d.statistic(name, args)
+	}
+
+	// остальные функции считаются через float64 и принимают только числа
+	for _, arg := range args {
+		if arg.dim != (dimension{}) {
+			return quantity{}, newError("argument must be dimensionless, got %s", d.db.describe(arg.dim))
+		}
+	}
+	return quantity{}, errUnsupported
+}
+
+// statistic считает статистику величин одной размерности; результат в той же
+// размерности, у дисперсии - в квадрате. Процент в percentile безразмерный.
+func (d *unitsDomain) statistic(name string, args []quantity) (quantity, error) {
+	values := args
+	if name == "percentile" {
+		if args[0].dim != (dimension{}) {
+			return quantity{}, newError("percentile must be dimensionless, got %s", d.db.describe(args[0].dim))
+		}
+		values = args[1:]
+	}
+	dim := values[0].dim
+	for _, arg := range values[1:] {
+		if arg.dim != dim {
+			return quantity{}, newError("incompatible units: %s and %s", d.db.describe(dim), d.db.describe(arg.dim))
+		}
+	}
+
+	floats := make([]float64, 0, len(args))
+	for _, arg := range args {
+		floats = append(floats, arg.v)
+	}
+	v, err := builtinFunctions[name].Fn(floats...)
+	if err != nil {
+		return quantity{}, err
+	}
+	if name == "var" || name == "varp" {
+		if dim, err = d.power(dim, 2); err != nil {
+			return quantity{}, err
+		}
+	}
+	return checkQuantity(quantity{v: v, dim: dim})
+}
+
+func (d *unitsDomain) list(elems []quantity) (quantity, error) {
+	return quantity{}, errUnsupported
+}
+
+func (d *unitsDomain) unit(u *Unit) (quantity, error) {
+	def, ok := d.db.lookup(u.Name)
+	if !ok {
+		return quantity{}, newError("unknown unit %s", u.Name)
+	}
+
+	z := quantity{v: def.factor}
+	if u.Power != 1 {
+		var err error
+		if z.dim, err = d.power(def.dim, float64(u.Power)); err != nil {
+			return quantity{}, err
+		}
+		z.v = math.Pow(def.factor, float64(u.Power))
+		return checkQuantity(z)
+	}
+	z.dim = def.dim
+	return z, nil
+}
+
+func (d *unitsDomain) convert(x, target quantity, unit string) (quantity, error) {
+	if x.dim == (dimension{}) && target.dim != (dimension{}) {
+		return quantity{}, errNoUnit
+	}
+	if x.dim != target.dim {
+		return quantity{}, newError("cannot convert %s to %s", d.db.describe(x.dim), d.db.describe(target.dim))
+	}
+	if target.v == 0 {
+		return quantity{}, errDivisionByZero
+	}
+	return quantity{v: x.v, dim: x.dim, unit: unit, scale: target.v}, nil
+}
+
+func (d *unitsDomain) value(x quantity) Value {
+	if x.unit != "" {
+		return Quantity{Value: x.v / x.scale, Unit: x.unit}
+	}
+	return Quantity{Value: x.v, Unit: d.db.format(x.dim)}
+}
+
+func checkQuantity(x quantity) (quantity, error) {
+	if math.IsNaN(x.v) {
+		return quantity{}, errNotANumber
+	}
+	if math.IsInf(x.v, 0) {
+		return quantity{}, errOverflow
+	}
+	return x, nil
+}
+
+// unitText печатает единицу из правой части to так, как её принято
+// записывать: m/s, kg m/s^2
+func unitText(node Node) string {
+	n, ok := node.(*BinaryOp)
+	if !ok {
+		return node.String()
+	}
+
+	switch n.Op {
+	case "*":
+		return unitText(n.X) + " " + unitText(n.Y)
+	case "/":
+		den := unitText(n.Y)
+		if y, ok := n.Y.(*BinaryOp); ok && (y.Op == "*" || y.Op == "/") {
+			den = "(" + den + ")"
+		}
+		return unitText(n.X) + "/" + den
+	case "^":
+		base := unitText(n.X)
+		if _, ok := n.X.(*BinaryOp); ok {
+			base = "(" + base + ")"
+		}
+		return base + "^" + unitText(n.Y)
+	}
+	return n.String()
+}
@@ -0,0 +1,175 @@
+package calculator
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnits(t *testing.T) {
+	type CaseUnits struct {
+		expression string
+		angleUnits AngleUnit
+		value      float64
+		unit       string
+		result     string
+		err        string
+	}
+	cases := []CaseUnits{
+		{expression: "5 km / 2 h", value: 5000.0 / 7200, unit: "m/s", result: "0.6944444444444444 m/s"},
+		{expression: "5 km / 2 h to km/h", value: 2.5, unit: "km/h", result: "2.5 km/h"},
+		{expression: "3 ft + 2 in", value: 0.9652, unit: "m"},
+		// кратчайшая запись, как в остальных режимах; округляет FormatOptions
+		{expression: "3 ft + 2 in to in", value: 38, unit: "in", result: "37.99999999999999 in"},
+		{expression: "60 mph to m/s", value: 26.8224, unit: "m/s", result: "26.822399999999995 m/s"},
+		{expression: "100 km/h in m/s", value: 100.0 / 3.6, unit: "m/s"},
+		{expression: "5 in in cm", value: 12.7, unit: "cm", result: "12.7 cm"},
+		{expression: "5 in to cm", value: 12.7, unit: "cm"},
+		{expression: "2 m^2", value: 2, unit: "m^2"},
+		{expression: "9.81 m s^-2 * 2 kg", value: 19.62, unit: "kg m/s^2"},
+		{expression: "1 N / (1 kg)", value: 1, unit: "m/s^2"},
+		{expression: "1 kWh to J", value: 3.6e6, unit: "J", result: "3.6e+06 J"},
+		{expression: "1 atm to kPa", value: 101.325, unit: "kPa"},
+		{expression: "250 mL + 0.5 L to L", value: 0.75, unit: "L"},
+		{expression: "1 µs to ns", value: 1000, unit: "ns"},
+		{expression: "1 min to ms", value: 60000, unit: "ms"},
+		{expression: "1/(2 s)", value: 0.5, unit: "1/s"},
+		{expression: "1 J / (1 mol K)", value: 1, unit: "kg m^2/(s^2 K mol)"},
+		{expression: "sqrt(16 m^2)", value: 4, unit: "m"},
+		{expression: "(3 m/s)^2", value: 9, unit: "m^2/s^2"},
+		{expression: "max(1 m, 90 cm, 2 ft)", value: 1, unit: "m"},
+		{expression: "2 km / 500 m", value: 4, unit: ""},
+		{expression: "sin(30 deg)", value: 0.5, unit: ""},
+		{expression: "atan2(1 m, 1 m)", angleUnits: "degree", value: 45, unit: ""},
+		{expression: "x = 5 ms; f(v) = v * 2; f(x) to s", value: 0.01, unit: "s"},
+		{expression: "-(1 h to min)", value: -60, unit: "min"},
+		{expression: "1 N to kg m/s^2", value: 1, unit: "kg m/s^2"},
+		{expression: "9 m^2/s^2 to (m/s)^2", value: 9, unit: "(m/s)^2"},
+		{expression: "2 * 3 + 4", value: 10, unit: ""},
+
+		{expression: "1 m + 2 s", err: "1:1: calculating addition: incompatible units: m and s"},
+		{expression: "1 m - 2", err: "calculating substraction: incompatible units: m and dimensionless"},
+		{expression: "5 m to s", err: "1:1: conversion: cannot convert m to s"},
+		{expression: "5 in cm", err: "1:1: 5 has no unit to convert to cm"},
+		{expression: "1 m to 2 cm", err: "error while parsing: 1:8: expected unit, got 2"},
+		{expression: "1 m to x", err: "error while parsing: 1:8: expected unit, got x"},
+		{expression: "sin(2 m)", err: "calculating sin: argument must be dimensionless, got m"},
+		{expression: "2 m ^ 0.5", err: "calculating power: m to a fractional power"},
+		{expression: "sqrt(2 m)", err: "calculating sqrt: m to a fractional power"},
+		{expression: "2 ^ (3 s)", err: "calculating power: exponent must be dimensionless, got s"},
+		{expression: "max(1 m, 1 s)", err: "calculating max: incompatible units: m and s"},
+		{expression: "1 m / (0 s)", err: "calculating division: division by zero"},
+		{expression: "m = 3", err: "error while parsing: 1:1: cannot assign to unit m"},
+		{expression: "f(s) = s * 2", err: "error while parsing: 1:3: cannot use unit s as parameter"},
+		{expression: "5 parsec", err: "error while parsing: 1:3: unexpected token: parsec"},
+		{expression: "5 km to", err: "error while parsing: 1:8: unexpected end of expression"},
+	}
+
+	for _, c := range cases {
+		result, err := Evaluate(c.expression, CalculatorConfig{Mode: Units, AngleUnits: c.angleUnits})
+		if c.err != "" {
+			require.ErrorContains(t, err, c.err, c.expression)
+			continue
+		}
+		require.NoError(t, err, c.expression)
+
+		q := result.(Quantity)
+		require.InDelta(t, c.value, q.Value, 1e-9*max(1, c.value), c.expression)
+		require.Equal(t, c.unit, q.Unit, c.expression)
+		if c.result != "" {
+			require.Equal(t, c.result, q.String(), c.expression)
+		}
+	}
+}
+
+func TestUnitsOnlyInUnitsMode(t *testing.T) {
+	_, err := Calculate("5 km", CalculatorConfig{})
+	require.EqualError(t, err, "error while parsing: 1:3: unexpected token: km")
+
+	value, err := CalculateWithVars("m * 2", CalculatorConfig{}, map[string]float64{"m": 3})
+	require.NoError(t, err)
+	require.Equal(t, 6.0, value)
+
+	value, err = Calculate("2 km / 500 m", CalculatorConfig{Mode: Units})
+	require.NoError(t, err)
+	require.InDelta(t, 4, value, 1e-12)
+
+	_, err = Calculate("2 km", CalculatorConfig{Mode: Units})
+	require.ErrorContains(t, err, "result 2000 m does not fit in float64")
+}
+
+func TestUnitsParse(t *testing.T) {
+	calc := NewCalculator(CalculatorConfig{Mode: Units})
+	type CaseParse struct {
+		expression string
+		tree       string
+	}
+	cases := []CaseParse{
+		{expression: "5 km / 2 h", tree: "5 km / 2 h"},
+		{expression: "9.81 m s^-2", tree: "9.81 m s^-2"},
+		{expression: "3 ft + 2 in to cm", tree: "3 ft + 2 in to cm"},
+		{expression: "(1 h to min) * 2", tree: "(1 h to min) * 2"},
+		{expression: "x in m/s", tree: "x to m / s"},
+		{expression: "1 N to kg m/s^2", tree: "1 N to kg * m / s^2"},
+	}
+
+	for _, c := range cases {
+		tree, err := calc.Parse(c.expression)
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.tree, tree.String(), c.expression)
+
+		again, err := calc.Parse(tree.String())
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.tree, again.String(), c.expression)
+	}
+}
+
+func TestUnitDatabase(t *testing.T) {
+	db := DefaultUnits()
+	require.NoError(t, db.Load(strings.NewReader(`
+# единицы команды
+furlong = 220 yd
+fortnight = 14 d  # две недели
+`)))
+
+	result, err := Evaluate("1 furlong / 1 fortnight to mm/s", CalculatorConfig{Mode: Units, UnitDatabase: db})
+	require.NoError(t, err)
+	require.InDelta(t, 201.168/1209.6, result.(Quantity).Value, 1e-12)
+
+	// встроенная база не меняется
+	_, err = Evaluate("1 furlong", CalculatorConfig{Mode: Units})
+	require.Error(t, err)
+
+	err = db.Load(strings.NewReader("bit* = !information\nB* = 8 bit\nbyte = zz"))
+	require.EqualError(t, err, "3: 1:1: unknown unit zz")
+	result, err = Evaluate("1 kB to bit", CalculatorConfig{Mode: Units, UnitDatabase: db})
+	require.NoError(t, err)
+	require.Equal(t, "8000 bit", result.String())
+
+	path := filepath.Join(t.TempDir(), "units.txt")
+	require.NoError(t, os.WriteFile(path, []byte("ft = 0.3 m\nnothing\n"), 0o644))
+	err = db.LoadFile(path)
+	require.EqualError(t, err, path+":2: expected name = definition")
+	// строки до ошибки уже загружены и переопределяют встроенные
+	result, err = Evaluate("10 ft", CalculatorConfig{Mode: Units, UnitDatabase: db})
+	require.NoError(t, err)
+	require.Equal(t, "3 m", result.String())
+
+	type CaseLoad struct {
+		line string
+		err  string
+	}
+	cases := []CaseLoad{
+		{line: "k- = 1 m", err: "1: prefix k must be a dimensionless number"},
+		{line: "to = 1 m", err: "1: to is reserved for unit conversion"},
+		{line: "1x = 1 m", err: `1: invalid unit name "1x"`},
+		{line: "zero = 0 m", err: "1: unit zero is zero"},
+		{line: "x = !", err: `1: invalid dimension name ""`},
+	}
+	for _, c := range cases {
+		require.EqualError(t, DefaultUnits().Load(strings.NewReader(c.line)), c.err, c.line)
+	}
+}
@@ -0,0 +1,32 @@
+package calculator
+
+// Value - результат вычисления. Конкретный тип зависит от режима калькулятора.
+type Value interface {
+	String() string
+	// Float64 возвращает значение как float64, если оно так представимо
+	Float64() (float64, bool)
+}
+
+// TextValue - результат из чисел float64: Float, ComplexNumber, Bounds,
+// Measurement или Quantity
+type TextValue interface {
+	Value
+	// Text печатает значение, форматируя каждое число функцией number
+	Text(number func(float64) string) string
+}
+
+// Float - результат вычисления в обычном режиме float64
+type Float float64
+
+func (f Float) String() string {
+	return f.Text(formatFloat)
+}
+
+// Text печатает значение, форматируя число функцией number
+func (f Float) Text(number func(float64) string) string {
+	return number(float64(f))
+}
+
+func (f Float) Float64() (float64, bool) {
+	return float64(f), true
+}
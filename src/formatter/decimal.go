@@ -0,0 +1,117 @@
+package formatter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// decimal - десятичная запись числа: 0.digits × 10^exp
+type decimal struct {
+	neg bool
+	// значащие цифры без нулей в начале и в конце, пусто - ноль
+	digits []byte
+	exp    int
+}
+
+// parseDecimal разбирает число вида -12.5, 1e-07 или 3.25E+10
+func parseDecimal(s string) (decimal, bool) {
+	var d decimal
+	mantissa, exponent, hasExp := strings.Cut(strings.ToLower(s), "e")
+	if hasExp {
+		exp, err := strconv.Atoi(exponent)
+		if err != nil {
+			return d, false
+		}
+		d.exp = exp
+	}
+	if strings.HasPrefix(mantissa, "-") {
+		d.neg, mantissa = true, mantissa[1:]
+	} else {
+		mantissa = strings.TrimPrefix(mantissa, "+")
+	}
+
+	intPart, frac, _ := strings.Cut(mantissa, ".")
+	if intPart == "" && frac == "" {
+		return d, false
+	}
+	for _, c := range intPart + frac {
+		if c < '0' || c > '9' {
+			return d, false
+		}
+	}
+	d.exp += len(intPart)
+	digits := strings.TrimLeft(intPart+frac, "0")
+	d.exp -= len(intPart+frac) - len(digits)
+	d.digits = []byte(strings.TrimRight(digits, "0"))
+	if d.isZero() {
+		d.neg = false
+	}
+	return d, true
+}
+
+func (d *decimal) isZero() bool {
+	return len(d.digits) == 0
+}
+
+// round округляет до n значащих цифр, половина - от нуля
+func (d *decimal) round(n int) {
+	if n >= len(d.digits) {
+		return
+	}
+	if n < 0 {
+		d.digits, d.neg = nil, false
+		return
+	}
+
+	up := d.digits[n] >= '5'
+	digits := append([]byte(nil), d.digits[:n]...)
+	if up {
+		i := n - 1
+		for i >= 0 && digits[i] == '9' {
+			i--
+		}
+		if i < 0 {
+			digits = []byte{'1'}
+			d.exp++
+		} else {
+			digits[i]++
+			digits = digits[:i+1]
+		}
+	}
+	d.digits = []byte(strings.TrimRight(string(digits), "0"))
+	if d.isZero() {
+		d.neg = false
+	}
+}
+
+// fixed печатает число в обычной записи с frac цифрами после точки;
+// frac = -1 - столько, сколько нужно
+func (d decimal) fixed(frac int) string {
+	if frac < 0 {
+		frac = max(0, len(d.digits)-d.exp)
+	}
+	digit := func(i int) byte {
+		if i >= 0 && i < len(d.digits) {
+			return d.digits[i]
+		}
+		return '0'
+	}
+
+	var b strings.Builder
+	if d.neg {
+		b.WriteByte('-')
+	}
+	if d.exp <= 0 {
+		b.WriteByte('0')
+	}
+	for i := range d.exp {
+		b.WriteByte(digit(i))
+	}
+	if frac > 0 {
+		b.WriteByte('.')
+		for i := range frac {
+			b.WriteByte(digit(d.exp + i))
+		}
+	}
+	return b.String()
+}
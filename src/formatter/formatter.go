@@ -0,0 +1,258 @@
+package formatter
+
+import (
+	"calcWithTests/src/calculator"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Notation - запись чисел
+type Notation int
+
+const (
+	// Auto - обычная запись, а для очень больших и очень маленьких чисел научная
+	Auto Notation = iota
+	// Fixed - обычная запись с фиксированным числом цифр после точки: 1234.50
+	Fixed
+	// Scientific - научная запись: 1.2345e+03
+	Scientific
+	// Engineering - научная запись с порядком, кратным трём: 1.2345e+03, 12.345e+03
+	Engineering
+)
+
+var notationNames = map[Notation]string{
+	Auto:        "auto",
+	Fixed:       "fixed",
+	Scientific:  "sci",
+	Engineering: "eng",
+}
+
+func (n Notation) String() string {
+	if name, ok := notationNames[n]; ok {
+		return name
+	}
+	return fmt.Sprintf("Notation(%d)", int(n))
+}
+
+// ParseNotation возвращает запись по названию, которое печатает Notation.String
+func ParseNotation(name string) (Notation, error) {
+	for notation, notationName := range notationNames {
+		if notationName == name {
+			return notation, nil
+		}
+	}
+	return 0, calculator.Errorf("unknown notation %q", name)
+}
+
+// в Auto без Significant обычная запись используется для порядков от
+// autoMinExp до autoMaxExp, как в JavaScript
+const (
+	autoMinExp = -6
+	autoMaxExp = 20
+)
+
+// FormatOptions - как печатать числа результата. Нулевое значение
+// печатает кратчайшую запись, которая точно задаёт число.
+type FormatOptions struct {
+	Notation Notation
+	// Digits - цифр после точки в Fixed, Scientific и Engineering;
+	// -1 - столько, сколько нужно для точной записи
+	Digits int
+	// Significant - число значащих цифр, 0 - не ограничено. Если задано,
+	// Digits не используется.
+	Significant int
+	// GroupSeparator разделяет группы по три цифры в целой части, пустая
+	// строка - без групп
+	GroupSeparator string
+	// DecimalSeparator - десятичный разделитель, по умолчанию точка
+	DecimalSeparator string
+	// TrimZeros убирает нули в конце дробной части
+	TrimZeros bool
+}
+
+// Validate проверяет параметры
+func (o FormatOptions) Validate() error {
+	if _, ok := notationNames[o.Notation]; !ok {
+		return calculator.Errorf("unknown notation %v", o.Notation)
+	}
+	if o.Digits < -1 {
+		return calculator.Errorf("invalid number of digits %d", o.Digits)
+	}
+	if o.Significant < 0 {
+		return calculator.Errorf("invalid number of significant digits %d", o.Significant)
+	}
+	return nil
+}
+
+// Format печатает результат вычисления. Форматируются числа с плавающей
+// точкой; точные дроби и целые числа печатаются без округления, в них
+// только разделяются группы разрядов.
+func Format(result calculator.Value, options FormatOptions) string {
+	switch v := result.(type) {
+	case calculator.TextValue:
+		return v.Text(func(x float64) string {
+			return options.number(strconv.FormatFloat(x, 'g', -1, 64))
+		})
+	case calculator.BigFloat:
+		return options.number(v.String())
+	case calculator.Fraction:
+		if !v.Exact() {
+			x, _ := v.Float64()
+			return "≈" + options.number(strconv.FormatFloat(x, 'g', -1, 64))
+		}
+		return options.groupIntegers(v.String())
+	case calculator.Word:
+		if v.Base == 10 || v.Base == 0 {
+			return options.groupIntegers(v.String())
+		}
+	}
+	return result.String()
+}
+
+// number печатает одно число, записанное в s в десятичном виде
+func (o FormatOptions) number(s string) string {
+	d, ok := parseDecimal(s)
+	if !ok {
+		// бесконечность и NaN
+		return s
+	}
+
+	var text string
+	switch o.Notation {
+	case Fixed:
+		frac := -1
+		if o.Significant > 0 {
+			d.round(o.Significant)
+			frac = max(0, o.Significant-d.exp)
+		} else if o.Digits >= 0 {
+			d.round(d.exp + o.Digits)
+			frac = o.Digits
+		}
+		text = d.fixed(frac)
+	case Scientific, Engineering:
+		text = o.scientific(d, o.Digits)
+	default:
+		if o.Significant > 0 {
+			d.round(o.Significant)
+			if exp := d.exp - 1; !d.isZero() && (exp < -4 || exp >= o.Significant) {
+				text = o.scientific(d, -1)
+			} else {
+				text = d.fixed(max(0, o.Significant-d.exp))
+			}
+		} else if exp := d.exp - 1; !d.isZero() && (exp < autoMinExp || exp > autoMaxExp) {
+			text = o.scientific(d, -1)
+		} else {
+			text = d.fixed(-1)
+		}
+	}
+	return o.localize(text)
+}
+
+// scientific печатает число в научной или инженерной записи с digits
+// цифрами мантиссы после точки, если не задано Significant
+func (o FormatOptions) scientific(d decimal, digits int) string {
+	// порядок и число цифр целой части мантиссы
+	exponent := func() (int, int) {
+		if d.isZero() {
+			return 0, 1
+		}
+		exp := d.exp - 1
+		if o.Notation == Engineering {
+			exp -= ((exp % 3) + 3) % 3
+		}
+		return exp, d.exp - exp
+	}
+
+	exp, intDigits := exponent()
+	frac := -1
+	switch {
+	case o.Significant > 0:
+		d.round(o.Significant)
+		exp, intDigits = exponent()
+		frac = max(0, o.Significant-intDigits)
+	case digits >= 0:
+		d.round(intDigits + digits)
+		exp, _ = exponent()
+		frac = digits
+	}
+
+	mantissa := d
+	mantissa.exp -= exp
+	return mantissa.fixed(frac) + fmt.Sprintf("e%+03d", exp)
+}
+
+// localize убирает нули в конце дробной части и расставляет разделители
+func (o FormatOptions) localize(text string) string {
+	number, exp, _ := strings.Cut(text, "e")
+	intPart, frac, hasFrac := strings.Cut(number, ".")
+	if o.TrimZeros {
+		frac = strings.TrimRight(frac, "0")
+		hasFrac = frac != ""
+	}
+
+	var b strings.Builder
+	b.WriteString(o.group(intPart))
+	if hasFrac {
+		if o.DecimalSeparator != "" {
+			b.WriteString(o.DecimalSeparator)
+		} else {
+			b.WriteByte('.')
+		}
+		b.WriteString(frac)
+	}
+	if exp != "" {
+		b.WriteString("e" + exp)
+	}
+	return b.String()
+}
+
+// group разделяет группы по три цифры в целом числе со знаком
+func (o FormatOptions) group(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	if o.GroupSeparator == "" || len(s) <= 3 {
+		return sign + s
+	}
+
+	var b strings.Builder
+	b.WriteString(sign)
+	first := len(s) % 3
+	if first == 0 {
+		first = 3
+	}
+	b.WriteString(s[:first])
+	for i := first; i < len(s); i += 3 {
+		b.WriteString(o.GroupSeparator)
+		b.WriteString(s[i : i+3])
+	}
+	return b.String()
+}
+
+// groupIntegers разделяет группы разрядов во всех целых числах в s: 1/3, 3 1/2
+func (o FormatOptions) groupIntegers(s string) string {
+	if o.GroupSeparator == "" {
+		return s
+	}
+
+	var b strings.Builder
+	start := -1
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			b.WriteString(o.group(s[start:i]))
+			start = -1
+		}
+		if i < len(s) {
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
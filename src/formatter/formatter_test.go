@@ -0,0 +1,107 @@
+package formatter
+
+import (
+	"calcWithTests/src/calculator"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	type CaseFormat struct {
+		expression string
+		config     calculator.CalculatorConfig
+		options    FormatOptions
+		result     string
+	}
+	cases := []CaseFormat{
+		// Auto без ограничений - кратчайшая точная запись
+		{expression: "1/3", result: "0.3333333333333333"},
+		{expression: "10^6", result: "1000000"},
+		{expression: "2^66", result: "73786976294838210000"},
+		{expression: "2^70", result: "1.1805916207174113e+21"},
+		{expression: "2^80", result: "1.2089258196146292e+24"},
+		{expression: "1/2^20", result: "9.5367431640625e-07"},
+		{expression: "1/2^30", result: "9.313225746154785e-10"},
+		{expression: "-0.1 - 0.2", result: "-0.30000000000000004"},
+		{expression: "0", result: "0"},
+
+		// значащие цифры
+		{expression: "1/3", options: FormatOptions{Significant: 4}, result: "0.3333"},
+		{expression: "2", options: FormatOptions{Significant: 4}, result: "2.000"},
+		{expression: "2", options: FormatOptions{Significant: 4, TrimZeros: true}, result: "2"},
+		{expression: "9.9996", options: FormatOptions{Significant: 4}, result: "10.00"},
+		{expression: "123456", options: FormatOptions{Significant: 3}, result: "1.23e+05"},
+		{expression: "0.000012345", options: FormatOptions{Significant: 3}, result: "1.23e-05"},
+		{expression: "0.0012345", options: FormatOptions{Significant: 3}, result: "0.00123"},
+
+		// фиксированное число цифр после точки
+		{expression: "1/3", options: FormatOptions{Notation: Fixed, Digits: 2}, result: "0.33"},
+		{expression: "2/3", options: FormatOptions{Notation: Fixed, Digits: 0}, result: "1"},
+		{expression: "2.5", options: FormatOptions{Notation: Fixed, Digits: 0}, result: "3"},
+		{expression: "-2.5", options: FormatOptions{Notation: Fixed, Digits: 0}, result: "-3"},
+		{expression: "-0.001", options: FormatOptions{Notation: Fixed, Digits: 2}, result: "0.00"},
+		{expression: "9.999", options: FormatOptions{Notation: Fixed, Digits: 2}, result: "10.00"},
+		{expression: "1.5", options: FormatOptions{Notation: Fixed, Digits: 4, TrimZeros: true}, result: "1.5"},
+		{expression: "2^80", options: FormatOptions{Notation: Fixed, Digits: -1}, result: "1208925819614629200000000"},
+		{expression: "123456.789", options: FormatOptions{Notation: Fixed, Significant: 4}, result: "123500"},
+		{expression: "1/3", options: FormatOptions{Notation: Fixed, Significant: 2}, result: "0.33"},
+
+		// научная и инженерная запись
+		{expression: "1234.5", options: FormatOptions{Notation: Scientific, Digits: -1}, result: "1.2345e+03"},
+		{expression: "1234.5", options: FormatOptions{Notation: Scientific, Digits: 2}, result: "1.23e+03"},
+		{expression: "9.999", options: FormatOptions{Notation: Scientific, Digits: 2}, result: "1.00e+01"},
+		{expression: "-0.00042", options: FormatOptions{Notation: Scientific, Significant: 3}, result: "-4.20e-04"},
+		{expression: "0", options: FormatOptions{Notation: Scientific, Digits: 2}, result: "0.00e+00"},
+		{expression: "12345", options: FormatOptions{Notation: Engineering, Digits: -1}, result: "12.345e+03"},
+		{expression: "0.0001234", options: FormatOptions{Notation: Engineering, Digits: -1}, result: "123.4e-06"},
+		{expression: "999.96", options: FormatOptions{Notation: Engineering, Digits: 1}, result: "1.0e+03"},
+		{expression: "47000", options: FormatOptions{Notation: Engineering, Significant: 3}, result: "47.0e+03"},
+		{expression: "10^100", options: FormatOptions{Notation: Engineering, Digits: 0}, result: "10e+99"},
+
+		// разделители
+		{expression: "1234567.891", options: FormatOptions{GroupSeparator: ","}, result: "1,234,567.891"},
+		{expression: "-1234567.891", options: FormatOptions{GroupSeparator: " ", DecimalSeparator: ","}, result: "-1 234 567,891"},
+		{expression: "123", options: FormatOptions{GroupSeparator: ","}, result: "123"},
+		{expression: "123456", options: FormatOptions{Notation: Fixed, Digits: 2, GroupSeparator: "'"}, result: "123'456.00"},
+		{expression: "1/3", options: FormatOptions{DecimalSeparator: ","}, result: "0,3333333333333333"},
+
+		// другие режимы
+		{expression: "1/3", config: calculator.CalculatorConfig{Precision: 100}, options: FormatOptions{Significant: 5}, result: "0.33333"},
+		{expression: "1/3", config: calculator.CalculatorConfig{Precision: 100}, result: "0.333333333333333333333333333333"},
+		{expression: "3 + 4i", config: calculator.CalculatorConfig{Mode: calculator.Complex}, options: FormatOptions{Notation: Fixed, Digits: 1}, result: "3.0+4.0i"},
+		{expression: "1/3 - i", config: calculator.CalculatorConfig{Mode: calculator.Complex}, options: FormatOptions{Notation: Fixed, Digits: 2}, result: "0.33-1.00i"},
+		{expression: "1/3 + i", config: calculator.CalculatorConfig{Mode: calculator.Complex}, result: "0.3333333333333333+i"},
+		{expression: "1/3 ± 0.01", config: calculator.CalculatorConfig{Mode: calculator.Uncertainty}, options: FormatOptions{Significant: 2}, result: "0.33±0.0011"},
+		{expression: "1/3", config: calculator.CalculatorConfig{Mode: calculator.Interval}, options: FormatOptions{Notation: Fixed, Digits: 3}, result: "[0.333, 0.333]"},
+		{expression: "1 mi to km", config: calculator.CalculatorConfig{Mode: calculator.Units}, options: FormatOptions{Notation: Fixed, Digits: 2}, result: "1.61 km"},
+		{expression: "3 ft + 2 in to in", config: calculator.CalculatorConfig{Mode: calculator.Units}, options: FormatOptions{Significant: 15, TrimZeros: true}, result: "38 in"},
+		{expression: "2^40 / 3", config: calculator.CalculatorConfig{Mode: calculator.Rational}, options: FormatOptions{Notation: Fixed, Digits: 2, GroupSeparator: ","}, result: "1,099,511,627,776/3"},
+		{expression: "2^40", config: calculator.CalculatorConfig{Mode: calculator.Integer}, options: FormatOptions{GroupSeparator: " "}, result: "1 099 511 627 776"},
+		{expression: "255", config: calculator.CalculatorConfig{Mode: calculator.Integer, Base: 16}, options: FormatOptions{GroupSeparator: " "}, result: "0xff"},
+	}
+
+	for _, c := range cases {
+		value, err := calculator.Evaluate(c.expression, c.config)
+		require.NoError(t, err, c.expression)
+		require.Equal(t, c.result, Format(value, c.options), "%s %+v", c.expression, c.options)
+	}
+	require.Equal(t, "+Inf", Format(calculator.Float(math.Inf(1)), FormatOptions{Notation: Fixed, Digits: 2}))
+}
+
+func TestParseNotation(t *testing.T) {
+	for _, notation := range []Notation{Auto, Fixed, Scientific, Engineering} {
+		parsed, err := ParseNotation(notation.String())
+		require.NoError(t, err)
+		require.Equal(t, notation, parsed)
+	}
+	_, err := ParseNotation("roman")
+	require.EqualError(t, err, `unknown notation "roman"`)
+	require.Equal(t, `неизвестная запись "roman"`, calculator.Localize(err, calculator.Russian))
+
+	require.NoError(t, FormatOptions{Digits: -1}.Validate())
+	require.EqualError(t, FormatOptions{Digits: -2}.Validate(), "invalid number of digits -2")
+	require.EqualError(t, FormatOptions{Significant: -1}.Validate(), "invalid number of significant digits -1")
+	require.EqualError(t, FormatOptions{Notation: 7}.Validate(), "unknown notation Notation(7)")
+}
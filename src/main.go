@@ -1,22 +1,45 @@
 package main
 
 import (
+	"calcWithTests/src/batch"
 	"calcWithTests/src/calculator"
+	"calcWithTests/src/formatter"
+	"calcWithTests/src/repl"
+	"calcWithTests/src/report"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"runtime"
 )
 
 func main() {
-	args := os.Args[1:]
-	if len(args) == 0 {
-		fmt.Println("no argument provided")
-		fmt.Println("usage: ./calculate [your expression]")
-		return
-	}
-
-	helpFlag := flag.Bool("help", false, "usage: ./calculate [your expression]")
-	angleUnit := flag.String("angle-unit", "radian", "Angle unit (degree or radian)")
+	helpFlag := flag.Bool("help", false, "usage: ./calculate [your expression], without an expression starts an interactive session")
+	replFlag := flag.Bool("repl", false, "Start an interactive session")
+	batchFlag := flag.Bool("batch", false, "Evaluate expressions from stdin, one per line")
+	batchFile := flag.String("f", "", "Evaluate expressions from the file, one per line")
+	workers := flag.Int("workers", runtime.GOMAXPROCS(0), "Number of expressions evaluated in parallel in batch mode")
+	historyFile := flag.String("history", repl.DefaultHistoryPath(), "History file of the interactive session (empty - do not save history)")
+	angleUnit := flag.String("angle-unit", "radian", "Angle unit (radian, degree, gradian or turn)")
+	mode := flag.String("mode", "float", "Number mode (float, rational, complex, interval, uncertainty, units or integer)")
+	mixedFractions := flag.Bool("mixed-fractions", false, "Print rational results as mixed fractions (3 1/2)")
+	allowInexact := flag.Bool("allow-inexact", false, "Allow irrational functions in rational mode, computed in float64")
+	polar := flag.Bool("polar", false, "Print complex results in polar form (r∠θ)")
+	precision := flag.Uint("precision", 0, "Mantissa precision in bits for arbitrary-precision mode (0 - float64)")
+	base := flag.String("base", "dec", "Output base for integer mode (hex, bin, oct or dec)")
+	width := flag.Uint("width", 64, "Integer width in bits for integer mode (8, 16, 32 or 64)")
+	unsigned := flag.Bool("unsigned", false, "Use unsigned integers in integer mode")
+	overflowError := flag.Bool("overflow-error", false, "Report integer overflow as an error instead of wrapping around")
+	unitsFile := flag.String("units", "", "File with additional units for units mode (see src/calculator/units.txt)")
+	output := flag.String("output", "text", "Output format (text or json, JSON Lines in batch mode)")
+	notation := flag.String("notation", "auto", "Number notation (auto, fixed, sci or eng)")
+	digits := flag.Int("digits", -1, "Digits after the decimal point in fixed, sci and eng notation (-1 - as many as needed)")
+	significant := flag.Int("significant", 0, "Number of significant digits (0 - not limited)")
+	groupSeparator := flag.String("group-separator", "", "Separator of digit groups in the integer part, e.g. \",\" or \" \"")
+	decimalSeparator := flag.String("decimal-separator", "", "Decimal separator in results (default - the one of the locale)")
+	trimZeros := flag.Bool("trim-zeros", false, "Remove trailing zeros of the fractional part")
+	langName := flag.String("lang", "en", "Language of error messages (en or ru); JSON output keeps messages in English")
+	localeName := flag.String("locale", "en", "Number format of expressions: en (1000.5, f(a, b)), ru (1 000,5, f(a; b)), de (1.000,5) or ch (1'000.5)")
 
 	flag.Parse()
 
@@ -25,18 +48,187 @@ func main() {
 		os.Exit(0)
 	}
 
-	if *angleUnit != "degree" && *angleUnit != "radian" {
-		fmt.Println("Error: angle-unit must be either 'degree' or 'radian'")
+	lang, err := calculator.ParseLanguage(*langName)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		flag.Usage()
+		os.Exit(report.ExitUsage)
+	}
+	// printError печатает уже переведённый текст ошибки
+	printError := func(text string) {
+		fmt.Printf("%s: %s\n", calculator.Translate("Error", lang), text)
+	}
+
+	angleUnits, err := calculator.ParseAngleUnit(*angleUnit)
+	if err != nil {
+		printError(calculator.Localize(err, lang))
+		flag.Usage()
+		os.Exit(report.ExitUsage)
+	}
+
+	calcMode, err := calculator.ParseMode(*mode)
+	if err != nil {
+		printError(calculator.Localize(err, lang))
+		flag.Usage()
+		os.Exit(report.ExitUsage)
+	}
+
+	outputBase, err := calculator.ParseBase(*base)
+	if err != nil {
+		printError(calculator.Localize(err, lang))
+		flag.Usage()
+		os.Exit(report.ExitUsage)
+	}
+	if outputBase != 10 && calcMode != calculator.Integer {
+		printError(calculator.Translate("base is only supported in integer mode", lang))
+		os.Exit(report.ExitUsage)
+	}
+	if *output != "text" && *output != "json" {
+		printError(calculator.Translate("unknown output format %q", lang, *output))
+		flag.Usage()
+		os.Exit(report.ExitUsage)
+	}
+	jsonOutput := *output == "json"
+
+	locale, err := calculator.ParseLocale(*localeName)
+	if err != nil {
+		printError(calculator.Localize(err, lang))
+		flag.Usage()
+		os.Exit(report.ExitUsage)
+	}
+	if *decimalSeparator == "" {
+		*decimalSeparator = string(locale.DecimalSeparator())
+	}
+
+	numberNotation, err := formatter.ParseNotation(*notation)
+	if err != nil {
+		printError(calculator.Localize(err, lang))
 		flag.Usage()
-		os.Exit(1)
+		os.Exit(report.ExitUsage)
+	}
+	format := formatter.FormatOptions{
+		Notation:         numberNotation,
+		Digits:           *digits,
+		Significant:      *significant,
+		GroupSeparator:   *groupSeparator,
+		DecimalSeparator: *decimalSeparator,
+		TrimZeros:        *trimZeros,
+	}
+	if err := format.Validate(); err != nil {
+		printError(calculator.Localize(err, lang))
+		os.Exit(report.ExitUsage)
+	}
+
+	var units *calculator.UnitDatabase
+	if *unitsFile != "" {
+		units = calculator.DefaultUnits()
+		if err := units.LoadFile(*unitsFile); err != nil {
+			printError(calculator.Localize(err, lang))
+			os.Exit(report.ExitIO)
+		}
+	}
+
+	config := calculator.CalculatorConfig{
+		AngleUnits:     angleUnits,
+		Mode:           calcMode,
+		Precision:      *precision,
+		MixedFractions: *mixedFractions,
+		AllowInexact:   *allowInexact,
+		PolarOutput:    *polar,
+		UnitDatabase:   units,
+		IntWidth:       *width,
+		Unsigned:       *unsigned,
+		OverflowError:  *overflowError,
+		Base:           outputBase,
+		Locale:         locale,
+	}
+
+	if *batchFlag || *batchFile != "" {
+		os.Exit(runBatch(*batchFile, config, format, lang, *workers, jsonOutput))
+	}
+
+	args := flag.Args()
+	if *replFlag || len(args) == 0 {
+		if err := repl.Run(config, format, lang, os.Stdin, os.Stdout, *historyFile); err != nil {
+			printError(calculator.Localize(err, lang))
+			os.Exit(1)
+		}
+		return
 	}
 
 	expr := args[len(args)-1]
-	result, err := calculator.Calculate(expr, calculator.CalculatorConfig{AngleUnits: *angleUnit})
+	result, err := calculator.Evaluate(expr, config)
+	if jsonOutput {
+		if err := report.Write(os.Stdout, report.New(expr, config, format, result, err)); err != nil {
+			os.Exit(report.ExitIO)
+		}
+		os.Exit(report.ExitCode(err))
+	}
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		printError(calculator.Localize(err, lang))
+
+		var posErr *calculator.Error
+		if errors.As(err, &posErr) {
+			fmt.Println(posErr.Caret(expr))
+		}
+		os.Exit(report.ExitCode(err))
 	}
 
-	fmt.Println(result)
+	fmt.Println(formatter.Format(result, format))
+}
+
+// runBatch печатает результат каждой строки с её номером и возвращает код
+// выхода: код категории ошибки, если все ошибочные строки одной категории,
+// иначе 1
+func runBatch(path string, config calculator.CalculatorConfig, format formatter.FormatOptions, lang calculator.Language, workers int, jsonOutput bool) int {
+	in := os.Stdin
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Printf("%s: %v\n", calculator.Translate("Error", lang), err)
+			return report.ExitIO
+		}
+		defer f.Close()
+		in = f
+	}
+
+	total := 0
+	code := report.ExitOK
+	// первая ошибка записи JSON; после неё строки уже не печатаются
+	var writeErr error
+	failed, err := batch.Run(in, config, workers, func(r batch.Result) {
+		total++
+		if r.Err != nil {
+			lineCode := report.ExitCode(r.Err)
+			if code == report.ExitOK {
+				code = lineCode
+			} else if code != lineCode {
+				code = report.ExitFailure
+			}
+		}
+		if jsonOutput {
+			rep := report.New(r.Expression, config, format, r.Value, r.Err)
+			rep.Line = r.Line
+			if writeErr == nil {
+				writeErr = report.Write(os.Stdout, rep)
+			}
+			return
+		}
+		if r.Err != nil {
+			fmt.Printf("%d: %s: %s\n", r.Line, calculator.Translate("Error", lang), calculator.Localize(r.Err, lang))
+			return
+		}
+		fmt.Printf("%d: %s\n", r.Line, formatter.Format(r.Value, format))
+	})
+	if err == nil {
+		err = writeErr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", calculator.Translate("Error", lang), err)
+		return report.ExitIO
+	}
+	if failed > 0 {
+		fmt.Fprintln(os.Stderr, calculator.Translate("%d of %d lines failed", lang, failed, total))
+	}
+	return code
 }
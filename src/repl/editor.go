@@ -0,0 +1,207 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"unicode"
+)
+
+// errInterrupted - ввод строки прерван через Ctrl+C
+var errInterrupted = errors.New("interrupted")
+
+// Коды управляющих клавиш
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyBackspace = 8
+	keyCtrlK     = 11
+	keyCtrlL     = 12
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// editor редактирует строку в терминале в сыром режиме: стрелки, Home/End,
+// Backspace/Delete, сочетания Ctrl как в emacs и листание истории
+type editor struct {
+	in      *bufio.Reader
+	out     io.Writer
+	history *history
+
+	prompt string
+	line   []rune
+	pos    int
+}
+
+// readLine читает строку. Ctrl+D в пустой строке возвращает io.EOF,
+// Ctrl+C - errInterrupted.
+func (ed *editor) readLine(prompt string) (string, error) {
+	ed.prompt, ed.line, ed.pos = prompt, nil, 0
+	// index - текущая строка истории, len(entries) - новая строка;
+	// её содержимое сохраняется в draft, пока листается история
+	index := len(ed.history.entries)
+	var draft []rune
+	ed.refresh()
+
+	for {
+		r, _, err := ed.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		switch r {
+		case keyEnter, '\n':
+			fmt.Fprint(ed.out, "\n")
+			return string(ed.line), nil
+		case keyCtrlC:
+			fmt.Fprint(ed.out, "^C\n")
+			return "", errInterrupted
+		case keyCtrlD:
+			if len(ed.line) == 0 {
+				fmt.Fprint(ed.out, "\n")
+				return "", io.EOF
+			}
+			ed.deleteAt(ed.pos)
+		case keyBackspace, keyDelete:
+			if ed.pos > 0 {
+				ed.pos--
+				ed.deleteAt(ed.pos)
+			}
+		case keyCtrlA:
+			ed.pos = 0
+		case keyCtrlE:
+			ed.pos = len(ed.line)
+		case keyCtrlB:
+			ed.pos = max(ed.pos-1, 0)
+		case keyCtrlF:
+			ed.pos = min(ed.pos+1, len(ed.line))
+		case keyCtrlK:
+			ed.line = ed.line[:ed.pos]
+		case keyCtrlU:
+			ed.line = append([]rune{}, ed.line[ed.pos:]...)
+			ed.pos = 0
+		case keyCtrlW:
+			start := ed.pos
+			for start > 0 && unicode.IsSpace(ed.line[start-1]) {
+				start--
+			}
+			for start > 0 && !unicode.IsSpace(ed.line[start-1]) {
+				start--
+			}
+			ed.line = append(ed.line[:start], ed.line[ed.pos:]...)
+			ed.pos = start
+		case keyCtrlL:
+			fmt.Fprint(ed.out, "\x1b[H\x1b[2J")
+		case keyCtrlP, keyCtrlN:
+			index, draft = ed.browse(r == keyCtrlP, index, draft)
+		case keyEscape:
+			key, err := ed.escape()
+			if err != nil {
+				return "", err
+			}
+			switch key {
+			case 'A', 'B':
+				index, draft = ed.browse(key == 'A', index, draft)
+			case 'C':
+				ed.pos = min(ed.pos+1, len(ed.line))
+			case 'D':
+				ed.pos = max(ed.pos-1, 0)
+			case 'H':
+				ed.pos = 0
+			case 'F':
+				ed.pos = len(ed.line)
+			case '~':
+				if ed.pos < len(ed.line) {
+					ed.deleteAt(ed.pos)
+				}
+			}
+		default:
+			if unicode.IsControl(r) {
+				continue
+			}
+			ed.line = append(ed.line[:ed.pos], append([]rune{r}, ed.line[ed.pos:]...)...)
+			ed.pos++
+		}
+		ed.refresh()
+	}
+}
+
+// escape разбирает последовательность после ESC и возвращает клавишу:
+// A-D - стрелки, H и F - Home и End, ~ - Delete, 0 - неизвестная клавиша
+func (ed *editor) escape() (rune, error) {
+	r, _, err := ed.in.ReadRune()
+	if err != nil || r != '[' && r != 'O' {
+		return 0, err
+	}
+	var param []rune
+	for {
+		r, _, err = ed.in.ReadRune()
+		if err != nil {
+			return 0, err
+		}
+		// параметры вида 1;5 у стрелок с Ctrl
+		if (r < '0' || r > '9') && r != ';' {
+			break
+		}
+		param = append(param, r)
+	}
+	if r != '~' {
+		return r, nil
+	}
+	// ESC [ n ~: 1 и 7 - Home, 4 и 8 - End, 3 - Delete
+	switch string(param) {
+	case "1", "7":
+		return 'H', nil
+	case "4", "8":
+		return 'F', nil
+	case "3":
+		return '~', nil
+	}
+	return 0, nil
+}
+
+// browse переходит к предыдущей (up) или следующей строке истории
+func (ed *editor) browse(up bool, index int, draft []rune) (int, []rune) {
+	entries := ed.history.entries
+	next := index + 1
+	if up {
+		next = index - 1
+	}
+	if next < 0 || next > len(entries) {
+		return index, draft
+	}
+	if index == len(entries) {
+		draft = ed.line
+	}
+	if next == len(entries) {
+		ed.line = draft
+	} else {
+		ed.line = []rune(entries[next])
+	}
+	ed.pos = len(ed.line)
+	return next, draft
+}
+
+func (ed *editor) deleteAt(i int) {
+	if i < len(ed.line) {
+		ed.line = append(ed.line[:i], ed.line[i+1:]...)
+	}
+}
+
+// refresh перерисовывает строку и ставит курсор на место
+func (ed *editor) refresh() {
+	fmt.Fprintf(ed.out, "\r%s%s\x1b[K", ed.prompt, string(ed.line))
+	if back := len(ed.line) - ed.pos; back > 0 {
+		fmt.Fprintf(ed.out, "\x1b[%dD", back)
+	}
+}
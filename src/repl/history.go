@@ -0,0 +1,70 @@
+package repl
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// maxHistory - сколько последних строк истории хранится
+const maxHistory = 1000
+
+// history - введённые строки. Если задан path, они загружаются из файла
+// и дописываются в него.
+type history struct {
+	entries []string
+	path    string
+}
+
+func loadHistory(path string) (*history, error) {
+	h := &history{path: path}
+	if path == "" {
+		return h, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	// файл только дописывается, поэтому при загрузке он обрезается
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+		if err := os.WriteFile(path, []byte(strings.Join(h.entries, "\n")+"\n"), 0o600); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// add запоминает строку; пустые строки и повтор предыдущей пропускаются
+func (h *history) add(line string) error {
+	line = strings.TrimSpace(line)
+	if line == "" || len(h.entries) > 0 && h.entries[len(h.entries)-1] == line {
+		return nil
+	}
+	h.entries = append(h.entries, line)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[1:]
+	}
+	if h.path == "" {
+		return nil
+	}
+
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(line + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
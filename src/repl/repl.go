@@ -0,0 +1,194 @@
+package repl
+
+import (
+	"bufio"
+	"calcWithTests/src/calculator"
+	"calcWithTests/src/formatter"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const prompt = "> "
+
+const help = `Enter an expression to evaluate it. Variables and functions are kept
+between lines, ans and _ hold the previous result.
+
+Commands:
+  :vars              list variables
+  :funcs             list user-defined functions
+  :mode [name]       show or set the number mode or the angle unit
+  :precision [bits]  show or set the precision (0 - float64)
+  :help              show this help
+  :quit              exit`
+
+// REPL - интерактивная сессия калькулятора: строки вычисляются по одной,
+// переменные и функции сохраняются между ними
+type REPL struct {
+	session *calculator.Session
+	format  formatter.FormatOptions
+	// язык сообщений об ошибках вычисления
+	lang calculator.Language
+	out  io.Writer
+}
+
+func New(config calculator.CalculatorConfig, format formatter.FormatOptions, lang calculator.Language, out io.Writer) *REPL {
+	return &REPL{session: calculator.NewSession(config), format: format, lang: lang, out: out}
+}
+
+// DefaultHistoryPath возвращает путь к файлу истории в домашнем каталоге
+func DefaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".calculate_history")
+}
+
+// Run читает строки из in до :quit или конца ввода. Если in - терминал,
+// строку можно редактировать, а введённые строки сохраняются в historyPath
+// (пустой путь - история только в памяти).
+func Run(config calculator.CalculatorConfig, format formatter.FormatOptions, lang calculator.Language, in *os.File, out io.Writer, historyPath string) error {
+	r := New(config, format, lang, out)
+	if !isTerminal(int(in.Fd())) {
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			if !r.Execute(scanner.Text()) {
+				return nil
+			}
+		}
+		return scanner.Err()
+	}
+
+	history, err := loadHistory(historyPath)
+	if err != nil {
+		return err
+	}
+	state, err := makeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer restore(int(in.Fd()), state)
+
+	ed := &editor{in: bufio.NewReader(in), out: out, history: history}
+	fmt.Fprintln(out, "Type :help for the list of commands, :quit to exit")
+	for {
+		line, err := ed.readLine(prompt)
+		if errors.Is(err, errInterrupted) {
+			continue
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := history.add(line); err != nil {
+			r.printError(err.Error())
+		}
+		if !r.Execute(line) {
+			return nil
+		}
+	}
+}
+
+// Execute выполняет строку: мета-команду или выражение. После :quit
+// возвращает false.
+func (r *REPL) Execute(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return true
+	}
+	if strings.HasPrefix(line, ":") {
+		return r.command(strings.Fields(line[1:]))
+	}
+
+	result, hasValue, err := r.session.Evaluate(line)
+	if err != nil {
+		r.printError(calculator.Localize(err, r.lang))
+		var posErr *calculator.Error
+		if errors.As(err, &posErr) {
+			fmt.Fprintln(r.out, posErr.Caret(line))
+		}
+		return true
+	}
+	if hasValue {
+		fmt.Fprintln(r.out, formatter.Format(result, r.format))
+	}
+	return true
+}
+
+func (r *REPL) command(fields []string) bool {
+	if len(fields) == 0 {
+		fields = []string{""}
+	}
+	name, args := fields[0], fields[1:]
+	config := r.session.Config()
+	switch name {
+	case "quit", "q", "exit":
+		return false
+	case "help":
+		fmt.Fprintln(r.out, help)
+	case "vars":
+		values := r.session.Values()
+		if len(values) == 0 {
+			fmt.Fprintln(r.out, "no variables")
+		}
+		for _, name := range slices.Sorted(maps.Keys(values)) {
+			fmt.Fprintf(r.out, "%s = %s\n", name, formatter.Format(values[name], r.format))
+		}
+	case "funcs":
+		defs := r.session.Functions()
+		if len(defs) == 0 {
+			fmt.Fprintln(r.out, "no functions")
+		}
+		for _, def := range defs {
+			fmt.Fprintln(r.out, def)
+		}
+	case "mode":
+		if len(args) == 0 {
+			unit := config.AngleUnits
+			if unit == "" {
+				unit = calculator.Radian
+			}
+			fmt.Fprintf(r.out, "mode: %s, angle unit: %s\n", config.Mode, unit)
+			return true
+		}
+		// :mode rational меняет режим, :mode degree - единицу углов
+		if mode, err := calculator.ParseMode(args[0]); err == nil {
+			config.Mode = mode
+		} else if unit, err := calculator.ParseAngleUnit(args[0]); err == nil {
+			config.AngleUnits = unit
+		} else {
+			r.printError(calculator.Translate("unknown mode or angle unit %q", r.lang, args[0]))
+			return true
+		}
+		r.session.SetConfig(config)
+	case "precision":
+		if len(args) == 0 {
+			fmt.Fprintf(r.out, "precision: %d bits\n", config.Precision)
+			return true
+		}
+		bits, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			r.printError(calculator.Translate("invalid precision %q", r.lang, args[0]))
+			return true
+		}
+		config.Precision = uint(bits)
+		r.session.SetConfig(config)
+	default:
+		r.printError(calculator.Translate("unknown command :%s, type :help for the list of commands", r.lang, name))
+	}
+	return true
+}
+
+// printError печатает уже переведённый текст ошибки
+func (r *REPL) printError(text string) {
+	fmt.Fprintf(r.out, "%s: %s\n", calculator.Translate("Error", r.lang), text)
+}
@@ -0,0 +1,141 @@
+package repl
+
+import (
+	"bufio"
+	"calcWithTests/src/calculator"
+	"calcWithTests/src/formatter"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecute(t *testing.T) {
+	type CaseExecute struct {
+		line   string
+		output string
+		quit   bool
+	}
+	cases := []CaseExecute{
+		{line: "x = 2", output: "2\n"},
+		{line: "f(y) = y * x", output: ""},
+		{line: "f(3) + 1", output: "7\n"},
+		{line: "ans * 2", output: "14\n"},
+		{line: "_ + x", output: "16\n"},
+		{line: "   ", output: ""},
+		{line: ":vars", output: "_ = 16\nans = 16\nx = 2\n"},
+		{line: ":funcs", output: "f(y) = y * x\n"},
+		{line: ":mode", output: "mode: float, angle unit: radian\n"},
+		{line: ":mode degree", output: ""},
+		{line: "sin(30)", output: "0.49999999999999994\n"},
+		{line: ":mode rational", output: ""},
+		{line: ":mode", output: "mode: rational, angle unit: degree\n"},
+		{line: "1/3 + x", output: "7/3\n"},
+		{line: ":precision", output: "precision: 0 bits\n"},
+		{line: ":precision 100", output: ""},
+		{line: ":mode float", output: ""},
+		{line: "1/3", output: "0.333333333333333333333333333333\n"},
+		{line: "1 +", output: "Error: error while parsing: 1:4: unexpected end of expression\n1 +\n   ^\n"},
+		{line: ":mode hex", output: "Error: unknown mode or angle unit \"hex\"\n"},
+		{line: ":precision -1", output: "Error: invalid precision \"-1\"\n"},
+		{line: ":nope", output: "Error: unknown command :nope, type :help for the list of commands\n"},
+		{line: ":quit", quit: true},
+	}
+
+	var out strings.Builder
+	r := New(calculator.CalculatorConfig{}, formatter.FormatOptions{}, calculator.English, &out)
+	for _, c := range cases {
+		out.Reset()
+		require.Equal(t, !c.quit, r.Execute(c.line), c.line)
+		require.Equal(t, c.output, out.String(), c.line)
+	}
+
+	// ошибки вычисления печатаются на выбранном языке
+	out.Reset()
+	r = New(calculator.CalculatorConfig{}, formatter.FormatOptions{}, calculator.Russian, &out)
+	require.True(t, r.Execute("1 / 0"))
+	require.Equal(t, "Ошибка: ошибка вычисления: 1:1: вычисление деления: деление на ноль\n1 / 0\n^~~~~\n", out.String())
+	out.Reset()
+	require.True(t, r.Execute(":mode hex"))
+	require.Equal(t, "Ошибка: неизвестный режим или единица углов \"hex\"\n", out.String())
+}
+
+func TestEditor(t *testing.T) {
+	type CaseEditor struct {
+		input string
+		lines []string
+	}
+	cases := []CaseEditor{
+		{input: "1+2\r", lines: []string{"1+2"}},
+		// стрелка влево, Home, End, Delete, Backspace
+		{input: "12\x1b[D3\r", lines: []string{"132"}},
+		{input: "23\x1b[H1\x1b[F4\r", lines: []string{"1234"}},
+		{input: "123\x1b[H\x1b[3~\x7f\r", lines: []string{"23"}},
+		{input: "1;5\x1b[1;5D\x1b[1;5D\x1b[1;5C\x1b[1;5C\x08\r", lines: []string{"1;"}},
+		// Ctrl+A, Ctrl+E, Ctrl+K, Ctrl+U, Ctrl+W
+		{input: "bc\x01a\x05d\r", lines: []string{"abcd"}},
+		{input: "12345\x02\x02\x0b\r", lines: []string{"123"}},
+		{input: "12345\x02\x02\x15\r", lines: []string{"45"}},
+		{input: "sin(x) + cos\x17tg\r", lines: []string{"sin(x) + tg"}},
+		{input: "sin(30°)\r", lines: []string{"sin(30°)"}},
+		// история: вверх, вниз возвращает недописанную строку
+		{input: "1\r2\r\x1b[A\x1b[A\r", lines: []string{"1", "2", "1"}},
+		{input: "1\r2\r3\x1b[A\x1b[B\r", lines: []string{"1", "2", "3"}},
+		{input: "1\r\x10\x10\x0e\x0e\r", lines: []string{"1", ""}},
+		// Ctrl+C отменяет строку, Ctrl+D в пустой строке завершает ввод
+		{input: "1+\x032\r\x04", lines: []string{"2"}},
+		{input: "12\x01\x04\r\x04", lines: []string{"2"}},
+	}
+
+	for _, c := range cases {
+		h := &history{}
+		ed := &editor{in: bufio.NewReader(strings.NewReader(c.input)), out: io.Discard, history: h}
+		var lines []string
+		for {
+			line, err := ed.readLine(prompt)
+			if err == errInterrupted {
+				continue
+			}
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err, c.input)
+			lines = append(lines, line)
+			require.NoError(t, h.add(line))
+		}
+		require.Equal(t, c.lines, lines, c.input)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	h, err := loadHistory(path)
+	require.NoError(t, err)
+	require.Empty(t, h.entries)
+
+	for _, line := range []string{"1 + 2", "1 + 2", "", "x = 3", "  sin(x)  "} {
+		require.NoError(t, h.add(line))
+	}
+	require.Equal(t, []string{"1 + 2", "x = 3", "sin(x)"}, h.entries)
+
+	h, err = loadHistory(path)
+	require.NoError(t, err)
+	require.Equal(t, []string{"1 + 2", "x = 3", "sin(x)"}, h.entries)
+
+	// при загрузке файл обрезается до maxHistory строк
+	var lines []string
+	for i := range maxHistory + 10 {
+		lines = append(lines, strings.Repeat("1", i+1))
+	}
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600))
+	h, err = loadHistory(path)
+	require.NoError(t, err)
+	require.Equal(t, lines[10:], h.entries)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, maxHistory, strings.Count(string(data), "\n"))
+}
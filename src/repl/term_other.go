@@ -0,0 +1,20 @@
+//go:build !linux
+
+package repl
+
+import "errors"
+
+// на других системах строки читаются без редактирования
+type termState struct{}
+
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (*termState, error) {
+	return nil, errors.New("line editing is not supported on this system")
+}
+
+func restore(fd int, state *termState) error {
+	return nil
+}
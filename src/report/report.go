@@ -0,0 +1,133 @@
+package report
+
+import (
+	"calcWithTests/src/calculator"
+	"calcWithTests/src/formatter"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// Коды выхода процесса. Значения стабильны: на них опираются скрипты.
+const (
+	ExitOK = 0
+	// ExitFailure - ошибка без категории или ошибки разных категорий в пакетном режиме
+	ExitFailure = 1
+	// ExitUsage - неверные флаги командной строки
+	ExitUsage          = 2
+	ExitSyntax         = 3
+	ExitUndefined      = 4
+	ExitDivisionByZero = 5
+	ExitOverflow       = 6
+	ExitMath           = 7
+	ExitUnsupported    = 8
+	// ExitIO - не удалось прочитать ввод или записать вывод
+	ExitIO = 9
+)
+
+var exitCodes = map[calculator.ErrorKind]int{
+	calculator.KindSyntax:         ExitSyntax,
+	calculator.KindUndefined:      ExitUndefined,
+	calculator.KindDivisionByZero: ExitDivisionByZero,
+	calculator.KindOverflow:       ExitOverflow,
+	calculator.KindMath:           ExitMath,
+	calculator.KindUnsupported:    ExitUnsupported,
+}
+
+// ExitCode возвращает код выхода для ошибки вычисления
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	if code, ok := exitCodes[calculator.KindOf(err)]; ok {
+		return code
+	}
+	return ExitFailure
+}
+
+// Report - результат вычисления одного выражения в JSON
+type Report struct {
+	// номер строки в пакетном режиме
+	Line       int    `json:"line,omitempty"`
+	Expression string `json:"expression"`
+	// Result - число JSON (json.Number) в режиме float, в том числе с
+	// Precision, и в режиме integer с основанием 10. В остальных режимах
+	// значение числом не записать, и Result - строка: дробь, комплексное
+	// число, интервал, величина с погрешностью или с единицами, целое в
+	// другом основании. При ошибке Result - nil.
+	Result    any    `json:"result"`
+	Error     *Error `json:"error"`
+	Mode      string `json:"mode"`
+	AngleUnit string `json:"angle_unit"`
+}
+
+// Error - описание ошибки вычисления
+type Error struct {
+	Kind calculator.ErrorKind `json:"kind"`
+	// нет, если ошибка не привязана к месту в выражении
+	Position *Position `json:"position"`
+	Message  string    `json:"message"`
+}
+
+// Position - строка и колонка ошибки, начиная с 1
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// New собирает отчёт по результату calculator.Evaluate, результат печатается
+// с параметрами format
+func New(expression string, config calculator.CalculatorConfig, format formatter.FormatOptions, value calculator.Value, err error) Report {
+	unit := config.AngleUnits
+	if unit == "" {
+		unit = calculator.Radian
+	}
+	r := Report{
+		Expression: expression,
+		Mode:       config.Mode.String(),
+		AngleUnit:  string(unit),
+	}
+	if err != nil {
+		r.Error = &Error{Kind: calculator.KindOf(err), Message: err.Error()}
+		var posErr *calculator.Error
+		if errors.As(err, &posErr) && posErr.Line > 0 {
+			r.Error.Position = &Position{Line: posErr.Line, Column: posErr.Column}
+		}
+		return r
+	}
+	r.Result = result(value, format)
+	return r
+}
+
+// result возвращает результат для JSON. Числа печатаются с записью и
+// точностью из format, но без разделителей групп и с десятичной точкой.
+func result(value calculator.Value, format formatter.FormatOptions) any {
+	if isNumber(value) {
+		plain := format
+		plain.GroupSeparator, plain.DecimalSeparator = "", ""
+		// бесконечность и NaN числами JSON не бывают
+		if text := formatter.Format(value, plain); json.Valid([]byte(text)) {
+			return json.Number(text)
+		}
+	}
+	return formatter.Format(value, format)
+}
+
+// isNumber сообщает, что значение записывается одним десятичным числом
+func isNumber(value calculator.Value) bool {
+	switch v := value.(type) {
+	case calculator.Float, calculator.BigFloat:
+		return true
+	case calculator.Word:
+		return v.Base == 10 || v.Base == 0
+	}
+	return false
+}
+
+// Write печатает отчёт одной строкой, так что несколько отчётов подряд
+// образуют JSON Lines
+func Write(w io.Writer, r Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(r)
+}
@@ -0,0 +1,108 @@
+package report
+
+import (
+	"calcWithTests/src/calculator"
+	"calcWithTests/src/formatter"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	type CaseReport struct {
+		expression string
+		config     calculator.CalculatorConfig
+		format     formatter.FormatOptions
+		json       string
+		code       int
+	}
+	cases := []CaseReport{
+		{
+			expression: "1 + 2",
+			json:       `{"expression":"1 + 2","result":3,"error":null,"mode":"float","angle_unit":"radian"}`,
+		},
+		{
+			expression: "1/3 + 1/6",
+			config:     calculator.CalculatorConfig{Mode: calculator.Rational, AngleUnits: calculator.Degree},
+			json:       `{"expression":"1/3 + 1/6","result":"1/2","error":null,"mode":"rational","angle_unit":"degree"}`,
+		},
+		{
+			expression: "2/3 * 1000",
+			format:     formatter.FormatOptions{Notation: formatter.Fixed, Digits: 2, GroupSeparator: ","},
+			json:       `{"expression":"2/3 * 1000","result":666.67,"error":null,"mode":"float","angle_unit":"radian"}`,
+		},
+		{
+			expression: "2^100",
+			config:     calculator.CalculatorConfig{Precision: 128},
+			format:     formatter.FormatOptions{GroupSeparator: ",", DecimalSeparator: ","},
+			json:       `{"expression":"2^100","result":1.267650600228229401496703205376e+30,"error":null,"mode":"float","angle_unit":"radian"}`,
+		},
+		{
+			expression: "255",
+			config:     calculator.CalculatorConfig{Mode: calculator.Integer},
+			json:       `{"expression":"255","result":255,"error":null,"mode":"integer","angle_unit":"radian"}`,
+		},
+		{
+			expression: "255",
+			config:     calculator.CalculatorConfig{Mode: calculator.Integer, Base: 16},
+			json:       `{"expression":"255","result":"0xff","error":null,"mode":"integer","angle_unit":"radian"}`,
+		},
+		{
+			expression: "2 + 3i",
+			config:     calculator.CalculatorConfig{Mode: calculator.Complex},
+			json:       `{"expression":"2 + 3i","result":"2+3i","error":null,"mode":"complex","angle_unit":"radian"}`,
+		},
+		{
+			expression: "1 +",
+			json:       `{"expression":"1 +","result":null,"error":{"kind":"syntax","position":{"line":1,"column":4},"message":"error while parsing: 1:4: unexpected end of expression"},"mode":"float","angle_unit":"radian"}`,
+			code:       ExitSyntax,
+		},
+		{
+			expression: "x < 1",
+			json:       `{"expression":"x < 1","result":null,"error":{"kind":"syntax","position":{"line":1,"column":3},"message":"error while parsing: 1:3: unexpected character: <"},"mode":"float","angle_unit":"radian"}`,
+			code:       ExitSyntax,
+		},
+		{
+			expression: "2 * y",
+			json:       `{"expression":"2 * y","result":null,"error":{"kind":"undefined","position":{"line":1,"column":5},"message":"error while calculating: 1:5: undefined variable y"},"mode":"float","angle_unit":"radian"}`,
+			code:       ExitUndefined,
+		},
+		{
+			expression: "1 / (2 - 2)",
+			json:       `{"expression":"1 / (2 - 2)","result":null,"error":{"kind":"division_by_zero","position":{"line":1,"column":1},"message":"error while calculating: 1:1: calculating division: division by zero"},"mode":"float","angle_unit":"radian"}`,
+			code:       ExitDivisionByZero,
+		},
+	}
+
+	for _, c := range cases {
+		value, err := calculator.Evaluate(c.expression, c.config)
+		var out strings.Builder
+		require.NoError(t, Write(&out, New(c.expression, c.config, c.format, value, err)))
+		require.Equal(t, c.json+"\n", out.String(), c.expression)
+		require.Equal(t, c.code, ExitCode(err), c.expression)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	type CaseExitCode struct {
+		expression string
+		config     calculator.CalculatorConfig
+		code       int
+	}
+	cases := []CaseExitCode{
+		{expression: "exp(1000)", code: ExitOverflow},
+		{expression: "200", config: calculator.CalculatorConfig{Mode: calculator.Integer, IntWidth: 8, OverflowError: true}, code: ExitOverflow},
+		{expression: "sqrt(-4)", code: ExitMath},
+		{expression: "sqrt(2)", config: calculator.CalculatorConfig{Mode: calculator.Rational}, code: ExitUnsupported},
+		{expression: "foo(1)", config: calculator.CalculatorConfig{Mode: calculator.Interval}, code: ExitUndefined},
+	}
+
+	for _, c := range cases {
+		_, err := calculator.Evaluate(c.expression, c.config)
+		require.Equal(t, c.code, ExitCode(err), "%s: %v", c.expression, err)
+	}
+	require.Equal(t, ExitOK, ExitCode(nil))
+	require.Equal(t, ExitMath, ExitCode(errors.New("some error")))
+}